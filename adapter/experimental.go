@@ -35,6 +35,8 @@ type CacheFile interface {
 
 	LoadMode() string
 	StoreMode(mode string) error
+	LoadClashConfig() *SavedClashConfig
+	StoreClashConfig(config *SavedClashConfig) error
 	LoadSelected(group string) string
 	StoreSelected(group string, selected string) error
 	LoadGroupExpand(group string) (isExpand bool, loaded bool)
@@ -43,7 +45,52 @@ type CacheFile interface {
 	SaveRuleSet(tag string, set *SavedRuleSet) error
 	LoadProviderExpand(provider string) (isExpand bool, loaded bool)
 	StoreProviderExpand(provider string, expand bool) error
-	
+	LoadProviderMetadata(tag string) *SavedProviderMetadata
+	SaveProviderMetadata(tag string, metadata *SavedProviderMetadata) error
+}
+
+// ConfigReloader replaces the running instance with one created from the
+// configuration content, or from the current configuration source if content
+// is empty. It is registered in the context by the runner of the instance.
+//
+// The configuration is validated and the new instance is created before
+// returning, the running instance keeps serving if either fails. The running
+// instance is then closed asynchronously before the new one is started, so
+// connections are interrupted and listeners are unavailable until the new
+// instance has started, and the previous configuration is restored if it
+// fails to start.
+type ConfigReloader interface {
+	ReloadConfig(content []byte) error
+}
+
+// ConfigError is returned by ConfigReloader if the configuration is invalid,
+// Stage is one of `read`, `decode`, `check` and `create`.
+type ConfigError struct {
+	Stage string
+	Err   error
+}
+
+func (e *ConfigError) Error() string {
+	return e.Stage + " config: " + e.Err.Error()
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// SavedClashConfig is the runtime configuration changed by the Clash API.
+type SavedClashConfig struct {
+	LogLevel string `json:"log_level,omitempty"`
+	IPv6     *bool  `json:"ipv6,omitempty"`
+}
+
+// SavedProviderMetadata is the last update state of an outbound provider.
+type SavedProviderMetadata struct {
+	LastUpdated  time.Time         `json:"last_updated"`
+	LastURL      string            `json:"last_url,omitempty"`
+	Etags        map[string]string `json:"etags,omitempty"`
+	LastModified map[string]string `json:"last_modified,omitempty"`
+	Skipped      []SkippedOutbound `json:"skipped,omitempty"`
 }
 
 type SavedRuleSet struct {
@@ -105,7 +152,7 @@ type OutboundGroup interface {
 	Outbound
 	Now() string
 	All() []string
-	UpdateOutbounds(tag string) error
+	UpdateOutbounds(tag string, delta OutboundProviderDelta) error
 	SelectedOutbound(network string) Outbound
 }
 
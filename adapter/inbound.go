@@ -53,6 +53,7 @@ type InboundContext struct {
 	SourceGeoIPCode      string
 	GeoIPCode            string
 	ProcessInfo          *process.Info
+	WIFIState            *WIFIState
 	QueryType            uint16
 
 	// rule cache
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net"
 
+	"github.com/sagernet/sing-box/option"
 	N "github.com/sagernet/sing/common/network"
 )
 
@@ -24,3 +25,8 @@ type Outbound interface {
 type OutboundUseIP interface {
 	UseIP() bool
 }
+
+type OutboundWithOptions interface {
+	Outbound
+	Options() option.Outbound
+}
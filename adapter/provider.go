@@ -11,6 +11,9 @@ type OutboundProvider interface {
 	Type() string
 	Outbounds() []Outbound
 	Outbound(tag string) (Outbound, bool)
+	// OutboundIdentity returns a tag independent identity of the node behind
+	// the outbound, stable across provider updates.
+	OutboundIdentity(tag string) (string, bool)
 	UpdateTime() time.Time
 
 	Start() error
@@ -19,6 +22,41 @@ type OutboundProvider interface {
 	PostStart() error
 	Healthcheck(ctx context.Context, link string, force bool) map[string]uint16
 	SubInfo() map[string]int64
+	SkippedOutbounds() []SkippedOutbound
+	SubscriptionStatus() SubscriptionStatus
 	UpdateProvider(ctx context.Context, router Router) error
 	UpdateOutboundByTag()
 }
+
+// SkippedOutbound describes a subscription entry that did not become an outbound.
+// Index is the position of the entry in the subscription content, or in the
+// parsed outbound list for entries dropped after parsing.
+type SkippedOutbound struct {
+	Index  int    `json:"index"`
+	Tag    string `json:"tag,omitempty"`
+	Type   string `json:"type,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// OutboundProviderDelta describes outbounds started and closed by a provider
+// update, outbounds not changed by the update keep their instances.
+type OutboundProviderDelta struct {
+	Added   []Outbound
+	Removed []Outbound
+	// Reordered is set if outbounds kept by the update changed their order, or
+	// groups should pick outbounds again without any of them changed, e.g. the
+	// subscription of the provider is exhausted.
+	Reordered bool
+}
+
+func (d OutboundProviderDelta) Changed() bool {
+	return len(d.Added) > 0 || len(d.Removed) > 0 || d.Reordered
+}
+
+// SubscriptionStatus describes the subscription quota state of a provider.
+// Outbounds of an excluded provider are not picked by groups.
+type SubscriptionStatus struct {
+	Exhausted bool     `json:"exhausted"`
+	Excluded  bool     `json:"excluded"`
+	Warnings  []string `json:"warnings,omitempty"`
+}
@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box/common/geoip"
+	"github.com/sagernet/sing-box/common/tracing"
+	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing-dns"
 	"github.com/sagernet/sing-tun"
 	"github.com/sagernet/sing/common/control"
@@ -49,6 +51,8 @@ type Router interface {
 	Lookup(ctx context.Context, domain string, strategy dns.DomainStrategy) ([]netip.Addr, error)
 	LookupDefault(ctx context.Context, domain string) ([]netip.Addr, error)
 	ClearDNSCache()
+	IPv6Enabled() bool
+	SetIPv6Enabled(enabled bool)
 
 	InterfaceFinder() control.InterfaceFinder
 	UpdateInterfaces() error
@@ -66,7 +70,10 @@ type Router interface {
 	Rule(uuid string) (Rule, bool)
 	DNSRules() []DNSRule
 	DNSRule(uuid string) (DNSRule, bool)
+	RuleEditor
 	DefaultDNSServer() string
+	Tracer() *tracing.Tracer
+	TestRoute(ctx context.Context, metadata InboundContext) (*RouteTestResult, error)
 
 	ClashServer() ClashServer
 	SetClashServer(server ClashServer)
@@ -92,6 +99,19 @@ type HeadlessRule interface {
 	ContainsDestinationIPCIDRRule() bool
 }
 
+// RuleEditor edits rules of a started router, rules are created from options
+// and get a new UUID when updated, an out of range index appends.
+type RuleEditor interface {
+	InsertRule(index int, options option.Rule) (Rule, error)
+	UpdateRule(uuid string, options option.Rule) (Rule, error)
+	MoveRule(uuid string, index int) error
+	RemoveRule(uuid string) error
+	InsertDNSRule(index int, options option.DNSRule) (DNSRule, error)
+	UpdateDNSRule(uuid string, options option.DNSRule) (DNSRule, error)
+	MoveDNSRule(uuid string, index int) error
+	RemoveDNSRule(uuid string) error
+}
+
 type Rule interface {
 	HeadlessRule
 	Service
@@ -162,6 +182,19 @@ type InterfaceUpdateListener interface {
 	InterfaceUpdated()
 }
 
+// RouteTestResult is where a connection would be routed to, rule indexes
+// are -1 if the final outbound or the default DNS server is used.
+type RouteTestResult struct {
+	RuleIndex    int      `json:"rule_index"`
+	Rule         string   `json:"rule,omitempty"`
+	Outbound     string   `json:"outbound"`
+	Chain        []string `json:"chain"`
+	DNSRuleIndex int      `json:"dns_rule_index"`
+	DNSRule      string   `json:"dns_rule,omitempty"`
+	DNSServer    string   `json:"dns_server,omitempty"`
+	FakeIP       bool     `json:"fakeip,omitempty"`
+}
+
 type WIFIState struct {
 	SSID  string
 	BSSID string
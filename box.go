@@ -10,6 +10,7 @@ import (
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/taskmonitor"
+	"github.com/sagernet/sing-box/common/urltest"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/experimental"
 	"github.com/sagernet/sing-box/experimental/cachefile"
@@ -60,6 +61,11 @@ func New(options Options) (*Box, error) {
 	ctx = pause.WithDefaultManager(ctx)
 	experimentalOptions := common.PtrValueOrDefault(options.Experimental)
 	applyDebugOptions(common.PtrValueOrDefault(experimentalOptions.Debug))
+	if cacheFileOptions := common.PtrValueOrDefault(experimentalOptions.CacheFile); cacheFileOptions.Enabled && cacheFileOptions.StoreHistory {
+		if service.PtrFromContext[urltest.HistoryStorage](ctx) == nil {
+			ctx = service.ContextWithPtr(ctx, urltest.NewHistoryStorage())
+		}
+	}
 	var needCacheFile bool
 	var needClashAPI bool
 	var needV2RayAPI bool
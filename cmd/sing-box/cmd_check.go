@@ -5,6 +5,7 @@ import (
 
 	"github.com/sagernet/sing-box"
 	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
 
 	"github.com/spf13/cobra"
 )
@@ -30,6 +31,10 @@ func check() error {
 	if err != nil {
 		return err
 	}
+	return checkOptions(options)
+}
+
+func checkOptions(options option.Options) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	instance, err := box.New(box.Options{
 		Context: ctx,
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/json/badjson"
+
+	"github.com/spf13/cobra"
+)
+
+var commandProviderFlagTag string
+
+var commandProvider = &cobra.Command{
+	Use:   "provider",
+	Short: "Outbound provider tools",
+}
+
+func init() {
+	commandProvider.PersistentFlags().StringVarP(&commandProviderFlagTag, "provider", "p", "", "apply filter and override options of the provider with the specified tag in configuration")
+	mainCommand.AddCommand(commandProvider)
+}
+
+func readProviderOptions(tag string) (option.OutboundProvider, error) {
+	if tag == "" {
+		return option.OutboundProvider{}, nil
+	}
+	options, err := readConfigAndMerge()
+	if err != nil {
+		return option.OutboundProvider{}, err
+	}
+	for _, providerOptions := range options.OutboundProviders {
+		if providerOptions.Tag == tag {
+			return providerOptions, nil
+		}
+	}
+	return option.OutboundProvider{}, E.New("outbound provider not found: ", tag)
+}
+
+func readProviderContent(source string, userAgent string) ([]byte, error) {
+	switch {
+	case source == "stdin":
+		return io.ReadAll(os.Stdin)
+	case strings.HasPrefix(source, "http://"), strings.HasPrefix(source, "https://"):
+		request, err := http.NewRequest("GET", source, nil)
+		if err != nil {
+			return nil, err
+		}
+		if userAgent == "" {
+			userAgent = "sing-box " + C.Version + "; PuerNya fork"
+		}
+		request.Header.Set("User-Agent", userAgent)
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			return nil, err
+		}
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			return nil, E.New("unexpected status: ", response.Status)
+		}
+		return io.ReadAll(response.Body)
+	default:
+		return os.ReadFile(source)
+	}
+}
+
+func printProviderOutbounds(outbounds []option.Outbound, skipped []adapter.SkippedOutbound) error {
+	options, err := badjson.Omitempty(option.OutboundProviderOptions{Outbounds: outbounds})
+	if err != nil {
+		return err
+	}
+	buffer := new(bytes.Buffer)
+	encoder := json.NewEncoder(buffer)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(options)
+	if err != nil {
+		return E.Cause(err, "encode outbounds")
+	}
+	os.Stdout.Write(buffer.Bytes())
+	printSkippedOutbounds(skipped)
+	return nil
+}
+
+func printSkippedOutbounds(skipped []adapter.SkippedOutbound) {
+	for _, entry := range skipped {
+		os.Stderr.WriteString(F.ToString("skipped [", entry.Index, "] ", entry.Tag, " (", entry.Type, "): ", entry.Reason, "\n"))
+	}
+}
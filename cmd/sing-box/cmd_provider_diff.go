@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"reflect"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/provider"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+
+	"github.com/spf13/cobra"
+)
+
+var commandProviderDiff = &cobra.Command{
+	Use:   "diff <old file|url> <new file|url>",
+	Short: "Compare the outbounds parsed from two subscriptions",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := providerDiff(args[0], args[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandProvider.AddCommand(commandProviderDiff)
+}
+
+func providerDiff(oldSource string, newSource string) error {
+	options, err := readProviderOptions(commandProviderFlagTag)
+	if err != nil {
+		return err
+	}
+	oldOutbounds, err := parseProviderSource(oldSource, options)
+	if err != nil {
+		return err
+	}
+	newOutbounds, err := parseProviderSource(newSource, options)
+	if err != nil {
+		return err
+	}
+	oldByTag := make(map[string]option.Outbound)
+	for _, outbound := range oldOutbounds {
+		oldByTag[outbound.Tag] = outbound
+	}
+	newByTag := make(map[string]option.Outbound)
+	for _, outbound := range newOutbounds {
+		newByTag[outbound.Tag] = outbound
+	}
+	for _, outbound := range oldOutbounds {
+		if _, loaded := newByTag[outbound.Tag]; !loaded {
+			os.Stdout.WriteString(F.ToString("- ", outbound.Tag, " (", outbound.Type, ")\n"))
+		}
+	}
+	for _, outbound := range newOutbounds {
+		oldOutbound, loaded := oldByTag[outbound.Tag]
+		if !loaded {
+			os.Stdout.WriteString(F.ToString("+ ", outbound.Tag, " (", outbound.Type, ")\n"))
+		} else if !reflect.DeepEqual(oldOutbound, outbound) {
+			os.Stdout.WriteString(F.ToString("~ ", outbound.Tag, " (", outbound.Type, ")\n"))
+		}
+	}
+	return nil
+}
+
+func parseProviderSource(source string, options option.OutboundProvider) ([]option.Outbound, error) {
+	content, err := readProviderContent(source, options.RemoteOptions.UserAgent)
+	if err != nil {
+		return nil, E.Cause(err, "read subscription ", source)
+	}
+	outbounds, skipped, err := provider.ParseContent(string(content), options)
+	if err != nil {
+		return nil, E.Cause(err, "parse subscription ", source)
+	}
+	printSkippedOutbounds(skipped)
+	return outbounds, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/provider"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/spf13/cobra"
+)
+
+var commandProviderExportFlagFormat string
+
+var commandProviderExport = &cobra.Command{
+	Use:   "export [file|url]",
+	Short: "Export outbounds as share links or a Clash configuration",
+	Long:  "Export outbounds of a subscription as share links or a Clash configuration. Outbounds in configuration are exported if no subscription is given.",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var source string
+		if len(args) > 0 {
+			source = args[0]
+		}
+		err := providerExport(source)
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandProviderExport.Flags().StringVarP(&commandProviderExportFlagFormat, "format", "f", "uri", "export format: uri, clash")
+	commandProvider.AddCommand(commandProviderExport)
+}
+
+func providerExport(source string) error {
+	var outbounds []option.Outbound
+	if source == "" {
+		options, err := readConfigAndMerge()
+		if err != nil {
+			return err
+		}
+		outbounds = options.Outbounds
+	} else {
+		options, err := readProviderOptions(commandProviderFlagTag)
+		if err != nil {
+			return err
+		}
+		content, err := readProviderContent(source, options.RemoteOptions.UserAgent)
+		if err != nil {
+			return E.Cause(err, "read subscription")
+		}
+		var skipped []adapter.SkippedOutbound
+		outbounds, skipped, err = provider.ParseContent(string(content), options)
+		if err != nil {
+			return E.Cause(err, "parse subscription")
+		}
+		printSkippedOutbounds(skipped)
+	}
+	content, skipped, err := provider.ExportContent(outbounds, commandProviderExportFlagFormat)
+	if err != nil {
+		return err
+	}
+	os.Stdout.Write(content)
+	printSkippedOutbounds(skipped)
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/provider"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/spf13/cobra"
+)
+
+var commandProviderFetchFlagOutput string
+
+var commandProviderFetch = &cobra.Command{
+	Use:   "fetch",
+	Short: "Download the subscription of a remote provider and print the resulting outbounds",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := providerFetch()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandProviderFetch.Flags().StringVarP(&commandProviderFetchFlagOutput, "output", "o", "", "write downloaded content to file")
+	commandProvider.AddCommand(commandProviderFetch)
+}
+
+func providerFetch() error {
+	if commandProviderFlagTag == "" {
+		return E.New("missing provider tag")
+	}
+	options, err := readProviderOptions(commandProviderFlagTag)
+	if err != nil {
+		return err
+	}
+	if options.Type != C.ProviderTypeRemote {
+		return E.New("outbound provider ", options.Tag, " is not a remote provider")
+	}
+	instance, err := createPreStartedClient()
+	if err != nil {
+		return err
+	}
+	defer instance.Close()
+	outboundProvider, loaded := instance.Router().OutboundProvider(options.Tag)
+	if !loaded {
+		return E.New("outbound provider not found: ", options.Tag)
+	}
+	remoteProvider, isRemote := outboundProvider.(*provider.RemoteProvider)
+	if !isRemote {
+		return E.New("outbound provider ", options.Tag, " is not a remote provider")
+	}
+	content, err := remoteProvider.Fetch(context.Background())
+	if err != nil {
+		return E.Cause(err, "download subscription")
+	}
+	if commandProviderFetchFlagOutput != "" {
+		err = os.WriteFile(commandProviderFetchFlagOutput, content, 0o644)
+		if err != nil {
+			return E.Cause(err, "write output")
+		}
+	}
+	outbounds, skipped, err := provider.ParseContent(string(content), options)
+	if err != nil {
+		return E.Cause(err, "parse subscription")
+	}
+	return printProviderOutbounds(outbounds, skipped)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/provider"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/spf13/cobra"
+)
+
+var commandProviderParse = &cobra.Command{
+	Use:   "parse <file|url>",
+	Short: "Parse a subscription and print the resulting outbounds",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := providerParse(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandProvider.AddCommand(commandProviderParse)
+}
+
+func providerParse(source string) error {
+	options, err := readProviderOptions(commandProviderFlagTag)
+	if err != nil {
+		return err
+	}
+	content, err := readProviderContent(source, options.RemoteOptions.UserAgent)
+	if err != nil {
+		return E.Cause(err, "read subscription")
+	}
+	outbounds, skipped, err := provider.ParseContent(string(content), options)
+	if err != nil {
+		return E.Cause(err, "parse subscription")
+	}
+	return printProviderOutbounds(outbounds, skipped)
+}
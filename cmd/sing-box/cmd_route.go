@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/spf13/cobra"
+)
+
+var commandRoute = &cobra.Command{
+	Use:   "route",
+	Short: "Routing tools",
+}
+
+var (
+	commandRouteTestFlagController string
+	commandRouteTestFlagSecret     string
+	commandRouteTestRequest        routeTestRequest
+)
+
+var commandRouteTest = &cobra.Command{
+	Use:   "test",
+	Short: "Ask the running instance where a connection would be routed to",
+	Long:  "Ask the running instance where a connection would be routed to through the Clash API, without opening the connection.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := routeTest()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// routeTestRequest is the request of the Clash API `POST /route/test`.
+type routeTestRequest struct {
+	Inbound     string `json:"inbound,omitempty"`
+	Network     string `json:"network,omitempty"`
+	Domain      string `json:"domain,omitempty"`
+	IP          string `json:"ip,omitempty"`
+	Port        uint16 `json:"port,omitempty"`
+	Source      string `json:"source,omitempty"`
+	ProcessName string `json:"process_name,omitempty"`
+	PackageName string `json:"package_name,omitempty"`
+	User        string `json:"user,omitempty"`
+	AuthUser    string `json:"auth_user,omitempty"`
+	WIFISSID    string `json:"wifi_ssid,omitempty"`
+	WIFIBSSID   string `json:"wifi_bssid,omitempty"`
+}
+
+func init() {
+	flags := commandRouteTest.Flags()
+	flags.StringVar(&commandRouteTestFlagController, "controller", "", "Clash API address, read from configuration by default")
+	flags.StringVar(&commandRouteTestFlagSecret, "secret", "", "Clash API secret, read from configuration by default")
+	flags.StringVar(&commandRouteTestRequest.Inbound, "inbound", "", "inbound tag")
+	flags.StringVarP(&commandRouteTestRequest.Network, "network", "n", "tcp", "network, tcp or udp")
+	flags.StringVarP(&commandRouteTestRequest.Domain, "domain", "d", "", "destination domain")
+	flags.StringVarP(&commandRouteTestRequest.IP, "ip", "i", "", "destination IP address, or resolved address of the domain")
+	flags.Uint16VarP(&commandRouteTestRequest.Port, "port", "p", 443, "destination port")
+	flags.StringVar(&commandRouteTestRequest.Source, "source", "", "source address and port")
+	flags.StringVar(&commandRouteTestRequest.ProcessName, "process", "", "process name or path")
+	flags.StringVar(&commandRouteTestRequest.PackageName, "package", "", "Android package name")
+	flags.StringVar(&commandRouteTestRequest.User, "user", "", "process user")
+	flags.StringVar(&commandRouteTestRequest.AuthUser, "auth-user", "", "inbound user")
+	flags.StringVar(&commandRouteTestRequest.WIFISSID, "wifi-ssid", "", "WIFI SSID")
+	flags.StringVar(&commandRouteTestRequest.WIFIBSSID, "wifi-bssid", "", "WIFI BSSID")
+	commandRoute.AddCommand(commandRouteTest)
+	mainCommand.AddCommand(commandRoute)
+}
+
+func routeTest() error {
+	controller := commandRouteTestFlagController
+	secret := commandRouteTestFlagSecret
+	if controller == "" {
+		options, err := readConfigAndMerge()
+		if err != nil {
+			return err
+		}
+		if options.Experimental == nil || options.Experimental.ClashAPI == nil || options.Experimental.ClashAPI.ExternalController == "" {
+			return E.New("missing clash_api.external_controller in configuration")
+		}
+		controller = options.Experimental.ClashAPI.ExternalController
+		if secret == "" {
+			secret = options.Experimental.ClashAPI.Secret
+		}
+	}
+	result, err := requestRouteTest(controller, secret, commandRouteTestRequest)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// controllerURL converts a Clash API listen address to a URL, unspecified
+// hosts are replaced with the loopback address.
+func controllerURL(controller string) (string, error) {
+	if strings.Contains(controller, "://") {
+		return strings.TrimSuffix(controller, "/"), nil
+	}
+	host, port, err := net.SplitHostPort(controller)
+	if err != nil {
+		return "", E.Cause(err, "parse controller address")
+	}
+	if host == "" || net.ParseIP(host) != nil && net.ParseIP(host).IsUnspecified() {
+		host = "127.0.0.1"
+	}
+	return "http://" + net.JoinHostPort(host, port), nil
+}
+
+func requestRouteTest(controller string, secret string, routeRequest routeTestRequest) (*adapter.RouteTestResult, error) {
+	controller, err := controllerURL(controller)
+	if err != nil {
+		return nil, err
+	}
+	content, err := json.Marshal(routeRequest)
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest(http.MethodPost, controller+"/route/test", bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		request.Header.Set("Authorization", "Bearer "+secret)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		var apiError struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(response.Body).Decode(&apiError)
+		if apiError.Message != "" {
+			return nil, E.New(apiError.Message)
+		}
+		return nil, E.New("unexpected status: ", response.Status)
+	}
+	var result adapter.RouteTestResult
+	err = json.NewDecoder(response.Body).Decode(&result)
+	if err != nil {
+		return nil, E.Cause(err, "decode result")
+	}
+	return &result, nil
+}
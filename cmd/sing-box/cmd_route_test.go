@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestControllerURL(t *testing.T) {
+	t.Parallel()
+	for controller, expected := range map[string]string{
+		"127.0.0.1:9090":         "http://127.0.0.1:9090",
+		":9090":                  "http://127.0.0.1:9090",
+		"0.0.0.0:9090":           "http://127.0.0.1:9090",
+		"[::]:9090":              "http://127.0.0.1:9090",
+		"192.168.1.1:9090":       "http://192.168.1.1:9090",
+		"https://example.com/":   "https://example.com",
+		"http://127.0.0.1:9090/": "http://127.0.0.1:9090",
+	} {
+		url, err := controllerURL(controller)
+		require.NoError(t, err, controller)
+		require.Equal(t, expected, url, controller)
+	}
+	_, err := controllerURL("127.0.0.1")
+	require.Error(t, err)
+}
+
+func TestRequestRouteTest(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/route/test" || r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message": "Unauthorized"}`))
+			return
+		}
+		var request routeTestRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		if request.Domain == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message": "missing domain or ip"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(adapter.RouteTestResult{RuleIndex: 2, Outbound: request.ProcessName, DNSRuleIndex: -1})
+	}))
+	defer server.Close()
+
+	result, err := requestRouteTest(server.URL, "secret", routeTestRequest{Domain: "example.com", ProcessName: "curl"})
+	require.NoError(t, err)
+	require.Equal(t, 2, result.RuleIndex)
+	require.Equal(t, "curl", result.Outbound)
+	require.Equal(t, -1, result.DNSRuleIndex)
+
+	_, err = requestRouteTest(server.URL, "secret", routeTestRequest{})
+	require.EqualError(t, err, "missing domain or ip")
+	_, err = requestRouteTest(server.URL, "", routeTestRequest{Domain: "example.com"})
+	require.EqualError(t, err, "Unauthorized")
+}
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box"
+	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/betterjson"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
@@ -20,6 +21,7 @@ import (
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/json"
 	"github.com/sagernet/sing/common/json/badjson"
+	"github.com/sagernet/sing/service"
 
 	"github.com/spf13/cobra"
 )
@@ -58,11 +60,7 @@ func readConfigAt(path string) (*OptionsEntry, error) {
 	if err != nil {
 		return nil, E.Cause(err, "read config at ", path)
 	}
-	content, err := betterjson.PreConvert(configContent)
-	if err != nil {
-		return nil, E.Cause(err, "decode config at ", path)
-	}
-	options, err := json.UnmarshalExtended[option.Options](content)
+	options, err := parseConfig(configContent)
 	if err != nil {
 		return nil, E.Cause(err, "decode config at ", path)
 	}
@@ -73,6 +71,14 @@ func readConfigAt(path string) (*OptionsEntry, error) {
 	}, nil
 }
 
+func parseConfig(configContent []byte) (option.Options, error) {
+	content, err := betterjson.PreConvert(configContent)
+	if err != nil {
+		return option.Options{}, err
+	}
+	return json.UnmarshalExtended[option.Options](content)
+}
+
 func readConfig() ([]*OptionsEntry, error) {
 	var optionsList []*OptionsEntry
 	for _, path := range configPaths {
@@ -127,11 +133,9 @@ func readConfigAndMerge() (option.Options, error) {
 	return mergedOptions, nil
 }
 
-func create() (*box.Box, context.CancelFunc, error) {
-	options, err := readConfigAndMerge()
-	if err != nil {
-		return nil, nil, err
-	}
+// prepareInstance creates an instance without starting it, so that a
+// configuration can be rejected while the running instance keeps serving.
+func prepareInstance(options option.Options, reloader *configReloader) (*box.Box, context.CancelFunc, error) {
 	if disableColor {
 		if options.Log == nil {
 			options.Log = &option.LogOptions{}
@@ -139,6 +143,8 @@ func create() (*box.Box, context.CancelFunc, error) {
 		options.Log.DisableColor = true
 	}
 	ctx, cancel := context.WithCancel(globalCtx)
+	ctx = service.ContextWithDefaultRegistry(ctx)
+	service.MustRegister[adapter.ConfigReloader](ctx, reloader)
 	instance, err := box.New(box.Options{
 		Context: ctx,
 		Options: options,
@@ -147,7 +153,10 @@ func create() (*box.Box, context.CancelFunc, error) {
 		cancel()
 		return nil, nil, E.Cause(err, "create service")
 	}
+	return instance, cancel, nil
+}
 
+func startInstance(instance *box.Box, cancel context.CancelFunc) error {
 	osSignals := make(chan os.Signal, 1)
 	signal.Notify(osSignals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
 	defer func() {
@@ -162,47 +171,160 @@ func create() (*box.Box, context.CancelFunc, error) {
 			closeMonitor(startCtx)
 		}
 	}()
-	err = instance.Start()
+	err := instance.Start()
 	finishStart()
 	if err != nil {
 		cancel()
-		return nil, nil, E.Cause(err, "start service")
+		instance.Close()
+		return E.Cause(err, "start service")
+	}
+	return nil
+}
+
+func closeInstance(instance *box.Box, cancel context.CancelFunc) error {
+	cancel()
+	closeCtx, closed := context.WithCancel(context.Background())
+	go closeMonitor(closeCtx)
+	defer closed()
+	return instance.Close()
+}
+
+// configReloader passes validated options from the Clash API to run, and
+// waits until the new instance is created.
+type configReloader struct {
+	reload chan reloadRequest
+	closed chan struct{}
+}
+
+type reloadRequest struct {
+	options option.Options
+	done    chan error
+}
+
+func (r *configReloader) ReloadConfig(content []byte) error {
+	var (
+		options option.Options
+		err     error
+	)
+	if len(content) == 0 {
+		options, err = readConfigAndMerge()
+		if err != nil {
+			return &adapter.ConfigError{Stage: "read", Err: err}
+		}
+	} else {
+		options, err = parseConfig(content)
+		if err != nil {
+			return &adapter.ConfigError{Stage: "decode", Err: err}
+		}
+	}
+	err = checkOptions(options)
+	if err != nil {
+		return &adapter.ConfigError{Stage: "check", Err: err}
+	}
+	request := reloadRequest{
+		options: options,
+		done:    make(chan error, 1),
+	}
+	select {
+	case r.reload <- request:
+	default:
+		return E.New("service is reloading")
+	}
+	select {
+	case err = <-request.done:
+		if err != nil {
+			return &adapter.ConfigError{Stage: "create", Err: err}
+		}
+		return nil
+	case <-r.closed:
+		return E.New("service is closing")
 	}
-	return instance, cancel, nil
 }
 
 func run() error {
 	osSignals := make(chan os.Signal, 1)
 	signal.Notify(osSignals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
 	defer signal.Stop(osSignals)
+	reloader := &configReloader{
+		reload: make(chan reloadRequest, 1),
+		closed: make(chan struct{}),
+	}
+	defer close(reloader.closed)
+	options, err := readConfigAndMerge()
+	if err != nil {
+		return err
+	}
+	var (
+		previousOptions *option.Options
+		instance        *box.Box
+		cancel          context.CancelFunc
+	)
 	for {
-		instance, cancel, err := create()
+		if instance == nil {
+			instance, cancel, err = prepareInstance(options, reloader)
+		}
+		if err == nil {
+			err = startInstance(instance, cancel)
+		}
 		if err != nil {
-			return err
+			if previousOptions == nil {
+				return err
+			}
+			log.Error(E.Cause(err, "reload service"), ", restore previous configuration")
+			options = *previousOptions
+			previousOptions = nil
+			instance = nil
+			continue
 		}
 		runtimeDebug.FreeOSMemory()
+		var (
+			newOptions  option.Options
+			newInstance *box.Box
+			newCancel   context.CancelFunc
+		)
+	waitSignal:
 		for {
-			osSignal := <-osSignals
-			if osSignal == syscall.SIGHUP {
-				err = check()
+			select {
+			case osSignal := <-osSignals:
+				if osSignal == syscall.SIGHUP {
+					newOptions, err = readConfigAndMerge()
+					if err == nil {
+						err = checkOptions(newOptions)
+					}
+					if err == nil {
+						newInstance, newCancel, err = prepareInstance(newOptions, reloader)
+					}
+					if err != nil {
+						log.Error(E.Cause(err, "reload service"))
+						continue
+					}
+				}
+				err = closeInstance(instance, cancel)
+				if err != nil {
+					log.Error(E.Cause(err, "sing-box did not closed properly"))
+				}
+				if osSignal != syscall.SIGHUP {
+					return nil
+				}
+				break waitSignal
+			case request := <-reloader.reload:
+				newInstance, newCancel, err = prepareInstance(request.options, reloader)
+				request.done <- err
 				if err != nil {
 					log.Error(E.Cause(err, "reload service"))
 					continue
 				}
-			}
-			cancel()
-			closeCtx, closed := context.WithCancel(context.Background())
-			go closeMonitor(closeCtx)
-			err = instance.Close()
-			closed()
-			if osSignal != syscall.SIGHUP {
+				newOptions = request.options
+				err = closeInstance(instance, cancel)
 				if err != nil {
 					log.Error(E.Cause(err, "sing-box did not closed properly"))
 				}
-				return nil
+				break waitSignal
 			}
-			break
 		}
+		previousOptions = &options
+		options = newOptions
+		instance, cancel, err = newInstance, newCancel, nil
 	}
 }
 
@@ -0,0 +1,157 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sagernet/sing/common/observable"
+)
+
+// Trace records routing decisions made for a connection.
+type Trace struct {
+	ID          uint32        `json:"id"`
+	Time        time.Time     `json:"time"`
+	Inbound     string        `json:"inbound"`
+	InboundType string        `json:"inboundType"`
+	Network     string        `json:"network"`
+	Source      string        `json:"source"`
+	Destination string        `json:"destination"`
+	Sniff       *SniffResult  `json:"sniff,omitempty"`
+	DNSMode     string        `json:"dnsMode,omitempty"`
+	Rules       []RuleResult  `json:"rules"`
+	DNS         []DNSDecision `json:"dns"`
+	Outbound    string        `json:"outbound,omitempty"`
+	Chain       []string      `json:"chain,omitempty"`
+	Error       string        `json:"error,omitempty"`
+
+	access   sync.Mutex
+	finished bool
+}
+
+type SniffResult struct {
+	Protocol string `json:"protocol"`
+	Domain   string `json:"domain,omitempty"`
+	Client   string `json:"client,omitempty"`
+}
+
+type RuleResult struct {
+	Index    int    `json:"index"`
+	Rule     string `json:"rule"`
+	Outbound string `json:"outbound"`
+	Matched  bool   `json:"matched"`
+}
+
+// DNSDecision is a DNS rule chosen for a query, a fallback rule hit, or the
+// result of a lookup if Addresses or Error is set. RuleIndex is -1 for the
+// default server and fallback rules.
+type DNSDecision struct {
+	Domain    string   `json:"domain"`
+	RuleIndex int      `json:"ruleIndex"`
+	Rule      string   `json:"rule,omitempty"`
+	Fallback  bool     `json:"fallback,omitempty"`
+	Server    string   `json:"server,omitempty"`
+	FakeIP    bool     `json:"fakeip,omitempty"`
+	Addresses []string `json:"addresses,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+func (t *Trace) AddRule(result RuleResult) {
+	if t == nil {
+		return
+	}
+	t.access.Lock()
+	defer t.access.Unlock()
+	if !t.finished {
+		t.Rules = append(t.Rules, result)
+	}
+}
+
+func (t *Trace) AddDNS(decision DNSDecision) {
+	if t == nil {
+		return
+	}
+	t.access.Lock()
+	defer t.access.Unlock()
+	if !t.finished {
+		t.DNS = append(t.DNS, decision)
+	}
+}
+
+type traceKey struct{}
+
+func ContextWithTrace(ctx context.Context, trace *Trace) context.Context {
+	return context.WithValue(ctx, (*traceKey)(nil), trace)
+}
+
+// TraceFromContext returns the trace of the connection, methods of Trace are
+// no-op on the nil result.
+func TraceFromContext(ctx context.Context) *Trace {
+	trace, _ := ctx.Value((*traceKey)(nil)).(*Trace)
+	return trace
+}
+
+// Tracer publishes traces to subscribers, traces are only created while
+// there are subscribers.
+type Tracer struct {
+	subscriber  *observable.Subscriber[*Trace]
+	observer    *observable.Observer[*Trace]
+	subscribers atomic.Int32
+}
+
+func NewTracer() *Tracer {
+	subscriber := observable.NewSubscriber[*Trace](128)
+	return &Tracer{
+		subscriber: subscriber,
+		observer:   observable.NewObserver[*Trace](subscriber, 64),
+	}
+}
+
+func (t *Tracer) Enabled() bool {
+	return t.subscribers.Load() > 0
+}
+
+// Start returns a new trace and the context carrying it, or nil if there are
+// no subscribers.
+func (t *Tracer) Start(ctx context.Context) (context.Context, *Trace) {
+	if !t.Enabled() {
+		return ctx, nil
+	}
+	trace := &Trace{Time: time.Now()}
+	return ContextWithTrace(ctx, trace), trace
+}
+
+// Finish publishes the trace once, fill is called before publishing unless
+// the trace is nil or already finished.
+func (t *Tracer) Finish(trace *Trace, fill func(trace *Trace)) {
+	if trace == nil {
+		return
+	}
+	trace.access.Lock()
+	if trace.finished {
+		trace.access.Unlock()
+		return
+	}
+	fill(trace)
+	trace.finished = true
+	trace.access.Unlock()
+	t.observer.Emit(trace)
+}
+
+func (t *Tracer) Subscribe() (subscription observable.Subscription[*Trace], done <-chan struct{}, err error) {
+	subscription, done, err = t.observer.Subscribe()
+	if err == nil {
+		t.subscribers.Add(1)
+	}
+	return
+}
+
+func (t *Tracer) UnSubscribe(subscription observable.Subscription[*Trace]) {
+	t.subscribers.Add(-1)
+	t.observer.UnSubscribe(subscription)
+}
+
+func (t *Tracer) Close() error {
+	return t.observer.Close()
+}
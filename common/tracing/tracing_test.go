@@ -0,0 +1,43 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracer(t *testing.T) {
+	t.Parallel()
+	tracer := NewTracer()
+	defer tracer.Close()
+	_, trace := tracer.Start(context.Background())
+	require.Nil(t, trace)
+	trace.AddRule(RuleResult{Index: 0})
+
+	subscription, _, err := tracer.Subscribe()
+	require.NoError(t, err)
+	ctx, trace := tracer.Start(context.Background())
+	require.NotNil(t, trace)
+	require.Equal(t, trace, TraceFromContext(ctx))
+	trace.AddRule(RuleResult{Index: 0, Matched: true})
+	trace.AddDNS(DNSDecision{Domain: "example.com", RuleIndex: -1})
+	tracer.Finish(trace, func(trace *Trace) {
+		trace.Outbound = "direct"
+	})
+	tracer.Finish(trace, func(trace *Trace) {
+		trace.Outbound = "block"
+	})
+	trace.AddRule(RuleResult{Index: 1})
+	select {
+	case published := <-subscription:
+		require.Equal(t, "direct", published.Outbound)
+		require.Len(t, published.Rules, 1)
+		require.Len(t, published.DNS, 1)
+	case <-time.After(time.Second):
+		t.Fatal("trace not published")
+	}
+	tracer.UnSubscribe(subscription)
+	require.False(t, tracer.Enabled())
+}
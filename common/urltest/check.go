@@ -0,0 +1,169 @@
+package urltest
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+const (
+	DefaultLink = "https://www.gstatic.com/generate_204"
+
+	maxBodySize = 64 * 1024
+)
+
+type StatusRange struct {
+	Start int
+	End   int
+}
+
+type CheckOptions struct {
+	URLs           []string
+	ExpectedStatus []StatusRange
+	ExpectedBody   string
+	Timeout        time.Duration
+}
+
+// ParseStatusRanges parses status codes like `204` and ranges like `200-299`.
+func ParseStatusRanges(ranges []string) ([]StatusRange, error) {
+	var statusRanges []StatusRange
+	for _, statusRange := range ranges {
+		startStr, endStr, isRange := strings.Cut(statusRange, "-")
+		start, err := strconv.Atoi(strings.TrimSpace(startStr))
+		if err != nil {
+			return nil, E.New("invalid status: ", statusRange)
+		}
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(strings.TrimSpace(endStr))
+			if err != nil {
+				return nil, E.New("invalid status: ", statusRange)
+			}
+		}
+		if start < 100 || end > 599 || start > end {
+			return nil, E.New("invalid status: ", statusRange)
+		}
+		statusRanges = append(statusRanges, StatusRange{Start: start, End: end})
+	}
+	return statusRanges, nil
+}
+
+func (o CheckOptions) matchStatus(status int) bool {
+	if len(o.ExpectedStatus) == 0 {
+		return true
+	}
+	for _, statusRange := range o.ExpectedStatus {
+		if status >= statusRange.Start && status <= statusRange.End {
+			return true
+		}
+	}
+	return false
+}
+
+// Check tests every URL in options through detour and returns the mean delay,
+// any failed URL fails the check.
+func Check(ctx context.Context, detour N.Dialer, options CheckOptions) (uint16, error) {
+	links := options.URLs
+	if len(links) == 0 {
+		links = []string{""}
+	}
+	var total int
+	for _, link := range links {
+		if link == "" {
+			link = DefaultLink
+		}
+		delay1, err := checkOnce(ctx, link, detour, options)
+		if err != nil {
+			return 0, err
+		}
+		delay2, err := checkOnce(ctx, link, detour, options)
+		if err != nil {
+			return 0, err
+		}
+		total += int(delay1+delay2) / 5 // ms preferences like (Speedtest by Ookla)
+	}
+	return uint16(total / len(links)), nil
+}
+
+func checkOnce(ctx context.Context, link string, detour N.Dialer, options CheckOptions) (delay uint16, err error) {
+	linkURL, err := url.Parse(link)
+	if err != nil {
+		return
+	}
+	hostname := linkURL.Hostname()
+	port := linkURL.Port()
+	if port == "" {
+		switch linkURL.Scheme {
+		case "http":
+			port = "80"
+		case "https":
+			port = "443"
+		}
+	}
+	start := time.Now()
+	instance, err := detour.DialContext(ctx, "tcp", M.ParseSocksaddrHostPortStr(hostname, port))
+	if err != nil {
+		return
+	}
+	defer instance.Close()
+	if earlyConn, isEarlyConn := common.Cast[N.EarlyConn](instance); isEarlyConn && earlyConn.NeedHandshake() {
+		start = time.Now()
+	}
+	method := http.MethodHead
+	if options.ExpectedBody != "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequest(method, link, nil)
+	if err != nil {
+		return
+	}
+	timeout := options.Timeout
+	if timeout == 0 {
+		timeout = C.TCPTimeout
+	}
+	client := http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return instance, nil
+			},
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Timeout: timeout,
+	}
+	defer client.CloseIdleConnections()
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	delay = uint16(time.Since(start) / time.Millisecond)
+	if !options.matchStatus(resp.StatusCode) {
+		err = E.New("unexpected status: ", resp.StatusCode)
+		return
+	}
+	if options.ExpectedBody != "" {
+		var body []byte
+		body, err = io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+		if err != nil {
+			return
+		}
+		if !strings.Contains(string(body), options.ExpectedBody) {
+			err = E.New("unexpected body")
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,55 @@
+package urltest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStatusRanges(t *testing.T) {
+	t.Parallel()
+	ranges, err := ParseStatusRanges([]string{"204", "200-299"})
+	require.NoError(t, err)
+	require.Equal(t, []StatusRange{{204, 204}, {200, 299}}, ranges)
+	for _, invalid := range []string{"", "abc", "99", "300-200", "200-600"} {
+		_, err = ParseStatusRanges([]string{invalid})
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/generate_204", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/portal", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("please login"))
+	})
+	mux.HandleFunc("/blocked", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	ctx := context.Background()
+	expect204 := []StatusRange{{204, 204}}
+	_, err := Check(ctx, N.SystemDialer, CheckOptions{URLs: []string{server.URL + "/generate_204"}, ExpectedStatus: expect204})
+	require.NoError(t, err)
+	_, err = Check(ctx, N.SystemDialer, CheckOptions{URLs: []string{server.URL + "/blocked"}})
+	require.NoError(t, err)
+	_, err = Check(ctx, N.SystemDialer, CheckOptions{
+		URLs:           []string{server.URL + "/generate_204", server.URL + "/portal"},
+		ExpectedStatus: expect204,
+	})
+	require.ErrorContains(t, err, "unexpected status: 200")
+	_, err = Check(ctx, N.SystemDialer, CheckOptions{URLs: []string{server.URL + "/portal"}, ExpectedBody: "success"})
+	require.ErrorContains(t, err, "unexpected body")
+	_, err = Check(ctx, N.SystemDialer, CheckOptions{URLs: []string{server.URL + "/portal"}, ExpectedBody: "login"})
+	require.NoError(t, err)
+}
@@ -0,0 +1,85 @@
+package urltest
+
+// HistorySize is the number of recent samples kept for each tag.
+const HistorySize = 16
+
+type HistoryStats struct {
+	Samples     int     `json:"samples"`
+	Mean        uint16  `json:"mean"`
+	Jitter      uint16  `json:"jitter"`
+	FailureRate float64 `json:"failure_rate"`
+}
+
+func (s *HistoryStorage) appendSample(tag string, history *History) {
+	samples := s.delaySamples[tag]
+	if len(samples) >= HistorySize {
+		samples = append(samples[:0:0], samples[len(samples)-HistorySize+1:]...)
+	}
+	s.delaySamples[tag] = append(samples, history)
+}
+
+// LoadURLTestHistories returns recent samples of tag from oldest to newest,
+// a failed sample has a zero delay.
+func (s *HistoryStorage) LoadURLTestHistories(tag string) []*History {
+	if s == nil {
+		return nil
+	}
+	s.access.RLock()
+	defer s.access.RUnlock()
+	return append([]*History(nil), s.delaySamples[tag]...)
+}
+
+// LoadURLTestStats computes stats of the last size samples of tag, or all of
+// them if size is zero.
+func (s *HistoryStorage) LoadURLTestStats(tag string, size int) *HistoryStats {
+	samples := s.LoadURLTestHistories(tag)
+	if len(samples) == 0 {
+		return nil
+	}
+	if size > 0 && len(samples) > size {
+		samples = samples[len(samples)-size:]
+	}
+	stats := ComputeStats(samples)
+	return &stats
+}
+
+// ComputeStats returns the mean delay and the jitter (mean difference between
+// consecutive delays) of successful samples, and the rate of failed ones.
+func ComputeStats(samples []*History) HistoryStats {
+	stats := HistoryStats{Samples: len(samples)}
+	if len(samples) == 0 {
+		return stats
+	}
+	var (
+		failures   int
+		delaySum   int
+		jitterSum  int
+		lastDelay  int
+		successful int
+	)
+	for _, sample := range samples {
+		if sample.Delay == 0 {
+			failures++
+			continue
+		}
+		delay := int(sample.Delay)
+		delaySum += delay
+		if successful > 0 {
+			if delay > lastDelay {
+				jitterSum += delay - lastDelay
+			} else {
+				jitterSum += lastDelay - delay
+			}
+		}
+		lastDelay = delay
+		successful++
+	}
+	if successful > 0 {
+		stats.Mean = uint16(delaySum / successful)
+	}
+	if successful > 1 {
+		stats.Jitter = uint16(jitterSum / (successful - 1))
+	}
+	stats.FailureRate = float64(failures) / float64(len(samples))
+	return stats
+}
@@ -0,0 +1,33 @@
+package urltest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistorySamples(t *testing.T) {
+	t.Parallel()
+	storage := NewHistoryStorage()
+	require.Nil(t, storage.LoadURLTestStats("a", 0))
+	for i := 1; i <= HistorySize+4; i++ {
+		storage.StoreURLTestHistory("a", &History{Time: time.Now(), Delay: uint16(i * 10)})
+	}
+	samples := storage.LoadURLTestHistories("a")
+	require.Len(t, samples, HistorySize)
+	require.Equal(t, uint16(50), samples[0].Delay)
+	require.Equal(t, uint16((HistorySize+4)*10), samples[HistorySize-1].Delay)
+
+	storage.DeleteURLTestHistory("a")
+	require.Nil(t, storage.LoadURLTestHistory("a"))
+	stats := storage.LoadURLTestStats("a", 4)
+	require.Equal(t, HistoryStats{Samples: 4, Mean: 190, Jitter: 10, FailureRate: 0.25}, *stats)
+}
+
+func TestComputeStats(t *testing.T) {
+	t.Parallel()
+	samples := []*History{{Delay: 100}, {Delay: 140}, {}, {Delay: 120}, {}}
+	require.Equal(t, HistoryStats{Samples: 5, Mean: 120, Jitter: 30, FailureRate: 0.4}, ComputeStats(samples))
+	require.Equal(t, HistoryStats{Samples: 1, FailureRate: 1}, ComputeStats([]*History{{}}))
+}
@@ -0,0 +1,73 @@
+package urltest
+
+import "time"
+
+// SavedHistory is the persisted form of the histories of a tag.
+type SavedHistory struct {
+	Delay   *History    `json:"delay,omitempty"`
+	Samples []*History  `json:"samples,omitempty"`
+	UDP     *History    `json:"udp,omitempty"`
+	Stats   *CheckStats `json:"stats,omitempty"`
+}
+
+// Export returns a copy of all histories by tag.
+func (s *HistoryStorage) Export() map[string]*SavedHistory {
+	s.access.RLock()
+	defer s.access.RUnlock()
+	saved := make(map[string]*SavedHistory)
+	load := func(tag string) *SavedHistory {
+		history := saved[tag]
+		if history == nil {
+			history = new(SavedHistory)
+			saved[tag] = history
+		}
+		return history
+	}
+	for tag, history := range s.delayHistory {
+		load(tag).Delay = history
+	}
+	for tag, samples := range s.delaySamples {
+		load(tag).Samples = append([]*History(nil), samples...)
+	}
+	for tag, history := range s.udpHistory {
+		load(tag).UDP = history
+	}
+	for tag, stats := range s.checkStats {
+		copied := *stats
+		load(tag).Stats = &copied
+	}
+	return saved
+}
+
+// Import loads saved histories not older than since, histories already in
+// the storage are kept.
+func (s *HistoryStorage) Import(saved map[string]*SavedHistory, since time.Time) {
+	s.access.Lock()
+	defer s.access.Unlock()
+	for tag, history := range saved {
+		if history.Delay != nil && !history.Delay.Time.Before(since) && s.delayHistory[tag] == nil {
+			s.delayHistory[tag] = history.Delay
+		}
+		if len(s.delaySamples[tag]) == 0 {
+			var samples []*History
+			for _, sample := range history.Samples {
+				if sample != nil && !sample.Time.Before(since) {
+					samples = append(samples, sample)
+				}
+			}
+			if len(samples) > HistorySize {
+				samples = samples[len(samples)-HistorySize:]
+			}
+			if len(samples) > 0 {
+				s.delaySamples[tag] = samples
+			}
+		}
+		if history.UDP != nil && !history.UDP.Time.Before(since) && s.udpHistory[tag] == nil {
+			s.udpHistory[tag] = history.UDP
+		}
+		if history.Stats != nil && s.checkStats[tag] == nil {
+			copied := *history.Stats
+			s.checkStats[tag] = &copied
+		}
+	}
+}
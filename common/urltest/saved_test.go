@@ -0,0 +1,55 @@
+package urltest
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistoryExportImport(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	storage := NewHistoryStorage()
+	storage.StoreURLTestHistory("old", &History{Time: now.Add(-2 * time.Hour), Delay: 300})
+	storage.StoreURLTestHistory("new", &History{Time: now.Add(-2 * time.Hour), Delay: 200})
+	storage.StoreURLTestHistory("new", &History{Time: now, Delay: 100})
+	storage.StoreUDPTestHistory("new", &History{Time: now, Delay: 50})
+	storage.RecordCheckResult("new", true)
+
+	content, err := json.Marshal(storage.Export())
+	require.NoError(t, err)
+	var saved map[string]*SavedHistory
+	require.NoError(t, json.Unmarshal(content, &saved))
+
+	restored := NewHistoryStorage()
+	restored.StoreURLTestHistory("new", &History{Time: now, Delay: 80})
+	restored.Import(saved, now.Add(-time.Hour))
+	require.Nil(t, restored.LoadURLTestHistory("old"))
+	require.Empty(t, restored.LoadURLTestHistories("old"))
+	require.Equal(t, uint16(80), restored.LoadURLTestHistory("new").Delay)
+	require.Len(t, restored.LoadURLTestHistories("new"), 1)
+	require.Equal(t, uint16(50), restored.LoadUDPTestHistory("new").Delay)
+	require.Equal(t, &CheckStats{Success: 1}, restored.LoadCheckStats("new"))
+
+	restored = NewHistoryStorage()
+	restored.Import(saved, now.Add(-time.Hour))
+	require.Equal(t, uint16(100), restored.LoadURLTestHistory("new").Delay)
+	require.Len(t, restored.LoadURLTestHistories("new"), 1)
+}
+
+func TestHistoryVersion(t *testing.T) {
+	t.Parallel()
+	storage := NewHistoryStorage()
+	version := storage.Version()
+	storage.StoreURLTestHistory("a", &History{Time: time.Now(), Delay: 100})
+	require.NotEqual(t, version, storage.Version())
+	version = storage.Version()
+	storage.RecordCheckResult("a", false)
+	require.NotEqual(t, version, storage.Version())
+	version = storage.Version()
+	storage.Export()
+	storage.LoadURLTestHistory("a")
+	require.Equal(t, version, storage.Version())
+}
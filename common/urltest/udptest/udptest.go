@@ -0,0 +1,146 @@
+package udptest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/sniff"
+	C "github.com/sagernet/sing-box/constant"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	mDNS "github.com/miekg/dns"
+)
+
+const (
+	DefaultDNSServer  = "8.8.8.8:53"
+	DefaultSTUNServer = "stun.l.google.com:19302"
+)
+
+type Options struct {
+	Type    string
+	Server  M.Socksaddr
+	Timeout time.Duration
+}
+
+// NewOptions validates the probe type and fills the default server and port
+// of it.
+func NewOptions(testType string, server string) (*Options, error) {
+	var defaultServer string
+	var defaultPort uint16
+	switch testType {
+	case "", C.UDPTestTypeDNS:
+		testType = C.UDPTestTypeDNS
+		defaultServer = DefaultDNSServer
+		defaultPort = 53
+	case C.UDPTestTypeSTUN:
+		defaultServer = DefaultSTUNServer
+		defaultPort = 3478
+	default:
+		return nil, E.New("unknown udp test type: ", testType)
+	}
+	if server == "" {
+		server = defaultServer
+	}
+	serverAddr := M.ParseSocksaddr(server)
+	if !serverAddr.IsValid() {
+		return nil, E.New("invalid udp test server: ", server)
+	}
+	if serverAddr.Port == 0 {
+		serverAddr.Port = defaultPort
+	}
+	return &Options{
+		Type:   testType,
+		Server: serverAddr,
+	}, nil
+}
+
+// Check sends a DNS query or a STUN binding request through detour and
+// returns the round trip delay.
+func Check(ctx context.Context, detour N.Dialer, options Options) (uint16, error) {
+	var (
+		request  []byte
+		validate func(response []byte) error
+		timeout  time.Duration
+		err      error
+	)
+	switch options.Type {
+	case C.UDPTestTypeSTUN:
+		request, validate = newSTUNProbe()
+		timeout = C.STUNTimeout
+	default:
+		request, validate, err = newDNSProbe()
+		if err != nil {
+			return 0, err
+		}
+		timeout = C.DNSTimeout
+	}
+	if options.Timeout > 0 {
+		timeout = options.Timeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	conn, err := detour.DialContext(ctx, N.NetworkUDP, options.Server)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	if deadline, loaded := ctx.Deadline(); loaded {
+		conn.SetDeadline(deadline)
+	}
+	start := time.Now()
+	_, err = conn.Write(request)
+	if err != nil {
+		return 0, err
+	}
+	buffer := make([]byte, 1500)
+	for {
+		n, err := conn.Read(buffer)
+		if err != nil {
+			return 0, err
+		}
+		if validate(buffer[:n]) == nil {
+			return uint16(time.Since(start) / time.Millisecond), nil
+		}
+	}
+}
+
+func newDNSProbe() ([]byte, func(response []byte) error, error) {
+	message := new(mDNS.Msg)
+	message.SetQuestion("www.google.com.", mDNS.TypeA)
+	request, err := message.Pack()
+	if err != nil {
+		return nil, nil, err
+	}
+	return request, func(response []byte) error {
+		var reply mDNS.Msg
+		if err := reply.Unpack(response); err != nil {
+			return err
+		}
+		if reply.Id != message.Id || !reply.Response {
+			return E.New("unexpected dns response")
+		}
+		return nil
+	}, nil
+}
+
+func newSTUNProbe() ([]byte, func(response []byte) error) {
+	// binding request with the magic cookie and a random transaction id
+	request := make([]byte, 20)
+	copy(request, []byte{0x00, 0x01, 0x00, 0x00, 0x21, 0x12, 0xa4, 0x42})
+	rand.Read(request[8:20])
+	return request, func(response []byte) error {
+		var metadata adapter.InboundContext
+		if err := sniff.STUNMessage(context.Background(), &metadata, response); err != nil {
+			return err
+		}
+		if !bytes.Equal(response[8:20], request[8:20]) {
+			return E.New("unexpected stun response")
+		}
+		return nil
+	}
+}
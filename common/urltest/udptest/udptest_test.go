@@ -0,0 +1,87 @@
+package udptest
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewOptions(t *testing.T) {
+	t.Parallel()
+	options, err := NewOptions("", "")
+	require.NoError(t, err)
+	require.Equal(t, C.UDPTestTypeDNS, options.Type)
+	require.Equal(t, M.ParseSocksaddr(DefaultDNSServer), options.Server)
+	options, err = NewOptions(C.UDPTestTypeSTUN, "stun.example.com")
+	require.NoError(t, err)
+	require.Equal(t, uint16(3478), options.Server.Port)
+	_, err = NewOptions("quic", "")
+	require.ErrorContains(t, err, "unknown udp test type")
+}
+
+func serveUDP(t *testing.T, handler func(request []byte) []byte) M.Socksaddr {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buffer := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			if response := handler(buffer[:n]); response != nil {
+				conn.WriteTo(response, addr)
+			}
+		}
+	}()
+	return M.SocksaddrFromNet(conn.LocalAddr())
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+	dnsServer := serveUDP(t, func(request []byte) []byte {
+		var message mDNS.Msg
+		if message.Unpack(request) != nil {
+			return nil
+		}
+		response, _ := new(mDNS.Msg).SetReply(&message).Pack()
+		return response
+	})
+	stunServer := serveUDP(t, func(request []byte) []byte {
+		response := make([]byte, 20)
+		copy(response, request)
+		binary.BigEndian.PutUint16(response[0:2], 0x0101)
+		return response
+	})
+	strangerServer := serveUDP(t, func(request []byte) []byte {
+		response := make([]byte, 20)
+		copy(response, request)
+		binary.BigEndian.PutUint16(response[0:2], 0x0101)
+		response[19]++
+		return response
+	})
+	silentServer := serveUDP(t, func(request []byte) []byte {
+		return []byte("not a response")
+	})
+	ctx := context.Background()
+	_, err := Check(ctx, N.SystemDialer, Options{Type: C.UDPTestTypeDNS, Server: dnsServer})
+	require.NoError(t, err)
+	_, err = Check(ctx, N.SystemDialer, Options{Type: C.UDPTestTypeSTUN, Server: stunServer})
+	require.NoError(t, err)
+	_, err = Check(ctx, N.SystemDialer, Options{Type: C.UDPTestTypeSTUN, Server: dnsServer, Timeout: 200 * time.Millisecond})
+	require.Error(t, err)
+	_, err = Check(ctx, N.SystemDialer, Options{Type: C.UDPTestTypeSTUN, Server: strangerServer, Timeout: 200 * time.Millisecond})
+	require.Error(t, err)
+	_, err = Check(ctx, N.SystemDialer, Options{Type: C.UDPTestTypeDNS, Server: silentServer, Timeout: 200 * time.Millisecond})
+	require.Error(t, err)
+}
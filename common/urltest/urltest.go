@@ -2,15 +2,10 @@ package urltest
 
 import (
 	"context"
-	"net"
-	"net/http"
-	"net/url"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	C "github.com/sagernet/sing-box/constant"
-	"github.com/sagernet/sing/common"
-	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
 )
 
@@ -19,15 +14,27 @@ type History struct {
 	Delay uint16    `json:"delay"`
 }
 
+type CheckStats struct {
+	Success uint64 `json:"success"`
+	Failure uint64 `json:"failure"`
+}
+
 type HistoryStorage struct {
 	access       sync.RWMutex
 	delayHistory map[string]*History
+	delaySamples map[string][]*History
+	udpHistory   map[string]*History
+	checkStats   map[string]*CheckStats
 	updateHook   chan<- struct{}
+	version      atomic.Uint64
 }
 
 func NewHistoryStorage() *HistoryStorage {
 	return &HistoryStorage{
 		delayHistory: make(map[string]*History),
+		delaySamples: make(map[string][]*History),
+		udpHistory:   make(map[string]*History),
+		checkStats:   make(map[string]*CheckStats),
 	}
 }
 
@@ -44,9 +51,12 @@ func (s *HistoryStorage) LoadURLTestHistory(tag string) *History {
 	return s.delayHistory[tag]
 }
 
+// DeleteURLTestHistory removes the last history of tag and records a failed
+// sample.
 func (s *HistoryStorage) DeleteURLTestHistory(tag string) {
 	s.access.Lock()
 	delete(s.delayHistory, tag)
+	s.appendSample(tag, &History{Time: time.Now()})
 	s.access.Unlock()
 	s.notifyUpdated()
 }
@@ -54,11 +64,72 @@ func (s *HistoryStorage) DeleteURLTestHistory(tag string) {
 func (s *HistoryStorage) StoreURLTestHistory(tag string, history *History) {
 	s.access.Lock()
 	s.delayHistory[tag] = history
+	s.appendSample(tag, history)
+	s.access.Unlock()
+	s.notifyUpdated()
+}
+
+func (s *HistoryStorage) LoadUDPTestHistory(tag string) *History {
+	if s == nil {
+		return nil
+	}
+	s.access.RLock()
+	defer s.access.RUnlock()
+	return s.udpHistory[tag]
+}
+
+func (s *HistoryStorage) DeleteUDPTestHistory(tag string) {
+	s.access.Lock()
+	delete(s.udpHistory, tag)
+	s.access.Unlock()
+	s.notifyUpdated()
+}
+
+func (s *HistoryStorage) StoreUDPTestHistory(tag string, history *History) {
+	s.access.Lock()
+	s.udpHistory[tag] = history
 	s.access.Unlock()
 	s.notifyUpdated()
 }
 
+// RecordCheckResult counts a successful or failed health check of tag.
+func (s *HistoryStorage) RecordCheckResult(tag string, success bool) {
+	s.access.Lock()
+	defer s.access.Unlock()
+	stats := s.checkStats[tag]
+	if stats == nil {
+		stats = new(CheckStats)
+		s.checkStats[tag] = stats
+	}
+	if success {
+		stats.Success++
+	} else {
+		stats.Failure++
+	}
+	s.version.Add(1)
+}
+
+// Version changes whenever a history or check stat is updated.
+func (s *HistoryStorage) Version() uint64 {
+	return s.version.Load()
+}
+
+func (s *HistoryStorage) LoadCheckStats(tag string) *CheckStats {
+	if s == nil {
+		return nil
+	}
+	s.access.RLock()
+	defer s.access.RUnlock()
+	stats := s.checkStats[tag]
+	if stats == nil {
+		return nil
+	}
+	copied := *stats
+	return &copied
+}
+
 func (s *HistoryStorage) notifyUpdated() {
+	s.version.Add(1)
 	updateHook := s.updateHook
 	if updateHook != nil {
 		select {
@@ -75,67 +146,5 @@ func (s *HistoryStorage) Close() error {
 
 // URLTest performs a test on unified delay [experimental]
 func URLTest(ctx context.Context, link string, detour N.Dialer) (t uint16, err error) {
-	if link == "" {
-		link = "https://www.gstatic.com/generate_204"
-	}
-	// Function to run the delay test
-	runTest := func() (delay uint16, err error) {
-	    linkURL, err := url.Parse(link)
-	    if err != nil {
-	    	return
-	    }
-	    hostname := linkURL.Hostname()
-	    port := linkURL.Port()
-	    if port == "" {
-	        switch linkURL.Scheme {
-	            case "http":
-	                port = "80"
-	            case "https":
-	                port = "443"
-	        }
-	    }
-	    start := time.Now()
-	    instance, err := detour.DialContext(ctx, "tcp", M.ParseSocksaddrHostPortStr(hostname, port))
-	    if err != nil {
-	        return
-	    }
-	    defer instance.Close()
-	    if earlyConn, isEarlyConn := common.Cast[N.EarlyConn](instance); isEarlyConn && earlyConn.NeedHandshake() {
-	        start = time.Now()
-	    }
-	    req, err := http.NewRequest(http.MethodHead, link, nil)
-	    if err != nil {
-	        return
-	    }
-	    client := http.Client{
-	        Transport: &http.Transport{
-	            DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-	                return instance, nil
-	            },
-	        },
-	        CheckRedirect: func(req *http.Request, via []*http.Request) error {
-	            return http.ErrUseLastResponse
-	        },
-	        Timeout: C.TCPTimeout,
-	    }
-	    defer client.CloseIdleConnections()
-	    resp, err := client.Do(req.WithContext(ctx))
-	    if err != nil {
-	        return
-	    }
-	    resp.Body.Close()
-	    delay = uint16(time.Since(start) / time.Millisecond)
-	    return
-	}
-	delay1, err := runTest()
-	if err != nil {
-		return
-	}
-	delay2, err := runTest()
-	if err != nil {
-		return
-	}
-
-	t = (delay1 + delay2) / 5 // ms preferences like (Speedtest by Ookla)
-	return
+	return Check(ctx, detour, CheckOptions{URLs: []string{link}})
 }
@@ -28,8 +28,22 @@ const (
 )
 
 const (
-	TypeSelector = "selector"
-	TypeURLTest  = "urltest"
+	TypeSelector    = "selector"
+	TypeURLTest     = "urltest"
+	TypeLoadBalance = "loadbalance"
+	TypeFallback    = "fallback"
+	TypeChain       = "chain"
+)
+
+const (
+	LoadBalanceStrategyRoundRobin     = "round_robin"
+	LoadBalanceStrategyConsistentHash = "consistent_hash"
+	LoadBalanceStrategyStickySession  = "sticky_session"
+)
+
+const (
+	UDPTestTypeDNS  = "dns"
+	UDPTestTypeSTUN = "stun"
 )
 
 func ProxyDisplayName(proxyType string) string {
@@ -82,6 +96,12 @@ func ProxyDisplayName(proxyType string) string {
 		return "Selector"
 	case TypeURLTest:
 		return "URLTest"
+	case TypeLoadBalance:
+		return "LoadBalance"
+	case TypeFallback:
+		return "Fallback"
+	case TypeChain:
+		return "Chain"
 	default:
 		return "Unknown"
 	}
@@ -92,6 +112,14 @@ const (
 	ProviderTypeRemote = "remote"
 )
 
+const (
+	ProviderFormatAuto    = "auto"
+	ProviderFormatSingBox = "singbox"
+	ProviderFormatClash   = "clash"
+	ProviderFormatURI     = "uri"
+	ProviderFormatSIP008  = "sip008"
+)
+
 func ProviderDisplayName(providerType string) string {
 	switch providerType {
 	case ProviderTypeLocal:
@@ -14,6 +14,7 @@ const (
 	STUNTimeout                = 15 * time.Second
 	UDPTimeout                 = 5 * time.Minute
 	DefaultDonloadInterval     = 1 * time.Hour
+	DefaultDownloadTimeout     = 1 * time.Minute
 	DefaultURLTestInterval     = 3 * time.Minute
 	DefaultURLTestIdleTimeout  = 30 * time.Minute
 	StartTimeout               = 10 * time.Second
@@ -2,6 +2,7 @@ package cachefile
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"net/netip"
 	"os"
@@ -12,9 +13,11 @@ import (
 	"github.com/sagernet/bbolt"
 	bboltErrors "github.com/sagernet/bbolt/errors"
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/urltest"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/service"
 	"github.com/sagernet/sing/service/filemanager"
 )
 
@@ -22,14 +25,18 @@ var (
 	bucketSelected = []byte("selected")
 	bucketExpand   = []byte("group_expand")
 	bucketMode     = []byte("clash_mode")
+	bucketConfig   = []byte("clash_config")
 	bucketRuleSet  = []byte("rule_set")
 
 	bucketNameList = []string{
 		string(bucketSelected),
 		string(bucketExpand),
 		string(bucketMode),
+		string(bucketConfig),
 		string(bucketRuleSet),
 		string(bucketRDRC),
+		string(bucketHistory),
+		string(bucketProvider),
 	}
 
 	cacheIDDefault = []byte("default")
@@ -44,6 +51,12 @@ type CacheFile struct {
 	storeFakeIP       bool
 	storeRDRC         bool
 	rdrcTimeout       time.Duration
+	storeHistory      bool
+	historyTimeout    time.Duration
+	historyStorage    *urltest.HistoryStorage
+	historyDone       chan struct{}
+	historyWait       sync.WaitGroup
+	storeClashConfig  bool
 	DB                *bbolt.DB
 	saveMetadataTimer *time.Timer
 	saveFakeIPAccess  sync.RWMutex
@@ -79,17 +92,28 @@ func New(ctx context.Context, options option.CacheFileOptions) *CacheFile {
 			rdrcTimeout = 7 * 24 * time.Hour
 		}
 	}
+	var historyTimeout time.Duration
+	if options.StoreHistory {
+		if options.HistoryTimeout > 0 {
+			historyTimeout = time.Duration(options.HistoryTimeout)
+		} else {
+			historyTimeout = time.Hour
+		}
+	}
 	return &CacheFile{
-		ctx:          ctx,
-		path:         filemanager.BasePath(ctx, path),
-		cacheID:      cacheIDBytes,
-		storeFakeIP:  options.StoreFakeIP,
-		storeRDRC:    options.StoreRDRC,
-		rdrcTimeout:  rdrcTimeout,
-		saveDomain:   make(map[netip.Addr]string),
-		saveAddress4: make(map[string]netip.Addr),
-		saveAddress6: make(map[string]netip.Addr),
-		saveRDRC:     make(map[saveRDRCCacheKey]bool),
+		ctx:              ctx,
+		path:             filemanager.BasePath(ctx, path),
+		cacheID:          cacheIDBytes,
+		storeFakeIP:      options.StoreFakeIP,
+		storeRDRC:        options.StoreRDRC,
+		rdrcTimeout:      rdrcTimeout,
+		storeHistory:     options.StoreHistory,
+		historyTimeout:   historyTimeout,
+		storeClashConfig: options.StoreClashConfig,
+		saveDomain:       make(map[netip.Addr]string),
+		saveAddress4:     make(map[string]netip.Addr),
+		saveAddress6:     make(map[string]netip.Addr),
+		saveRDRC:         make(map[saveRDRCCacheKey]bool),
 	}
 }
 
@@ -148,6 +172,15 @@ func (c *CacheFile) start() error {
 		return err
 	}
 	c.DB = db
+	if c.storeHistory {
+		c.historyStorage = service.PtrFromContext[urltest.HistoryStorage](c.ctx)
+		if c.historyStorage != nil {
+			c.loadHistory(c.historyStorage)
+			c.historyDone = make(chan struct{})
+			c.historyWait.Add(1)
+			go c.loopSaveHistory()
+		}
+	}
 	return nil
 }
 
@@ -163,7 +196,14 @@ func (c *CacheFile) Close() error {
 	if c.DB == nil {
 		return nil
 	}
-	return c.DB.Close()
+	var err error
+	if c.historyDone != nil {
+		close(c.historyDone)
+		c.historyWait.Wait()
+		c.historyDone = nil
+		err = c.saveHistory(c.historyStorage)
+	}
+	return E.Errors(err, c.DB.Close())
 }
 
 func (c *CacheFile) StoreFakeIP() bool {
@@ -203,6 +243,54 @@ func (c *CacheFile) StoreMode(mode string) error {
 	})
 }
 
+func (c *CacheFile) LoadClashConfig() *adapter.SavedClashConfig {
+	if !c.storeClashConfig {
+		return nil
+	}
+	var config adapter.SavedClashConfig
+	err := c.DB.View(func(t *bbolt.Tx) error {
+		bucket := t.Bucket(bucketConfig)
+		if bucket == nil {
+			return os.ErrNotExist
+		}
+		var configBytes []byte
+		if len(c.cacheID) > 0 {
+			configBytes = bucket.Get(c.cacheID)
+		} else {
+			configBytes = bucket.Get(cacheIDDefault)
+		}
+		if len(configBytes) == 0 {
+			return os.ErrNotExist
+		}
+		return json.Unmarshal(configBytes, &config)
+	})
+	if err != nil {
+		return nil
+	}
+	return &config
+}
+
+func (c *CacheFile) StoreClashConfig(config *adapter.SavedClashConfig) error {
+	if !c.storeClashConfig {
+		return nil
+	}
+	configBytes, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return c.DB.Batch(func(t *bbolt.Tx) error {
+		bucket, err := t.CreateBucketIfNotExists(bucketConfig)
+		if err != nil {
+			return err
+		}
+		if len(c.cacheID) > 0 {
+			return bucket.Put(c.cacheID, configBytes)
+		} else {
+			return bucket.Put(cacheIDDefault, configBytes)
+		}
+	})
+}
+
 func (c *CacheFile) bucket(t *bbolt.Tx, key []byte) *bbolt.Bucket {
 	if c.cacheID == nil {
 		return t.Bucket(key)
@@ -0,0 +1,141 @@
+package cachefile
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/sagernet/bbolt"
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/urltest"
+)
+
+var (
+	bucketHistory  = []byte("url_test_history")
+	bucketProvider = []byte("provider_metadata")
+)
+
+const historySaveInterval = time.Minute
+
+// loadHistory restores saved histories not older than the history timeout into
+// storage.
+func (c *CacheFile) loadHistory(storage *urltest.HistoryStorage) {
+	saved := make(map[string]*urltest.SavedHistory)
+	c.DB.View(func(t *bbolt.Tx) error {
+		bucket := c.bucket(t, bucketHistory)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var history urltest.SavedHistory
+			if json.Unmarshal(v, &history) == nil {
+				saved[string(k)] = &history
+			}
+			return nil
+		})
+	})
+	storage.Import(saved, time.Now().Add(-c.historyTimeout))
+}
+
+// saveHistory replaces saved histories with the ones in storage.
+func (c *CacheFile) saveHistory(storage *urltest.HistoryStorage) error {
+	saved := storage.Export()
+	return c.DB.Update(func(t *bbolt.Tx) error {
+		err := c.deleteBucket(t, bucketHistory)
+		if err != nil {
+			return err
+		}
+		bucket, err := c.createBucket(t, bucketHistory)
+		if err != nil {
+			return err
+		}
+		for tag, history := range saved {
+			content, err := json.Marshal(history)
+			if err != nil {
+				return err
+			}
+			err = bucket.Put([]byte(tag), content)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// loopSaveHistory saves histories changed since the last save periodically,
+// so they survive the process being killed.
+func (c *CacheFile) loopSaveHistory() {
+	defer c.historyWait.Done()
+	ticker := time.NewTicker(historySaveInterval)
+	defer ticker.Stop()
+	savedVersion := c.historyStorage.Version()
+	for {
+		select {
+		case <-c.historyDone:
+			return
+		case <-ticker.C:
+		}
+		version := c.historyStorage.Version()
+		if version == savedVersion {
+			continue
+		}
+		err := c.saveHistory(c.historyStorage)
+		if err == nil {
+			savedVersion = version
+		}
+	}
+}
+
+func (c *CacheFile) deleteBucket(t *bbolt.Tx, key []byte) error {
+	if c.cacheID == nil {
+		if t.Bucket(key) == nil {
+			return nil
+		}
+		return t.DeleteBucket(key)
+	}
+	bucket := t.Bucket(c.cacheID)
+	if bucket == nil || bucket.Bucket(key) == nil {
+		return nil
+	}
+	return bucket.DeleteBucket(key)
+}
+
+func (c *CacheFile) LoadProviderMetadata(tag string) *adapter.SavedProviderMetadata {
+	if !c.storeHistory {
+		return nil
+	}
+	var metadata adapter.SavedProviderMetadata
+	err := c.DB.View(func(t *bbolt.Tx) error {
+		bucket := c.bucket(t, bucketProvider)
+		if bucket == nil {
+			return os.ErrNotExist
+		}
+		content := bucket.Get([]byte(tag))
+		if len(content) == 0 {
+			return os.ErrNotExist
+		}
+		return json.Unmarshal(content, &metadata)
+	})
+	if err != nil {
+		return nil
+	}
+	return &metadata
+}
+
+func (c *CacheFile) SaveProviderMetadata(tag string, metadata *adapter.SavedProviderMetadata) error {
+	if !c.storeHistory {
+		return nil
+	}
+	content, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	return c.DB.Batch(func(t *bbolt.Tx) error {
+		bucket, err := c.createBucket(t, bucketProvider)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(tag), content)
+	})
+}
@@ -1,9 +1,19 @@
 package clashapi
 
 import (
+	"context"
+	"errors"
+	"io"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/service"
+	"github.com/sagernet/sing/service/filemanager"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
@@ -12,7 +22,7 @@ import (
 func configRouter(server *Server, logFactory log.Factory) http.Handler {
 	r := chi.NewRouter()
 	r.Get("/", getConfigs(server, logFactory))
-	r.Put("/", updateConfigs)
+	r.Put("/", updateConfigs(server))
 	r.Patch("/", patchConfigs(server))
 	return r
 }
@@ -32,6 +42,21 @@ type configSchema struct {
 	Tun         map[string]any `json:"tun"`
 }
 
+type patchConfigSchema struct {
+	Mode     *string `json:"mode"`
+	LogLevel *string `json:"log-level"`
+	IPv6     *bool   `json:"ipv6"`
+}
+
+// parseClashLogLevel parses log levels of both Clash and sing-box, silent is
+// mapped to the panic level.
+func parseClashLogLevel(level string) (log.Level, error) {
+	if level == "silent" {
+		return log.LevelPanic, nil
+	}
+	return log.ParseLevel(level)
+}
+
 func getConfigs(server *Server, logFactory log.Factory) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		logLevel := logFactory.Level()
@@ -46,26 +71,121 @@ func getConfigs(server *Server, logFactory log.Factory) func(w http.ResponseWrit
 			AllowLan:    true,
 			BindAddress: "*",
 			LogLevel:    log.FormatLevel(logLevel),
+			IPv6:        server.router.IPv6Enabled(),
 		})
 	}
 }
 
 func patchConfigs(server *Server) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var newConfig configSchema
+		var newConfig patchConfigSchema
 		err := render.DecodeJSON(r.Body, &newConfig)
 		if err != nil {
 			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, ErrBadRequest)
 			return
 		}
-		if newConfig.Mode != "" {
-			server.SetMode(newConfig.Mode)
+		var logLevel log.Level
+		if newConfig.LogLevel != nil {
+			logLevel, err = parseClashLogLevel(*newConfig.LogLevel)
+			if err != nil {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, newError(err.Error()))
+				return
+			}
+		}
+		if newConfig.Mode != nil {
+			server.SetMode(*newConfig.Mode)
+		}
+		if newConfig.LogLevel != nil {
+			server.SetLogLevel(logLevel)
+		}
+		if newConfig.IPv6 != nil {
+			server.SetIPv6(*newConfig.IPv6)
 		}
 		render.NoContent(w, r)
 	}
 }
 
-func updateConfigs(w http.ResponseWriter, r *http.Request) {
-	render.NoContent(w, r)
+type updateConfigRequest struct {
+	Path    string `json:"path"`
+	Payload string `json:"payload"`
+}
+
+type configError struct {
+	Message string `json:"message"`
+	Stage   string `json:"stage"`
+}
+
+func updateConfigs(server *Server) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request updateConfigRequest
+		err := render.DecodeJSON(r.Body, &request)
+		if err != nil && !errors.Is(err, io.EOF) {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, ErrBadRequest)
+			return
+		}
+		content := []byte(request.Payload)
+		if request.Path != "" {
+			var path string
+			path, err = safeConfigPath(server.ctx, request.Path)
+			if err == nil {
+				content, err = os.ReadFile(path)
+			}
+			if err != nil {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, &configError{Message: err.Error(), Stage: "read"})
+				return
+			}
+		}
+		reloader := service.FromContext[adapter.ConfigReloader](server.ctx)
+		if reloader == nil {
+			render.Status(r, http.StatusNotImplemented)
+			render.JSON(w, r, newError("Reload is not supported"))
+			return
+		}
+		err = reloader.ReloadConfig(content)
+		if err != nil {
+			var invalidErr *adapter.ConfigError
+			if errors.As(err, &invalidErr) {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, &configError{Message: invalidErr.Err.Error(), Stage: invalidErr.Stage})
+			} else {
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, newError(err.Error()))
+			}
+			return
+		}
+		render.Status(r, http.StatusAccepted)
+		render.JSON(w, r, render.M{
+			"message": "Reloading, connections are interrupted until the new configuration is started",
+		})
+	}
+}
+
+// safeConfigPath resolves path against the working directory and rejects
+// paths outside of it, so API clients can not read arbitrary files.
+func safeConfigPath(ctx context.Context, path string) (string, error) {
+	basePath, err := filepath.Abs(filemanager.BasePath(ctx, "."))
+	if err != nil {
+		return "", err
+	}
+	basePath, err = filepath.EvalSymlinks(basePath)
+	if err != nil {
+		return "", err
+	}
+	path, err = filepath.Abs(filemanager.BasePath(ctx, path))
+	if err != nil {
+		return "", err
+	}
+	resolvedPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", E.New("invalid config path: ", path)
+	}
+	relPath, err := filepath.Rel(basePath, resolvedPath)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return "", E.New("path is not in the working directory: ", path)
+	}
+	return resolvedPath, nil
 }
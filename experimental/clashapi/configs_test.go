@@ -0,0 +1,70 @@
+package clashapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/service"
+	"github.com/sagernet/sing/service/filemanager"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testConfigReloader struct {
+	content []byte
+	err     error
+}
+
+func (r *testConfigReloader) ReloadConfig(content []byte) error {
+	r.content = content
+	return r.err
+}
+
+func TestUpdateConfigsPath(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	workingDirectory := filepath.Join(root, "work")
+	require.NoError(t, os.MkdirAll(filepath.Join(workingDirectory, "configs"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(workingDirectory, "configs", "config.json"), []byte(`{"log": {}}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "secret.json"), []byte(`{"secret": true}`), 0o644))
+	require.NoError(t, os.Symlink(filepath.Join(root, "secret.json"), filepath.Join(workingDirectory, "link.json")))
+
+	ctx := service.ContextWithDefaultRegistry(context.Background())
+	ctx = filemanager.WithDefault(ctx, workingDirectory, "", os.Getuid(), os.Getgid())
+	reloader := &testConfigReloader{}
+	service.MustRegister[adapter.ConfigReloader](ctx, reloader)
+	handler := updateConfigs(&Server{ctx: ctx})
+	doRequest := func(body string) int {
+		request := httptest.NewRequest(http.MethodPut, "/", bytes.NewReader([]byte(body)))
+		request.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		handler(recorder, request)
+		return recorder.Code
+	}
+
+	require.Equal(t, http.StatusAccepted, doRequest(`{"path": "configs/config.json"}`))
+	require.Equal(t, `{"log": {}}`, string(reloader.content))
+	require.Equal(t, http.StatusAccepted, doRequest(`{"path": "`+filepath.Join(workingDirectory, "configs", "config.json")+`"}`))
+	reloader.content = nil
+	for _, path := range []string{
+		"../secret.json",
+		filepath.Join(root, "secret.json"),
+		"link.json",
+		"missing.json",
+		"/etc/passwd",
+	} {
+		require.Equal(t, http.StatusBadRequest, doRequest(`{"path": "`+path+`"}`), path)
+	}
+	require.Nil(t, reloader.content)
+	require.Equal(t, http.StatusAccepted, doRequest(`{"payload": "{}"}`))
+	require.Equal(t, "{}", string(reloader.content))
+	reloader.err = &adapter.ConfigError{Stage: "create", Err: errors.New("unknown outbound")}
+	require.Equal(t, http.StatusBadRequest, doRequest(`{"payload": "{}"}`))
+}
@@ -1,53 +1,71 @@
 package clashapi
 
 import (
+	"bytes"
 	"net/http"
 
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/websocket"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 )
 
-func profileRouter() http.Handler {
+func profileRouter(router adapter.Router) http.Handler {
 	r := chi.NewRouter()
-	r.Get("/tracing", subscribeTracing)
+	r.Get("/tracing", subscribeTracing(router))
 	return r
 }
 
-func subscribeTracing(w http.ResponseWriter, r *http.Request) {
-	// if !profile.Tracing.Load() {
-	render.Status(r, http.StatusNotFound)
-	render.JSON(w, r, ErrNotFound)
-	//return
-	//}
-
-	/*wsConn, err := upgrader.Upgrade(w, r, nil)
-	if err != nil {
-		return
-	}
-
-	ch := make(chan map[string]any, 1024)
-	sub := event.Subscribe()
-	defer event.UnSubscribe(sub)
-	buf := &bytes.Buffer{}
+func subscribeTracing(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tracer := router.Tracer()
+		subscription, done, err := tracer.Subscribe()
+		if err != nil {
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		defer tracer.UnSubscribe(subscription)
 
-	go func() {
-		for elm := range sub {
-			select {
-			case ch <- elm:
-			default:
+		var wsConn *websocket.Conn
+		if websocket.IsWebSocketUpgrade(r) {
+			wsConn, err = upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
 			}
+			defer wsConn.Close()
 		}
-		close(ch)
-	}()
 
-	for elm := range ch {
-		buf.Reset()
-		if err := json.NewEncoder(buf).Encode(elm); err != nil {
-			break
+		if wsConn == nil {
+			w.Header().Set("Content-Type", "application/json")
+			render.Status(r, http.StatusOK)
 		}
 
-		if err := wsConn.WriteMessage(websocket.TextMessage, buf.Bytes()); err != nil {
-			break
+		buf := &bytes.Buffer{}
+		for {
+			select {
+			case <-done:
+				return
+			case <-r.Context().Done():
+				return
+			case trace := <-subscription:
+				buf.Reset()
+				err = json.NewEncoder(buf).Encode(trace)
+				if err != nil {
+					return
+				}
+				if wsConn == nil {
+					_, err = w.Write(buf.Bytes())
+					w.(http.Flusher).Flush()
+				} else {
+					err = wsConn.WriteMessage(websocket.TextMessage, buf.Bytes())
+				}
+				if err != nil {
+					return
+				}
+			}
 		}
-	}*/
+	}
 }
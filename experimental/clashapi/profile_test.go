@@ -0,0 +1,32 @@
+package clashapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/tracing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testTracingRouter struct {
+	adapter.Router
+	tracer *tracing.Tracer
+}
+
+func (r *testTracingRouter) Tracer() *tracing.Tracer {
+	return r.tracer
+}
+
+func TestProfileTracingClosed(t *testing.T) {
+	t.Parallel()
+	tracer := tracing.NewTracer()
+	require.NoError(t, tracer.Close())
+	handler := profileRouter(&testTracingRouter{tracer: tracer})
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/tracing", nil))
+	require.Equal(t, http.StatusInternalServerError, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "message")
+}
@@ -8,6 +8,8 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	P "github.com/sagernet/sing-box/provider"
 	"github.com/sagernet/sing/common"
 	F "github.com/sagernet/sing/common/format"
 	"github.com/sagernet/sing/common/json/badjson"
@@ -23,21 +25,28 @@ func proxyProviderRouter(server *Server, router adapter.Router) http.Handler {
 		r.Get("/", getProvider(server))
 		r.Put("/", updateProvider(server, router))
 		r.Get("/healthcheck", healthCheckProvider(server))
+		r.Get("/export", exportProvider)
 	})
 	return r
 }
 
 func providerInfo(server *Server, provider adapter.OutboundProvider) *render.M {
-	return &render.M{
+	info := render.M{
 		"name":             provider.Tag(),
 		"type":             "Proxy",
 		"vehicleType":      C.ProviderDisplayName(provider.Type()),
 		"subscriptionInfo": provider.SubInfo(),
+		"subscription":     provider.SubscriptionStatus(),
+		"skipped":          provider.SkippedOutbounds(),
 		"updatedAt":        provider.UpdateTime().Format("2006-01-02T15:04:05.999999999-07:00"),
 		"proxies": common.Map(provider.Outbounds(), func(it adapter.Outbound) *badjson.JSONObject {
 			return proxyInfo(server, it)
 		}),
 	}
+	if remoteProvider, isRemote := provider.(*P.RemoteProvider); isRemote {
+		info["downloadURL"] = remoteProvider.LastURL()
+	}
+	return &info
 }
 
 func getProviders(server *Server, router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
@@ -93,6 +102,29 @@ func healthCheckProvider(server *Server) func(w http.ResponseWriter, r *http.Req
 	}
 }
 
+func exportProvider(w http.ResponseWriter, r *http.Request) {
+	provider := r.Context().Value(CtxKeyProvider).(adapter.OutboundProvider)
+	format := r.URL.Query().Get("format")
+	var outbounds []option.Outbound
+	for _, it := range provider.Outbounds() {
+		if outboundWithOptions, isExportable := it.(adapter.OutboundWithOptions); isExportable {
+			outbounds = append(outbounds, outboundWithOptions.Options())
+		}
+	}
+	content, _, err := P.ExportContent(outbounds, format)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, newError(err.Error()))
+		return
+	}
+	if format == "clash" {
+		w.Header().Set("Content-Type", "text/yaml; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	w.Write(content)
+}
+
 func parseProviderName(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		name := getEscapeParam(r, "name")
@@ -12,6 +12,7 @@ import (
 	"github.com/sagernet/sing-box/common/urltest"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/outbound"
+	P "github.com/sagernet/sing-box/provider"
 	"github.com/sagernet/sing/common"
 	F "github.com/sagernet/sing/common/format"
 	"github.com/sagernet/sing/common/json/badjson"
@@ -29,6 +30,7 @@ func proxyRouter(server *Server, router adapter.Router) http.Handler {
 		r.Use(parseProxyName, findProxyByName(router))
 		r.Get("/", getProxy(server))
 		r.Get("/delay", getProxyDelay(server))
+		r.Get("/export", exportProxy)
 		r.Put("/", updateProxy)
 	})
 	return r
@@ -70,12 +72,18 @@ func proxyInfo(server *Server, detour adapter.Outbound) *badjson.JSONObject {
 	info.Put("type", clashType)
 	info.Put("name", detour.Tag())
 	info.Put("udp", common.Contains(detour.Network(), N.NetworkUDP))
-	delayHistory := server.urlTestHistory.LoadURLTestHistory(adapter.OutboundTag(detour))
+	delayHistory := server.urlTestHistory.LoadURLTestHistories(adapter.OutboundTag(detour))
 	if delayHistory != nil {
-		info.Put("history", []*urltest.History{delayHistory})
+		info.Put("history", delayHistory)
 	} else {
 		info.Put("history", []*urltest.History{})
 	}
+	if stats := server.urlTestHistory.LoadURLTestStats(adapter.OutboundTag(detour), 0); stats != nil {
+		info.Put("stats", stats)
+	}
+	if checkStats := server.urlTestHistory.LoadCheckStats(adapter.OutboundTag(detour)); checkStats != nil {
+		info.Put("healthcheck", checkStats)
+	}
 	if group, isGroup := detour.(adapter.OutboundGroup); isGroup {
 		if urltestGroup, isURLTest := group.(adapter.URLTestGroup); isURLTest {
 			urltestGroup.PerformUpdateCheck("", true)
@@ -99,8 +107,8 @@ func getProxies(server *Server, router adapter.Router) func(w http.ResponseWrite
 			switch detour.Type() {
 			case C.TypeDirect, C.TypeBlock, C.TypeDNS:
 				continue
-		    case C.TypeSelector, C.TypeURLTest:
-		        allProxies = append(allProxies, detour.Tag())
+			case C.TypeSelector, C.TypeURLTest, C.TypeLoadBalance, C.TypeFallback, C.TypeChain:
+				allProxies = append(allProxies, detour.Tag())
 			}
 		}
 
@@ -188,6 +196,34 @@ func updateProxy(w http.ResponseWriter, r *http.Request) {
 	render.NoContent(w, r)
 }
 
+func exportProxy(w http.ResponseWriter, r *http.Request) {
+	proxy := r.Context().Value(CtxKeyProxy).(adapter.Outbound)
+	outboundWithOptions, isExportable := proxy.(adapter.OutboundWithOptions)
+	if !isExportable {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, newError("Proxy cannot be exported"))
+		return
+	}
+	options := outboundWithOptions.Options()
+	response := render.M{
+		"name": proxy.Tag(),
+	}
+	uri, uriErr := P.ExportURI(options)
+	if uriErr == nil {
+		response["uri"] = uri
+	}
+	clashProxy, clashErr := P.ExportClash(options)
+	if clashErr == nil {
+		response["clash"] = clashProxy
+	}
+	if uriErr != nil && clashErr != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, newError(clashErr.Error()))
+		return
+	}
+	render.JSON(w, r, response)
+}
+
 func getProxyDelay(server *Server) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		query := r.URL.Query()
@@ -0,0 +1,51 @@
+package clashapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/urltest"
+	C "github.com/sagernet/sing-box/constant"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testProxy struct {
+	adapter.Outbound
+	tag string
+}
+
+func (p *testProxy) Type() string {
+	return C.TypeDirect
+}
+
+func (p *testProxy) Tag() string {
+	return p.tag
+}
+
+func (p *testProxy) Network() []string {
+	return []string{N.NetworkTCP}
+}
+
+func TestProxyInfoStats(t *testing.T) {
+	t.Parallel()
+	history := urltest.NewHistoryStorage()
+	server := &Server{urlTestHistory: history}
+	content, err := json.Marshal(proxyInfo(server, &testProxy{tag: "node"}))
+	require.NoError(t, err)
+	require.NotContains(t, string(content), "stats")
+
+	for _, delay := range []uint16{100, 0, 200} {
+		history.StoreURLTestHistory("node", &urltest.History{Time: time.Now(), Delay: delay})
+	}
+	content, err = json.Marshal(proxyInfo(server, &testProxy{tag: "node"}))
+	require.NoError(t, err)
+	var info struct {
+		Stats urltest.HistoryStats `json:"stats"`
+	}
+	require.NoError(t, json.Unmarshal(content, &info))
+	require.Equal(t, urltest.HistoryStats{Samples: 3, Mean: 150, Jitter: 100, FailureRate: 1.0 / 3}, info.Stats)
+}
@@ -0,0 +1,109 @@
+package clashapi
+
+import (
+	"net/http"
+	"net/netip"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/process"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+func routeRouter(router adapter.Router) http.Handler {
+	r := chi.NewRouter()
+	r.Post("/test", testRoute(router))
+	return r
+}
+
+// routeTestRequest is a synthetic connection, process_name may also be a
+// full path to match process_path rules.
+type routeTestRequest struct {
+	Inbound     string `json:"inbound"`
+	Network     string `json:"network"`
+	Domain      string `json:"domain"`
+	IP          string `json:"ip"`
+	Port        uint16 `json:"port"`
+	Source      string `json:"source"`
+	ProcessName string `json:"process_name"`
+	PackageName string `json:"package_name"`
+	User        string `json:"user"`
+	AuthUser    string `json:"auth_user"`
+	WIFISSID    string `json:"wifi_ssid"`
+	WIFIBSSID   string `json:"wifi_bssid"`
+}
+
+func (r routeTestRequest) metadata() (adapter.InboundContext, error) {
+	metadata := adapter.InboundContext{
+		Inbound: r.Inbound,
+		Network: r.Network,
+		User:    r.AuthUser,
+	}
+	var destinationAddr netip.Addr
+	if r.IP != "" {
+		var err error
+		destinationAddr, err = netip.ParseAddr(r.IP)
+		if err != nil {
+			return metadata, E.Cause(err, "parse ip")
+		}
+	}
+	if r.Domain != "" {
+		metadata.Destination = M.Socksaddr{Fqdn: r.Domain, Port: r.Port}
+		if destinationAddr.IsValid() {
+			metadata.DestinationAddresses = []netip.Addr{destinationAddr}
+		}
+	} else if destinationAddr.IsValid() {
+		metadata.Destination = M.Socksaddr{Addr: destinationAddr, Port: r.Port}
+	} else {
+		return metadata, E.New("missing domain or ip")
+	}
+	if r.Source != "" {
+		metadata.Source = M.ParseSocksaddr(r.Source)
+		if !metadata.Source.IsIP() {
+			return metadata, E.New("invalid source: ", r.Source)
+		}
+	}
+	if r.ProcessName != "" || r.PackageName != "" || r.User != "" {
+		metadata.ProcessInfo = &process.Info{
+			ProcessPath: r.ProcessName,
+			PackageName: r.PackageName,
+			User:        r.User,
+			UserId:      -1,
+		}
+	}
+	if r.WIFISSID != "" || r.WIFIBSSID != "" {
+		metadata.WIFIState = &adapter.WIFIState{
+			SSID:  r.WIFISSID,
+			BSSID: r.WIFIBSSID,
+		}
+	}
+	return metadata, nil
+}
+
+func testRoute(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request routeTestRequest
+		err := render.DecodeJSON(r.Body, &request)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, ErrBadRequest)
+			return
+		}
+		metadata, err := request.metadata()
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		result, err := router.TestRoute(r.Context(), metadata)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		render.JSON(w, r, result)
+	}
+}
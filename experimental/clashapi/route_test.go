@@ -0,0 +1,83 @@
+package clashapi
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testRouteRouter struct {
+	adapter.Router
+	metadata adapter.InboundContext
+}
+
+func (r *testRouteRouter) TestRoute(ctx context.Context, metadata adapter.InboundContext) (*adapter.RouteTestResult, error) {
+	if metadata.Inbound == "unknown" {
+		return nil, E.New("inbound not found: ", metadata.Inbound)
+	}
+	r.metadata = metadata
+	return &adapter.RouteTestResult{
+		RuleIndex:    1,
+		Outbound:     "proxy",
+		Chain:        []string{"proxy", "node"},
+		DNSRuleIndex: -1,
+		DNSServer:    "local",
+	}, nil
+}
+
+func TestRouteRouterTest(t *testing.T) {
+	t.Parallel()
+	router := &testRouteRouter{}
+	handler := routeRouter(router)
+	doRequest := func(body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader([]byte(body)))
+		request.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := doRequest(`{"inbound": "mixed-in", "network": "udp", "domain": "example.com", "ip": "1.1.1.1", "port": 53, "source": "192.168.1.2:5353", "process_name": "/usr/bin/curl", "user": "root", "auth_user": "admin", "wifi_ssid": "home"}`)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var result adapter.RouteTestResult
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+	require.Equal(t, 1, result.RuleIndex)
+	require.Equal(t, []string{"proxy", "node"}, result.Chain)
+	metadata := router.metadata
+	require.Equal(t, "mixed-in", metadata.Inbound)
+	require.Equal(t, "udp", metadata.Network)
+	require.Equal(t, M.Socksaddr{Fqdn: "example.com", Port: 53}, metadata.Destination)
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("1.1.1.1")}, metadata.DestinationAddresses)
+	require.Equal(t, M.ParseSocksaddr("192.168.1.2:5353"), metadata.Source)
+	require.Equal(t, "/usr/bin/curl", metadata.ProcessInfo.ProcessPath)
+	require.Equal(t, "root", metadata.ProcessInfo.User)
+	require.Equal(t, "admin", metadata.User)
+	require.Equal(t, "home", metadata.WIFIState.SSID)
+
+	recorder = doRequest(`{"ip": "10.0.0.1", "port": 443}`)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, M.ParseSocksaddrHostPort("10.0.0.1", 443), router.metadata.Destination)
+	require.Nil(t, router.metadata.ProcessInfo)
+	require.Nil(t, router.metadata.WIFIState)
+
+	for _, body := range []string{
+		`{"port": 443}`,
+		`{"ip": "example.com"}`,
+		`{"domain": "example.com", "source": "example.com:80"}`,
+		`{"inbound": "unknown", "domain": "example.com"}`,
+		`{`,
+	} {
+		recorder = doRequest(body)
+		require.Equal(t, http.StatusBadRequest, recorder.Code, body)
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"net/http"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
 	N "github.com/sagernet/sing/common/network"
 
 	"github.com/go-chi/chi/v5"
@@ -14,9 +17,12 @@ import (
 func ruleRouter(router adapter.Router) http.Handler {
 	r := chi.NewRouter()
 	r.Get("/", getRules(router))
+	r.Post("/", insertRule(router))
 	r.Route("/{uuid}", func(r chi.Router) {
 		r.Use(parseRuleUUID, findRuleByUUID(router))
 		r.Put("/", changeRuleStatus)
+		r.Patch("/", updateRule(router))
+		r.Delete("/", removeRule(router))
 	})
 	return r
 }
@@ -125,3 +131,136 @@ func changeRuleStatus(w http.ResponseWriter, r *http.Request) {
 	rule.ChangeStatus()
 	render.NoContent(w, r)
 }
+
+type insertRuleRequest struct {
+	Type  string          `json:"type"`
+	Index *int            `json:"index"`
+	Rule  json.RawMessage `json:"rule"`
+}
+
+type updateRuleRequest struct {
+	Index *int            `json:"index"`
+	Rule  json.RawMessage `json:"rule"`
+}
+
+func newRuleInfo(rule adapter.Rule, ruleType string) Rule {
+	return Rule{
+		Type:     ruleType,
+		Payload:  rule.String(),
+		Proxy:    rule.Outbound(),
+		Disabled: rule.Disabled(),
+		UUID:     rule.UUID(),
+	}
+}
+
+func insertRule(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request insertRuleRequest
+		err := render.DecodeJSON(r.Body, &request)
+		if err != nil || len(request.Rule) == 0 {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, ErrBadRequest)
+			return
+		}
+		index := -1
+		if request.Index != nil {
+			index = *request.Index
+		}
+		var info Rule
+		switch request.Type {
+		case "", "route", "ROUTE":
+			var options option.Rule
+			options, err = json.UnmarshalExtended[option.Rule](request.Rule)
+			if err == nil {
+				var rule adapter.Rule
+				rule, err = router.InsertRule(index, options)
+				if err == nil {
+					info = newRuleInfo(rule, "ROUTE")
+				}
+			}
+		case "dns", "DNS":
+			var options option.DNSRule
+			options, err = json.UnmarshalExtended[option.DNSRule](request.Rule)
+			if err == nil {
+				var rule adapter.DNSRule
+				rule, err = router.InsertDNSRule(index, options)
+				if err == nil {
+					info = newRuleInfo(rule, "DNS")
+				}
+			}
+		default:
+			err = E.New("unknown rule type: ", request.Type)
+		}
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		render.JSON(w, r, info)
+	}
+}
+
+// updateRule replaces the rule if `rule` is given, then moves it if `index`
+// is given, the updated rule gets a new UUID.
+func updateRule(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rule := r.Context().Value(CtxKeyRule).(adapter.Rule)
+		var request updateRuleRequest
+		err := render.DecodeJSON(r.Body, &request)
+		if err != nil || len(request.Rule) == 0 && request.Index == nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, ErrBadRequest)
+			return
+		}
+		ruleType := "ROUTE"
+		if _, isDNSRule := rule.(adapter.DNSRule); isDNSRule {
+			ruleType = "DNS"
+		}
+		if len(request.Rule) > 0 {
+			if ruleType == "DNS" {
+				var options option.DNSRule
+				options, err = json.UnmarshalExtended[option.DNSRule](request.Rule)
+				if err == nil {
+					rule, err = router.UpdateDNSRule(rule.UUID(), options)
+				}
+			} else {
+				var options option.Rule
+				options, err = json.UnmarshalExtended[option.Rule](request.Rule)
+				if err == nil {
+					rule, err = router.UpdateRule(rule.UUID(), options)
+				}
+			}
+		}
+		if err == nil && request.Index != nil {
+			if ruleType == "DNS" {
+				err = router.MoveDNSRule(rule.UUID(), *request.Index)
+			} else {
+				err = router.MoveRule(rule.UUID(), *request.Index)
+			}
+		}
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		render.JSON(w, r, newRuleInfo(rule, ruleType))
+	}
+}
+
+func removeRule(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rule := r.Context().Value(CtxKeyRule).(adapter.Rule)
+		var err error
+		if _, isDNSRule := rule.(adapter.DNSRule); isDNSRule {
+			err = router.RemoveDNSRule(rule.UUID())
+		} else {
+			err = router.RemoveRule(rule.UUID())
+		}
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		render.NoContent(w, r)
+	}
+}
@@ -0,0 +1,223 @@
+package clashapi
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testRule struct {
+	adapter.Rule
+	uuid     string
+	outbound string
+	disabled bool
+}
+
+func (r *testRule) UUID() string {
+	return r.uuid
+}
+
+func (r *testRule) Outbound() string {
+	return r.outbound
+}
+
+func (r *testRule) String() string {
+	return "test"
+}
+
+func (r *testRule) Disabled() bool {
+	return r.disabled
+}
+
+func (r *testRule) ChangeStatus() {
+	r.disabled = !r.disabled
+}
+
+type testDNSRule struct {
+	adapter.DNSRule
+	uuid     string
+	outbound string
+	disabled bool
+}
+
+func (r *testDNSRule) UUID() string {
+	return r.uuid
+}
+
+func (r *testDNSRule) Outbound() string {
+	return r.outbound
+}
+
+func (r *testDNSRule) String() string {
+	return "test"
+}
+
+func (r *testDNSRule) Disabled() bool {
+	return r.disabled
+}
+
+func (r *testDNSRule) ChangeStatus() {
+	r.disabled = !r.disabled
+}
+
+// testRuleRouter keeps rules by UUID and records the last edit.
+type testRuleRouter struct {
+	adapter.Router
+	rules     map[string]adapter.Rule
+	dnsRules  map[string]adapter.DNSRule
+	lastIndex int
+	nextUUID  int
+}
+
+func newTestRuleRouter() *testRuleRouter {
+	return &testRuleRouter{
+		rules:    make(map[string]adapter.Rule),
+		dnsRules: make(map[string]adapter.DNSRule),
+	}
+}
+
+func (r *testRuleRouter) newUUID() string {
+	r.nextUUID++
+	return strings.Repeat("a", r.nextUUID)
+}
+
+func (r *testRuleRouter) Rule(uuid string) (adapter.Rule, bool) {
+	rule, loaded := r.rules[uuid]
+	return rule, loaded
+}
+
+func (r *testRuleRouter) DNSRule(uuid string) (adapter.DNSRule, bool) {
+	rule, loaded := r.dnsRules[uuid]
+	return rule, loaded
+}
+
+func (r *testRuleRouter) InsertRule(index int, options option.Rule) (adapter.Rule, error) {
+	if options.DefaultOptions.Outbound == "" {
+		return nil, E.New("missing outbound")
+	}
+	rule := &testRule{uuid: r.newUUID(), outbound: options.DefaultOptions.Outbound}
+	r.rules[rule.uuid] = rule
+	r.lastIndex = index
+	return rule, nil
+}
+
+func (r *testRuleRouter) UpdateRule(uuid string, options option.Rule) (adapter.Rule, error) {
+	delete(r.rules, uuid)
+	return r.InsertRule(-1, options)
+}
+
+func (r *testRuleRouter) MoveRule(uuid string, index int) error {
+	if _, loaded := r.rules[uuid]; !loaded {
+		return E.New("rule not found: ", uuid)
+	}
+	r.lastIndex = index
+	return nil
+}
+
+func (r *testRuleRouter) RemoveRule(uuid string) error {
+	delete(r.rules, uuid)
+	return nil
+}
+
+func (r *testRuleRouter) InsertDNSRule(index int, options option.DNSRule) (adapter.DNSRule, error) {
+	rule := &testDNSRule{uuid: r.newUUID(), outbound: options.DefaultOptions.Server}
+	r.dnsRules[rule.uuid] = rule
+	r.lastIndex = index
+	return rule, nil
+}
+
+func (r *testRuleRouter) UpdateDNSRule(uuid string, options option.DNSRule) (adapter.DNSRule, error) {
+	delete(r.dnsRules, uuid)
+	return r.InsertDNSRule(-1, options)
+}
+
+func (r *testRuleRouter) MoveDNSRule(uuid string, index int) error {
+	r.lastIndex = index
+	return nil
+}
+
+func (r *testRuleRouter) RemoveDNSRule(uuid string) error {
+	delete(r.dnsRules, uuid)
+	return nil
+}
+
+func doRuleRequest(t *testing.T, handler http.Handler, method string, path string, body string) (int, Rule) {
+	request := httptest.NewRequest(method, path, bytes.NewReader([]byte(body)))
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	var rule Rule
+	if recorder.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rule))
+	}
+	return recorder.Code, rule
+}
+
+func TestRuleRouterEdit(t *testing.T) {
+	t.Parallel()
+	router := newTestRuleRouter()
+	handler := ruleRouter(router)
+
+	status, rule := doRuleRequest(t, handler, http.MethodPost, "/", `{"index": 1, "rule": {"domain": "example.com", "outbound": "direct"}}`)
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "ROUTE", rule.Type)
+	require.Equal(t, "direct", rule.Proxy)
+	require.Equal(t, 1, router.lastIndex)
+
+	status, dnsRule := doRuleRequest(t, handler, http.MethodPost, "/", `{"type": "dns", "rule": {"domain": "example.com", "server": "local"}}`)
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "DNS", dnsRule.Type)
+	require.Equal(t, "local", dnsRule.Proxy)
+	require.Equal(t, -1, router.lastIndex)
+
+	for _, body := range []string{
+		`{"type": "unknown", "rule": {"domain": "example.com", "outbound": "direct"}}`,
+		`{"rule": {"domain": "example.com"}}`,
+		`{"type": "route"}`,
+		`{`,
+	} {
+		status, _ = doRuleRequest(t, handler, http.MethodPost, "/", body)
+		require.Equal(t, http.StatusBadRequest, status, body)
+	}
+
+	status, updated := doRuleRequest(t, handler, http.MethodPatch, "/"+rule.UUID, `{"index": 0, "rule": {"domain": "example.com", "outbound": "block"}}`)
+	require.Equal(t, http.StatusOK, status)
+	require.NotEqual(t, rule.UUID, updated.UUID)
+	require.Equal(t, "block", updated.Proxy)
+	require.Equal(t, 0, router.lastIndex)
+	_, loaded := router.rules[rule.UUID]
+	require.False(t, loaded)
+
+	status, _ = doRuleRequest(t, handler, http.MethodPatch, "/"+updated.UUID, `{}`)
+	require.Equal(t, http.StatusBadRequest, status)
+	status, _ = doRuleRequest(t, handler, http.MethodPatch, "/"+rule.UUID, `{"index": 0}`)
+	require.Equal(t, http.StatusNotFound, status)
+
+	status, moved := doRuleRequest(t, handler, http.MethodPatch, "/"+dnsRule.UUID, `{"index": 2}`)
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "DNS", moved.Type)
+	require.Equal(t, dnsRule.UUID, moved.UUID)
+	require.Equal(t, 2, router.lastIndex)
+
+	status, _ = doRuleRequest(t, handler, http.MethodPut, "/"+updated.UUID, ``)
+	require.Equal(t, http.StatusNoContent, status)
+	require.True(t, router.rules[updated.UUID].Disabled())
+
+	status, _ = doRuleRequest(t, handler, http.MethodDelete, "/"+updated.UUID, ``)
+	require.Equal(t, http.StatusNoContent, status)
+	require.Empty(t, router.rules)
+	status, _ = doRuleRequest(t, handler, http.MethodDelete, "/"+dnsRule.UUID, ``)
+	require.Equal(t, http.StatusNoContent, status)
+	require.Empty(t, router.dnsRules)
+	status, _ = doRuleRequest(t, handler, http.MethodDelete, "/"+dnsRule.UUID, ``)
+	require.Equal(t, http.StatusNotFound, status)
+}
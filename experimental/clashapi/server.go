@@ -27,7 +27,7 @@ import (
 	N "github.com/sagernet/sing/common/network"
 	"github.com/sagernet/sing/service"
 	"github.com/sagernet/sing/service/filemanager"
-    "github.com/sagernet/websocket"
+	"github.com/sagernet/websocket"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/cors"
@@ -44,12 +44,14 @@ type Server struct {
 	ctx            context.Context
 	router         adapter.Router
 	logger         log.Logger
+	logFactory     log.ObservableFactory
 	httpServer     *http.Server
 	trafficManager *trafficontrol.Manager
 	urlTestHistory *urltest.HistoryStorage
 	mode           string
 	modeList       []string
 	modeUpdateHook chan<- struct{}
+	savedConfig    adapter.SavedClashConfig
 	tlsConfig      tls.ServerConfig
 
 	externalController       bool
@@ -62,9 +64,10 @@ func NewServer(ctx context.Context, router adapter.Router, logFactory log.Observ
 	trafficManager := trafficontrol.NewManager()
 	chiRouter := chi.NewRouter()
 	server := &Server{
-		ctx:    ctx,
-		router: router,
-		logger: logFactory.NewLogger("clash-api"),
+		ctx:        ctx,
+		router:     router,
+		logger:     logFactory.NewLogger("clash-api"),
+		logFactory: logFactory,
 		httpServer: &http.Server{
 			Addr:    options.ExternalController,
 			Handler: chiRouter,
@@ -124,7 +127,8 @@ func NewServer(ctx context.Context, router adapter.Router, logFactory log.Observ
 		r.Mount("/providers/proxies", proxyProviderRouter(server, router))
 		r.Mount("/providers/rules", ruleProviderRouter(router))
 		r.Mount("/script", scriptRouter())
-		r.Mount("/profile", profileRouter())
+		r.Mount("/route", routeRouter(router))
+		r.Mount("/profile", profileRouter(router))
 		r.Mount("/cache", cacheRouter(ctx))
 		r.Mount("/dns", dnsRouter(router))
 
@@ -159,6 +163,19 @@ func (s *Server) PreStart() error {
 		}) {
 			s.mode = mode
 		}
+		savedConfig := cacheFile.LoadClashConfig()
+		if savedConfig != nil {
+			s.savedConfig = *savedConfig
+			if savedConfig.LogLevel != "" {
+				logLevel, err := log.ParseLevel(savedConfig.LogLevel)
+				if err == nil {
+					s.logFactory.SetLevel(logLevel)
+				}
+			}
+			if savedConfig.IPv6 != nil {
+				s.router.SetIPv6Enabled(*savedConfig.IPv6)
+			}
+		}
 	}
 	return nil
 }
@@ -248,6 +265,36 @@ func (s *Server) SetMode(newMode string) {
 	s.logger.Info("updated mode: ", newMode)
 }
 
+func (s *Server) SetLogLevel(level log.Level) {
+	if level == s.logFactory.Level() {
+		return
+	}
+	s.logFactory.SetLevel(level)
+	s.savedConfig.LogLevel = log.FormatLevel(level)
+	s.storeConfig()
+	s.logger.Info("updated log level: ", s.savedConfig.LogLevel)
+}
+
+func (s *Server) SetIPv6(enabled bool) {
+	if enabled == s.router.IPv6Enabled() {
+		return
+	}
+	s.router.SetIPv6Enabled(enabled)
+	s.savedConfig.IPv6 = &enabled
+	s.storeConfig()
+	s.logger.Info("updated ipv6: ", enabled)
+}
+
+func (s *Server) storeConfig() {
+	cacheFile := service.FromContext[adapter.CacheFile](s.ctx)
+	if cacheFile != nil {
+		err := cacheFile.StoreClashConfig(&s.savedConfig)
+		if err != nil {
+			s.logger.Error(E.Cause(err, "save config"))
+		}
+	}
+}
+
 func (s *Server) HistoryStorage() *urltest.HistoryStorage {
 	return s.urlTestHistory
 }
@@ -91,6 +91,11 @@ type OutboundGroupItem struct {
 	Type         string
 	URLTestTime  int64
 	URLTestDelay int32
+	// URLTestMean, URLTestJitter and URLTestFailureRate are computed from the
+	// recent tests of the outbound.
+	URLTestMean        int32
+	URLTestJitter      int32
+	URLTestFailureRate float64
 }
 
 type OutboundGroupItemIterator interface {
@@ -142,6 +147,11 @@ func writeGroups(writer io.Writer, boxService *BoxService) error {
 				item.URLTestTime = history.Time.Unix()
 				item.URLTestDelay = int32(history.Delay)
 			}
+			if stats := historyStorage.LoadURLTestStats(adapter.OutboundTag(itemOutbound), 0); stats != nil {
+				item.URLTestMean = int32(stats.Mean)
+				item.URLTestJitter = int32(stats.Jitter)
+				item.URLTestFailureRate = stats.FailureRate
+			}
 			group.ItemList = append(group.ItemList, &item)
 		}
 		groups = append(groups, group)
@@ -15,16 +15,21 @@ import (
 )
 
 type OutboundProvider struct {
-	Tag        string
-	Type       string
-	IsExpand   bool
-	ItemList   []*OutboundProviderItem
+	Tag         string
+	Type        string
+	IsExpand    bool
+	ItemList    []*OutboundProviderItem
+	SkippedList []*OutboundProviderSkippedItem
 }
 
 func (g *OutboundProvider) GetItems() OutboundProviderItemIterator {
 	return newIterator(g.ItemList)
 }
 
+func (g *OutboundProvider) GetSkippedItems() OutboundProviderSkippedItemIterator {
+	return newIterator(g.SkippedList)
+}
+
 type OutboundProviderIterator interface {
 	Next() *OutboundProvider
 	HasNext() bool
@@ -42,16 +47,28 @@ type OutboundProviderItemIterator interface {
 	HasNext() bool
 }
 
+type OutboundProviderSkippedItem struct {
+	Index  int32
+	Tag    string
+	Type   string
+	Reason string
+}
+
+type OutboundProviderSkippedItemIterator interface {
+	Next() *OutboundProviderSkippedItem
+	HasNext() bool
+}
+
 func (c *CommandClient) handleProviderConn(conn net.Conn) {
 	defer conn.Close()
 
 	for {
-		providers, err := readGroups(conn)
+		providers, err := readProviders(conn)
 		if err != nil {
 			c.handler.Disconnected(err.Error())
 			return
 		}
-		c.handler.WriteGroups(providers)
+		c.handler.WriteProviders(providers)
 	}
 }
 
@@ -134,6 +151,14 @@ func writeProviders(writer io.Writer, boxService *BoxService) error {
 			}
 			provider.ItemList = append(provider.ItemList, &item)
 		}
+		for _, skipped := range iProvider.SkippedOutbounds() {
+			provider.SkippedList = append(provider.SkippedList, &OutboundProviderSkippedItem{
+				Index:  int32(skipped.Index),
+				Tag:    skipped.Tag,
+				Type:   skipped.Type,
+				Reason: skipped.Reason,
+			})
+		}
 		providers = append(providers, provider)
 	}
 	return varbin.Write(writer, binary.BigEndian, providers)
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"sync"
 
+	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/urltest"
 	"github.com/sagernet/sing-box/experimental/clashapi"
 	"github.com/sagernet/sing-box/log"
@@ -61,11 +62,27 @@ func (s *CommandServer) SetService(newService *BoxService) {
 	if newService != nil {
 		service.PtrFromContext[urltest.HistoryStorage](newService.ctx).SetHook(s.urlTestUpdate)
 		newService.instance.Router().ClashServer().(*clashapi.Server).SetModeUpdateHook(s.modeUpdate)
+		service.MustRegister[adapter.ConfigReloader](newService.ctx, s)
 	}
 	s.service = newService
 	s.notifyURLTestUpdate()
 }
 
+// ReloadConfig implements adapter.ConfigReloader, the configuration is owned
+// by the platform so only reloading from it is supported.
+func (s *CommandServer) ReloadConfig(content []byte) error {
+	if len(content) > 0 {
+		return E.New("reload with configuration content is not supported on this platform")
+	}
+	go func() {
+		err := s.handler.ServiceReload()
+		if err != nil {
+			log.Error(E.Cause(err, "reload service"))
+		}
+	}()
+	return nil
+}
+
 func (s *CommandServer) notifyURLTestUpdate() {
 	select {
 	case s.urlTestUpdate <- struct{}{}:
@@ -8,12 +8,15 @@ type ExperimentalOptions struct {
 }
 
 type CacheFileOptions struct {
-	Enabled     bool     `json:"enabled,omitempty"`
-	Path        string   `json:"path,omitempty"`
-	CacheID     string   `json:"cache_id,omitempty"`
-	StoreFakeIP bool     `json:"store_fakeip,omitempty"`
-	StoreRDRC   bool     `json:"store_rdrc,omitempty"`
-	RDRCTimeout Duration `json:"rdrc_timeout,omitempty"`
+	Enabled          bool     `json:"enabled,omitempty"`
+	Path             string   `json:"path,omitempty"`
+	CacheID          string   `json:"cache_id,omitempty"`
+	StoreFakeIP      bool     `json:"store_fakeip,omitempty"`
+	StoreRDRC        bool     `json:"store_rdrc,omitempty"`
+	RDRCTimeout      Duration `json:"rdrc_timeout,omitempty"`
+	StoreHistory     bool     `json:"store_history,omitempty"`
+	HistoryTimeout   Duration `json:"history_timeout,omitempty"`
+	StoreClashConfig bool     `json:"store_clash_config,omitempty"`
 }
 
 type ClashAPIOptions struct {
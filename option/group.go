@@ -8,9 +8,10 @@ type FilterOptions struct {
 }
 
 type GroupOutboundOptions struct {
-	Outbounds       Listable[string] `json:"outbounds,omitempty"`
-	Providers       Listable[string] `json:"providers,omitempty"`
-	UseAllProviders bool             `json:"use_all_providers,omitempty"`
+	Outbounds                Listable[string] `json:"outbounds,omitempty"`
+	Providers                Listable[string] `json:"providers,omitempty"`
+	UseAllProviders          bool             `json:"use_all_providers,omitempty"`
+	PreferAvailableProviders bool             `json:"prefer_available_providers,omitempty"`
 	FilterOptions
 }
 
@@ -23,9 +24,34 @@ type SelectorOutboundOptions struct {
 
 type URLTestOutboundOptions struct {
 	GroupOutboundOptions
-	URL                       string   `json:"url,omitempty"`
-	Interval                  Duration `json:"interval,omitempty"`
-	Tolerance                 uint16   `json:"tolerance,omitempty"`
-	IdleTimeout               Duration `json:"idle_timeout,omitempty"`
-	InterruptExistConnections bool     `json:"interrupt_exist_connections,omitempty"`
+	URL                       string          `json:"url,omitempty"`
+	Interval                  Duration        `json:"interval,omitempty"`
+	Tolerance                 uint16          `json:"tolerance,omitempty"`
+	IdleTimeout               Duration        `json:"idle_timeout,omitempty"`
+	InterruptExistConnections bool            `json:"interrupt_exist_connections,omitempty"`
+	UDPTest                   *UDPTestOptions `json:"udp_test,omitempty"`
+	DelaySamples              uint8           `json:"delay_samples,omitempty"`
+}
+
+type UDPTestOptions struct {
+	Type   string `json:"type,omitempty"`
+	Server string `json:"server,omitempty"`
+}
+
+type LoadBalanceOutboundOptions struct {
+	GroupOutboundOptions
+	Strategy      string `json:"strategy,omitempty"`
+	UseUDPHistory bool   `json:"use_udp_history,omitempty"`
+}
+
+type FallbackOutboundOptions struct {
+	GroupOutboundOptions
+	URL         string          `json:"url,omitempty"`
+	Interval    Duration        `json:"interval,omitempty"`
+	IdleTimeout Duration        `json:"idle_timeout,omitempty"`
+	UDPTest     *UDPTestOptions `json:"udp_test,omitempty"`
+}
+
+type ChainOutboundOptions struct {
+	GroupOutboundOptions
 }
@@ -28,6 +28,9 @@ type _Outbound struct {
 	Hysteria2Options    Hysteria2OutboundOptions    `json:"-"`
 	SelectorOptions     SelectorOutboundOptions     `json:"-"`
 	URLTestOptions      URLTestOutboundOptions      `json:"-"`
+	LoadBalanceOptions  LoadBalanceOutboundOptions  `json:"-"`
+	FallbackOptions     FallbackOutboundOptions     `json:"-"`
+	ChainOptions        ChainOutboundOptions        `json:"-"`
 }
 
 type Outbound _Outbound
@@ -71,6 +74,12 @@ func (h *Outbound) RawOptions() (any, error) {
 		rawOptionsPtr = &h.SelectorOptions
 	case C.TypeURLTest:
 		rawOptionsPtr = &h.URLTestOptions
+	case C.TypeLoadBalance:
+		rawOptionsPtr = &h.LoadBalanceOptions
+	case C.TypeFallback:
+		rawOptionsPtr = &h.FallbackOptions
+	case C.TypeChain:
+		rawOptionsPtr = &h.ChainOptions
 	case "":
 		return nil, E.New("missing outbound type")
 	default:
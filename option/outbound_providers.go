@@ -10,7 +10,10 @@ type _OutboundProvider struct {
 	Type             string                   `json:"type"`
 	Path             string                   `json:"path"`
 	Tag              string                   `json:"tag,omitempty"`
+	Format           string                   `json:"format,omitempty"`
 	OutboundOverride *OutboundOverrideOptions `json:"outbound_override,omitempty"`
+	OutboundPatches  []OutboundPatchOptions   `json:"outbound_patches,omitempty"`
+	Subscription     *SubscriptionOptions     `json:"subscription,omitempty"`
 	LocalOptions     LocalProviderOptions     `json:"-"`
 	RemoteOptions    RemoteProviderOptions    `json:"-"`
 	FilterOptions
@@ -19,11 +22,38 @@ type _OutboundProvider struct {
 type OutboundProvider _OutboundProvider
 
 type OutboundOverrideOptions struct {
-	TagPrefix string `json:"tag_prefix,omitempty"`
-	TagSuffix string `json:"tag_suffix,omitempty"`
+	TagRename []TagRenameOptions `json:"tag_rename,omitempty"`
+	TagPrefix string             `json:"tag_prefix,omitempty"`
+	TagSuffix string             `json:"tag_suffix,omitempty"`
 	*OverrideDialerOptions
 }
 
+type SubscriptionOptions struct {
+	UsageWarning     uint8    `json:"usage_warning,omitempty"`
+	ExpireWarning    Duration `json:"expire_warning,omitempty"`
+	ExcludeExhausted bool     `json:"exclude_exhausted,omitempty"`
+}
+
+type TagRenameOptions struct {
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement,omitempty"`
+}
+
+type OutboundPatchOptions struct {
+	FilterOptions
+	TLS              *OutboundTLSPatchOptions  `json:"tls,omitempty"`
+	Multiplex        *OutboundMultiplexOptions `json:"multiplex,omitempty"`
+	UDPOverTCP       *UDPOverTCPOptions        `json:"udp_over_tcp,omitempty"`
+	TransportHeaders HTTPHeader                `json:"transport_headers,omitempty"`
+}
+
+type OutboundTLSPatchOptions struct {
+	ServerName      *string           `json:"server_name,omitempty"`
+	Insecure        *bool             `json:"insecure,omitempty"`
+	ALPN            *Listable[string] `json:"alpn,omitempty"`
+	UTLSFingerprint *string           `json:"utls_fingerprint,omitempty"`
+}
+
 type OverrideDialerOptions struct {
 	Detour           *string         `json:"detour,omitempty"`
 	BindInterface    *string         `json:"bind_interface,omitempty"`
@@ -46,13 +76,24 @@ type LocalProviderOptions struct {
 }
 
 type RemoteProviderOptions struct {
-	Url       string   `json:"download_url"`
-	UserAgent string   `json:"download_ua,omitempty"`
-	Interval  Duration `json:"download_interval,omitempty"`
-	Detour    string   `json:"download_detour,omitempty"`
+	Url       string                 `json:"download_url"`
+	UserAgent string                 `json:"download_ua,omitempty"`
+	Interval  Duration               `json:"download_interval,omitempty"`
+	Detour    string                 `json:"download_detour,omitempty"`
+	Mirrors   []RemoteProviderMirror `json:"download_mirrors,omitempty"`
+	Race      bool                   `json:"download_race,omitempty"`
+	Headers   HTTPHeader             `json:"download_headers,omitempty"`
+	TLS       *OutboundTLSOptions    `json:"download_tls,omitempty"`
+	Timeout   Duration               `json:"download_timeout,omitempty"`
+	MaxSize   MemoryBytes            `json:"download_max_size,omitempty"`
 	HealthcheckOptions
 }
 
+type RemoteProviderMirror struct {
+	URL    string `json:"url"`
+	Detour string `json:"detour,omitempty"`
+}
+
 func (h OutboundProvider) MarshalJSON() ([]byte, error) {
 	var v any
 	switch h.Type {
@@ -88,7 +129,11 @@ func (h *OutboundProvider) UnmarshalJSON(bytes []byte) error {
 }
 
 type HealthcheckOptions struct {
-	EnableHealthcheck   bool     `json:"enable_healthcheck,omitempty"`
-	HealthcheckUrl      string   `json:"healthcheck_url,omitempty"`
-	HealthcheckInterval Duration `json:"healthcheck_interval,omitempty"`
+	EnableHealthcheck         bool             `json:"enable_healthcheck,omitempty"`
+	HealthcheckUrl            Listable[string] `json:"healthcheck_url,omitempty"`
+	HealthcheckInterval       Duration         `json:"healthcheck_interval,omitempty"`
+	HealthcheckTimeout        Duration         `json:"healthcheck_timeout,omitempty"`
+	HealthcheckExpectedStatus Listable[string] `json:"healthcheck_expected_status,omitempty"`
+	HealthcheckExpectedBody   string           `json:"healthcheck_expected_body,omitempty"`
+	HealthcheckUDP            *UDPTestOptions  `json:"healthcheck_udp,omitempty"`
 }
@@ -14,6 +14,15 @@ type RouteOptions struct {
 	DefaultMark          uint32          `json:"default_mark,omitempty"`
 	ConcurrentDial       bool            `json:"concurrent_dial,omitempty"`
 	KeepAliveInterval    Duration        `json:"keep_alive_interval,omitempty"`
+	RulesOverlay         string          `json:"rules_overlay,omitempty"`
+}
+
+// RulesOverlay stores route and DNS rules edited through the Clash API.
+// BaseHash is the hash of the configuration rules the edits were made on.
+type RulesOverlay struct {
+	Rules    []Rule    `json:"rules"`
+	DNSRules []DNSRule `json:"dns_rules"`
+	BaseHash string    `json:"base_hash,omitempty"`
 }
 
 type GeoIPOptions struct {
@@ -11,6 +11,17 @@ import (
 )
 
 func New(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.Outbound) (adapter.Outbound, error) {
+	outbound, err := newOutbound(ctx, router, logger, tag, options)
+	if err != nil {
+		return nil, err
+	}
+	if optionsSetter, isSetter := outbound.(interface{ setOptions(options option.Outbound) }); isSetter {
+		optionsSetter.setOptions(options)
+	}
+	return outbound, nil
+}
+
+func newOutbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.Outbound) (adapter.Outbound, error) {
 	var metadata *adapter.InboundContext
 	if tag != "" {
 		ctx, metadata = adapter.AppendContext(ctx)
@@ -59,6 +70,12 @@ func New(ctx context.Context, router adapter.Router, logger log.ContextLogger, t
 		return NewSelector(ctx, router, logger, tag, options.SelectorOptions)
 	case C.TypeURLTest:
 		return NewURLTest(ctx, router, logger, tag, options.URLTestOptions)
+	case C.TypeLoadBalance:
+		return NewLoadBalance(ctx, router, logger, tag, options.LoadBalanceOptions)
+	case C.TypeFallback:
+		return NewFallback(ctx, router, logger, tag, options.FallbackOptions)
+	case C.TypeChain:
+		return NewChain(ctx, router, logger, tag, options.ChainOptions)
 	default:
 		return nil, E.New("unknown outbound type: ", options.Type)
 	}
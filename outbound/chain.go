@@ -0,0 +1,364 @@
+package outbound
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	R "github.com/dlclark/regexp2"
+)
+
+var (
+	_ adapter.Outbound      = (*Chain)(nil)
+	_ adapter.OutboundGroup = (*Chain)(nil)
+)
+
+// Chain dials through its members in order, each member after the first one
+// is rebuilt with its detour pointing to the previous member.
+type Chain struct {
+	myOutboundAdapter
+	myGroupAdapter
+	members []chainMember
+	access  sync.Mutex
+	hops    []adapter.Outbound
+	chained []adapter.Outbound
+}
+
+// chainMember is a hop of the chain, an outbound in outbounds or the first
+// outbound of a provider in providers matching the filter fields. outbound is
+// nil if nothing of the provider matches.
+type chainMember struct {
+	outbound adapter.Outbound
+	provider string
+}
+
+func NewChain(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.ChainOutboundOptions) (*Chain, error) {
+	outbound := &Chain{
+		myOutboundAdapter: myOutboundAdapter{
+			protocol:     C.TypeChain,
+			network:      []string{N.NetworkTCP, N.NetworkUDP},
+			router:       router,
+			logger:       logger,
+			tag:          tag,
+			dependencies: options.Outbounds,
+		},
+		myGroupAdapter: myGroupAdapter{
+			ctx:             ctx,
+			tags:            options.Outbounds,
+			uses:            options.Providers,
+			useAllProviders: options.UseAllProviders,
+			types:           options.Types,
+			ports:           make(map[int]bool),
+			providers:       make(map[string]adapter.OutboundProvider),
+		},
+	}
+	if len(outbound.tags) == 0 && len(outbound.uses) == 0 && !outbound.useAllProviders {
+		return nil, E.New("missing tags and uses")
+	}
+	if len(options.Includes) > 0 {
+		includes := make([]*R.Regexp, 0, len(options.Includes))
+		for i, include := range options.Includes {
+			regex, err := R.Compile(include, R.IgnoreCase)
+			if err != nil {
+				return nil, E.Cause(err, "parse includes[", i, "]")
+			}
+			includes = append(includes, regex)
+		}
+		outbound.includes = includes
+	}
+	if options.Excludes != "" {
+		regex, err := R.Compile(options.Excludes, R.IgnoreCase)
+		if err != nil {
+			return nil, E.Cause(err, "parse excludes")
+		}
+		outbound.excludes = regex
+	}
+	if !CheckType(outbound.types) {
+		return nil, E.New("invalid types")
+	}
+	if portMap, err := CreatePortsMap(options.Ports); err == nil {
+		outbound.ports = portMap
+	} else {
+		return nil, err
+	}
+	return outbound, nil
+}
+
+func (s *Chain) Start() error {
+	if s.useAllProviders {
+		uses := []string{}
+		for _, provider := range s.router.OutboundProviders() {
+			uses = append(uses, provider.Tag())
+		}
+		s.uses = uses
+	}
+	members, err := s.pickMembers()
+	if err != nil {
+		return err
+	}
+	if len(members) == 0 {
+		return E.New("missing outbounds and providers")
+	}
+	s.members = members
+	return nil
+}
+
+// pickMembers returns a member for each outbound and each provider. Providers
+// excluded for an exhausted subscription are kept as members without an
+// outbound, so that the chain fails instead of skipping the hop.
+func (s *Chain) pickMembers() ([]chainMember, error) {
+	var members []chainMember
+	for i, tag := range s.tags {
+		detour, loaded := s.router.Outbound(tag)
+		if !loaded {
+			return nil, E.New("outbound ", i, " not found: ", tag)
+		}
+		members = append(members, chainMember{outbound: detour})
+	}
+	for i, tag := range s.uses {
+		provider, loaded := s.router.OutboundProvider(tag)
+		if !loaded {
+			return nil, E.New("outbound provider ", i, " not found: ", tag)
+		}
+		s.providers[tag] = provider
+		member := chainMember{provider: tag}
+		if !provider.SubscriptionStatus().Excluded {
+			for _, outbound := range provider.Outbounds() {
+				if s.OutboundFilter(outbound) {
+					member.outbound = outbound
+					break
+				}
+			}
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+func (s *Chain) UpdateOutbounds(tag string, delta adapter.OutboundProviderDelta) error {
+	if _, ok := s.providers[tag]; ok && delta.Changed() {
+		members, err := s.pickMembers()
+		if err != nil {
+			return E.New("update outbounds failed: ", s.tag, ", with reason: ", err)
+		}
+		s.members = members
+	}
+	return nil
+}
+
+func (s *Chain) Close() error {
+	s.access.Lock()
+	defer s.access.Unlock()
+	err := closeChained(s.chained)
+	s.hops = nil
+	s.chained = nil
+	return err
+}
+
+// memberOutbound returns the outbound of member, or OUTBOUNDLESS for a provider
+// without a matching outbound, to be reported by the group.
+func (s *Chain) memberOutbound(member chainMember) adapter.Outbound {
+	if member.outbound != nil {
+		return member.outbound
+	}
+	OUTBOUNDLESS, _ := s.router.Outbound("OUTBOUNDLESS")
+	return OUTBOUNDLESS
+}
+
+func (s *Chain) Now() string {
+	return s.memberOutbound(s.members[len(s.members)-1]).Tag()
+}
+
+func (s *Chain) All() []string {
+	var all []string
+	for _, member := range s.members {
+		all = append(all, s.memberOutbound(member).Tag())
+	}
+	return all
+}
+
+func (s *Chain) SelectedOutbound(network string) adapter.Outbound {
+	return s.memberOutbound(s.members[len(s.members)-1])
+}
+
+// resolveHops replaces group members with the outbound they currently select,
+// it fails if a provider has no outbound for its hop.
+func (s *Chain) resolveHops(network string) ([]adapter.Outbound, error) {
+	hops := make([]adapter.Outbound, 0, len(s.members))
+	for _, member := range s.members {
+		if member.outbound == nil {
+			return nil, E.New("no outbound of provider ", member.provider, " available for chain ", s.tag)
+		}
+		realTag := RealOutboundTag(member.outbound, network)
+		hop, loaded := s.router.OutboundWithProvider(realTag)
+		if !loaded {
+			return nil, E.New("outbound not found: ", realTag)
+		}
+		if network == N.NetworkUDP && !common.Contains(hop.Network(), N.NetworkUDP) {
+			return nil, E.New("outbound ", hop.Tag(), " in chain ", s.tag, " does not support UDP")
+		}
+		hops = append(hops, hop)
+	}
+	return hops, nil
+}
+
+// dialer returns the last outbound of the chain built from the current hops,
+// the chain is rebuilt only if any hop changes.
+func (s *Chain) dialer(network string) (adapter.Outbound, error) {
+	hops, err := s.resolveHops(network)
+	if err != nil {
+		return nil, err
+	}
+	s.access.Lock()
+	defer s.access.Unlock()
+	if sameOutbounds(hops, s.hops) {
+		return s.chained[len(s.chained)-1], nil
+	}
+	chained, err := s.buildChain(hops)
+	if err != nil {
+		return nil, err
+	}
+	if err = closeChained(s.chained); err != nil {
+		s.logger.Warn("close previous chain: ", err)
+	}
+	s.hops = hops
+	s.chained = chained
+	return chained[len(chained)-1], nil
+}
+
+func (s *Chain) buildChain(hops []adapter.Outbound) ([]adapter.Outbound, error) {
+	router := &chainRouter{
+		Router:    s.router,
+		outbounds: make(map[string]adapter.Outbound),
+	}
+	chained := []adapter.Outbound{hops[0]}
+	for i, hop := range hops[1:] {
+		previousTag := F.ToString("[", s.tag, "]", i)
+		router.outbounds[previousTag] = chained[i]
+		options, err := chainHopOptions(hop, previousTag)
+		if err != nil {
+			closeChained(chained)
+			return nil, err
+		}
+		outbound, err := New(s.ctx, router, s.logger, hop.Tag(), options)
+		if err == nil {
+			err = startChained(outbound)
+		}
+		if err != nil {
+			closeChained(append(chained, outbound))
+			return nil, E.Cause(err, "create chain ", s.tag, " hop ", i+1, ": ", hop.Tag())
+		}
+		chained = append(chained, outbound)
+	}
+	return chained, nil
+}
+
+func sameOutbounds(a []adapter.Outbound, b []adapter.Outbound) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// chainHopOptions returns options of the outbound with its detour replaced.
+func chainHopOptions(detour adapter.Outbound, previousTag string) (option.Outbound, error) {
+	withOptions, isWithOptions := detour.(adapter.OutboundWithOptions)
+	if !isWithOptions {
+		return option.Outbound{}, E.New("outbound ", detour.Tag(), " can not be chained")
+	}
+	options := withOptions.Options()
+	rawOptions, err := options.RawOptions()
+	if err != nil {
+		return option.Outbound{}, err
+	}
+	dialerOptions, isDialer := rawOptions.(option.DialerOptionsWrapper)
+	if !isDialer {
+		return option.Outbound{}, E.New("outbound ", detour.Tag(), " of type ", detour.Type(), " can not be chained")
+	}
+	replaced := dialerOptions.TakeDialerOptions()
+	replaced.Detour = previousTag
+	dialerOptions.ReplaceDialerOptions(replaced)
+	return options, nil
+}
+
+func startChained(outbound adapter.Outbound) error {
+	if starter, isStarter := outbound.(common.Starter); isStarter {
+		if err := starter.Start(); err != nil {
+			return err
+		}
+	}
+	if starter, isStarter := outbound.(adapter.PostStarter); isStarter {
+		return starter.PostStart()
+	}
+	return nil
+}
+
+// closeChained closes outbounds created by the chain, the first hop is the
+// original outbound and is left open.
+func closeChained(chained []adapter.Outbound) error {
+	if len(chained) < 2 {
+		return nil
+	}
+	return common.Close(common.Map(chained[1:], func(it adapter.Outbound) any {
+		return it
+	})...)
+}
+
+func (s *Chain) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	detour, err := s.dialer(N.NetworkName(network))
+	if err != nil {
+		return nil, err
+	}
+	return detour.DialContext(ctx, network, destination)
+}
+
+func (s *Chain) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	detour, err := s.dialer(N.NetworkUDP)
+	if err != nil {
+		return nil, err
+	}
+	return detour.ListenPacket(ctx, destination)
+}
+
+func (s *Chain) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	return NewConnection(ctx, s, conn, metadata)
+}
+
+func (s *Chain) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
+	return NewPacketConnection(ctx, s, conn, metadata)
+}
+
+// chainRouter resolves detours of chained outbounds to the previous hop.
+type chainRouter struct {
+	adapter.Router
+	outbounds map[string]adapter.Outbound
+}
+
+func (r *chainRouter) Outbound(tag string) (adapter.Outbound, bool) {
+	if outbound, loaded := r.outbounds[tag]; loaded {
+		return outbound, true
+	}
+	return r.Router.Outbound(tag)
+}
+
+func (r *chainRouter) OutboundWithProvider(tag string) (adapter.Outbound, bool) {
+	if outbound, loaded := r.outbounds[tag]; loaded {
+		return outbound, true
+	}
+	return r.Router.OutboundWithProvider(tag)
+}
@@ -0,0 +1,123 @@
+package outbound
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChainHopOptions(t *testing.T) {
+	t.Parallel()
+	logger := log.NewNOPFactory().Logger()
+	socks, err := New(context.Background(), nil, logger, "socks", option.Outbound{
+		Type: C.TypeSOCKS,
+		SocksOptions: option.SocksOutboundOptions{
+			DialerOptions: option.DialerOptions{Detour: "direct"},
+			ServerOptions: option.ServerOptions{Server: "127.0.0.1", ServerPort: 1080},
+		},
+	})
+	require.NoError(t, err)
+	options, err := chainHopOptions(socks, "[chain]0")
+	require.NoError(t, err)
+	require.Equal(t, "[chain]0", options.SocksOptions.Detour)
+	require.Equal(t, "127.0.0.1", options.SocksOptions.Server)
+	require.Equal(t, "direct", socks.(*Socks).Options().SocksOptions.Detour)
+
+	block, err := New(context.Background(), nil, logger, "block", option.Outbound{Type: C.TypeBlock})
+	require.NoError(t, err)
+	_, err = chainHopOptions(block, "[chain]0")
+	require.ErrorContains(t, err, "can not be chained")
+}
+
+type testChainRouter struct {
+	adapter.Router
+	outbounds map[string]adapter.Outbound
+	providers map[string]adapter.OutboundProvider
+}
+
+func (r *testChainRouter) Outbound(tag string) (adapter.Outbound, bool) {
+	outbound, loaded := r.outbounds[tag]
+	return outbound, loaded
+}
+
+func (r *testChainRouter) OutboundWithProvider(tag string) (adapter.Outbound, bool) {
+	if outbound, loaded := r.outbounds[tag]; loaded {
+		return outbound, true
+	}
+	for _, provider := range r.providers {
+		for _, outbound := range provider.Outbounds() {
+			if outbound.Tag() == tag {
+				return outbound, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (r *testChainRouter) OutboundProvider(tag string) (adapter.OutboundProvider, bool) {
+	provider, loaded := r.providers[tag]
+	return provider, loaded
+}
+
+type testChainProvider struct {
+	adapter.OutboundProvider
+	outbounds []adapter.Outbound
+	status    adapter.SubscriptionStatus
+}
+
+func (p *testChainProvider) Outbounds() []adapter.Outbound {
+	return p.outbounds
+}
+
+func (p *testChainProvider) SubscriptionStatus() adapter.SubscriptionStatus {
+	return p.status
+}
+
+func TestChainProviderHop(t *testing.T) {
+	t.Parallel()
+	logger := log.NewNOPFactory().Logger()
+	provider := &testChainProvider{outbounds: []adapter.Outbound{
+		NewBlock(logger, "hk"),
+		NewBlock(logger, "jp-1"),
+		NewBlock(logger, "jp-2"),
+	}}
+	router := &testChainRouter{
+		outbounds: map[string]adapter.Outbound{
+			"first":        NewBlock(logger, "first"),
+			"OUTBOUNDLESS": NewBlock(logger, "OUTBOUNDLESS"),
+		},
+		providers: map[string]adapter.OutboundProvider{"sub": provider},
+	}
+	chain, err := NewChain(context.Background(), router, logger, "chain", option.ChainOutboundOptions{
+		GroupOutboundOptions: option.GroupOutboundOptions{
+			Outbounds:     []string{"first"},
+			Providers:     []string{"sub"},
+			FilterOptions: option.FilterOptions{Includes: []string{"^jp"}},
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, chain.Start())
+	require.Equal(t, []string{"first", "jp-1"}, chain.All())
+	hops, err := chain.resolveHops(N.NetworkTCP)
+	require.NoError(t, err)
+	require.Len(t, hops, 2)
+
+	provider.outbounds = provider.outbounds[:1]
+	require.NoError(t, chain.UpdateOutbounds("sub", adapter.OutboundProviderDelta{Reordered: true}))
+	require.Equal(t, []string{"first", "OUTBOUNDLESS"}, chain.All())
+	_, err = chain.resolveHops(N.NetworkTCP)
+	require.ErrorContains(t, err, "no outbound of provider sub")
+
+	provider.outbounds = []adapter.Outbound{NewBlock(logger, "jp-3")}
+	provider.status.Excluded = true
+	require.NoError(t, chain.UpdateOutbounds("sub", adapter.OutboundProviderDelta{Reordered: true}))
+	_, err = chain.resolveHops(N.NetworkTCP)
+	require.ErrorContains(t, err, "no outbound of provider sub")
+}
@@ -29,6 +29,7 @@ type myOutboundAdapter struct {
 	tag          string
 	port         uint16
 	dependencies []string
+	options      option.Outbound
 }
 
 func (a *myOutboundAdapter) Type() string {
@@ -41,7 +42,7 @@ func (a *myOutboundAdapter) Tag() string {
 
 func (a *myOutboundAdapter) Port() int {
 	switch a.protocol {
-	case C.TypeDirect, C.TypeBlock, C.TypeDNS, C.TypeTor, C.TypeSelector, C.TypeURLTest:
+	case C.TypeDirect, C.TypeBlock, C.TypeDNS, C.TypeTor, C.TypeSelector, C.TypeURLTest, C.TypeLoadBalance, C.TypeFallback, C.TypeChain:
 		return 65536
 	default:
 		return int(a.port)
@@ -52,6 +53,16 @@ func (a *myOutboundAdapter) SetTag(tag string) {
 	a.tag = tag
 }
 
+func (a *myOutboundAdapter) Options() option.Outbound {
+	options := a.options
+	options.Tag = a.tag
+	return options
+}
+
+func (a *myOutboundAdapter) setOptions(options option.Outbound) {
+	a.options = options
+}
+
 func (a *myOutboundAdapter) Network() []string {
 	return a.network
 }
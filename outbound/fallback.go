@@ -0,0 +1,350 @@
+package outbound
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/urltest"
+	"github.com/sagernet/sing-box/common/urltest/udptest"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	R "github.com/dlclark/regexp2"
+)
+
+var (
+	_ adapter.Outbound                = (*Fallback)(nil)
+	_ adapter.OutboundGroup           = (*Fallback)(nil)
+	_ adapter.URLTestGroup            = (*Fallback)(nil)
+	_ adapter.InterfaceUpdateListener = (*Fallback)(nil)
+)
+
+type Fallback struct {
+	myOutboundAdapter
+	myGroupAdapter
+	link        string
+	interval    time.Duration
+	idleTimeout time.Duration
+	udpTest     *udptest.Options
+	group       *URLTestGroup
+	access      sync.Mutex
+	down        map[string]time.Time
+}
+
+func NewFallback(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.FallbackOutboundOptions) (*Fallback, error) {
+	outbound := &Fallback{
+		myOutboundAdapter: myOutboundAdapter{
+			protocol:     C.TypeFallback,
+			network:      []string{N.NetworkTCP, N.NetworkUDP},
+			router:       router,
+			logger:       logger,
+			tag:          tag,
+			dependencies: options.Outbounds,
+		},
+		myGroupAdapter: myGroupAdapter{
+			ctx:             ctx,
+			tags:            options.Outbounds,
+			uses:            options.Providers,
+			useAllProviders: options.UseAllProviders,
+			preferAvailable: options.PreferAvailableProviders,
+			types:           options.Types,
+			ports:           make(map[int]bool),
+			providers:       make(map[string]adapter.OutboundProvider),
+		},
+		link:        options.URL,
+		interval:    time.Duration(options.Interval),
+		idleTimeout: time.Duration(options.IdleTimeout),
+		down:        make(map[string]time.Time),
+	}
+	if len(outbound.tags) == 0 && len(outbound.uses) == 0 && !outbound.useAllProviders {
+		return nil, E.New("missing tags and uses")
+	}
+	if options.UDPTest != nil {
+		udpTest, err := udptest.NewOptions(options.UDPTest.Type, options.UDPTest.Server)
+		if err != nil {
+			return nil, E.Cause(err, "parse udp_test")
+		}
+		outbound.udpTest = udpTest
+	}
+	if len(options.Includes) > 0 {
+		includes := make([]*R.Regexp, 0, len(options.Includes))
+		for i, include := range options.Includes {
+			regex, err := R.Compile(include, R.IgnoreCase)
+			if err != nil {
+				return nil, E.Cause(err, "parse includes[", i, "]")
+			}
+			includes = append(includes, regex)
+		}
+		outbound.includes = includes
+	}
+	if options.Excludes != "" {
+		regex, err := R.Compile(options.Excludes, R.IgnoreCase)
+		if err != nil {
+			return nil, E.Cause(err, "parse excludes")
+		}
+		outbound.excludes = regex
+	}
+	if !CheckType(outbound.types) {
+		return nil, E.New("invalid types")
+	}
+	if portMap, err := CreatePortsMap(options.Ports); err == nil {
+		outbound.ports = portMap
+	} else {
+		return nil, err
+	}
+	return outbound, nil
+}
+
+func (s *Fallback) pickOutbounds() ([]adapter.Outbound, error) {
+	outbounds := []adapter.Outbound{}
+	exhausted := make(map[adapter.Outbound]bool)
+	for i, tag := range s.tags {
+		detour, loaded := s.router.Outbound(tag)
+		if !loaded {
+			return nil, E.New("outbound ", i, " not found: ", tag)
+		}
+		outbounds = append(outbounds, detour)
+	}
+	for i, tag := range s.availableUses(s.router) {
+		provider, loaded := s.router.OutboundProvider(tag)
+		if !loaded {
+			return nil, E.New("outbound provider ", i, " not found: ", tag)
+		}
+		if _, ok := s.providers[tag]; !ok {
+			s.providers[tag] = provider
+		}
+		isExhausted := s.isExhausted(provider)
+		for _, outbound := range provider.Outbounds() {
+			if !s.OutboundFilter(outbound) {
+				continue
+			}
+			if isExhausted {
+				exhausted[outbound] = true
+			}
+			outbounds = append(outbounds, outbound)
+		}
+	}
+	if len(outbounds) == 0 {
+		OUTBOUNDLESS, _ := s.router.Outbound("OUTBOUNDLESS")
+		outbounds = append(outbounds, OUTBOUNDLESS)
+	}
+	s.exhausted = exhausted
+	return outbounds, nil
+}
+
+func (s *Fallback) Start() error {
+	if s.useAllProviders {
+		uses := []string{}
+		for _, provider := range s.router.OutboundProviders() {
+			uses = append(uses, provider.Tag())
+		}
+		s.uses = uses
+	}
+	outbounds, err := s.pickOutbounds()
+	if err != nil {
+		return err
+	}
+	group, err := NewURLTestGroup(
+		s.ctx,
+		s.router,
+		s.logger,
+		outbounds,
+		s.link,
+		s.interval,
+		0,
+		s.idleTimeout,
+		false,
+		s.udpTest,
+		0,
+	)
+	if err != nil {
+		return err
+	}
+	group.exhausted = s.exhausted
+	s.group = group
+	return nil
+}
+
+func (s *Fallback) UpdateOutbounds(tag string, delta adapter.OutboundProviderDelta) error {
+	if _, ok := s.providers[tag]; ok && delta.Changed() {
+		outbounds, err := s.pickOutbounds()
+		if err != nil {
+			return E.New("update outbounds failed: ", s.tag, ", with reason: ", err)
+		}
+		s.access.Lock()
+		s.group.outbounds = outbounds
+		s.group.exhausted = s.exhausted
+		for _, removed := range delta.Removed {
+			if s.group.selectedOutboundTCP == removed {
+				s.group.selectedOutboundTCP = nil
+			}
+			if s.group.selectedOutboundUDP == removed {
+				s.group.selectedOutboundUDP = nil
+			}
+			delete(s.down, RealTag(removed))
+		}
+		s.access.Unlock()
+	}
+	return nil
+}
+
+func (s *Fallback) PostStart() error {
+	s.group.PostStart()
+	return nil
+}
+
+func (s *Fallback) Close() error {
+	return common.Close(
+		common.PtrOrNil(s.group),
+	)
+}
+
+func (s *Fallback) Now() string {
+	if outbound := s.SelectedOutbound(N.NetworkTCP); outbound != nil {
+		return outbound.Tag()
+	}
+	return ""
+}
+
+func (s *Fallback) SelectedOutbound(network string) adapter.Outbound {
+	if candidates := s.candidates(network); len(candidates) > 0 {
+		return candidates[0]
+	}
+	return s.group.outbounds[0]
+}
+
+func (s *Fallback) All() []string {
+	var all []string
+	for _, outbound := range s.group.outbounds {
+		all = append(all, outbound.Tag())
+	}
+	return all
+}
+
+func (s *Fallback) URLTest(ctx context.Context) (map[string]uint16, error) {
+	return s.group.URLTest(ctx)
+}
+
+func (s *Fallback) CheckOutbounds() {
+	s.group.CheckOutbounds(true)
+}
+
+func (s *Fallback) PerformUpdateCheck(tag string, force bool) {
+}
+
+// candidates returns members supporting network in declared order: healthy
+// members first, then untested ones. Healthy members of exhausted providers
+// are moved after untested ones if any other member is healthy. A member
+// marked down by a failed connection is skipped until a later health check
+// succeeds, and down members are only returned if no other member is left.
+// UDP members are checked by the UDP test if udp_test is set.
+func (s *Fallback) candidates(network string) []adapter.Outbound {
+	s.access.Lock()
+	defer s.access.Unlock()
+	var healthy, untested, down []adapter.Outbound
+	for _, detour := range s.group.outbounds {
+		if !common.Contains(detour.Network(), network) {
+			continue
+		}
+		realTag := RealTag(detour)
+		var history *urltest.History
+		if network == N.NetworkUDP && s.group.udpTest != nil {
+			history = s.group.history.LoadUDPTestHistory(realTag)
+		} else {
+			history = s.group.history.LoadURLTestHistory(realTag)
+		}
+		downTime, isDown := s.down[realTag]
+		if isDown && history != nil && history.Time.After(downTime) {
+			delete(s.down, realTag)
+			isDown = false
+		}
+		switch {
+		case isDown:
+			down = append(down, detour)
+		case history != nil:
+			healthy = append(healthy, detour)
+		default:
+			untested = append(untested, detour)
+		}
+	}
+	if len(healthy) == 0 && len(untested) == 0 {
+		return down
+	}
+	available := preferAvailableOutbounds(healthy, s.group.exhausted)
+	if len(available) < len(healthy) {
+		untested = append(untested, common.Filter(healthy, func(it adapter.Outbound) bool {
+			return s.group.exhausted[it]
+		})...)
+	}
+	return append(available, untested...)
+}
+
+func (s *Fallback) markDown(detour adapter.Outbound) {
+	realTag := RealTag(detour)
+	s.access.Lock()
+	s.down[realTag] = time.Now()
+	s.access.Unlock()
+	s.group.history.DeleteURLTestHistory(realTag)
+}
+
+func (s *Fallback) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	s.group.Touch()
+	var errors []error
+	for _, detour := range s.candidates(N.NetworkName(network)) {
+		conn, err := detour.DialContext(ctx, network, destination)
+		if err == nil {
+			return conn, nil
+		}
+		s.logger.ErrorContext(ctx, "outbound ", detour.Tag(), " down: ", err)
+		s.markDown(detour)
+		errors = append(errors, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if len(errors) == 0 {
+		return nil, E.New("missing supported outbound")
+	}
+	return nil, E.Errors(errors...)
+}
+
+func (s *Fallback) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	s.group.Touch()
+	var errors []error
+	for _, detour := range s.candidates(N.NetworkUDP) {
+		conn, err := detour.ListenPacket(ctx, destination)
+		if err == nil {
+			return conn, nil
+		}
+		s.logger.ErrorContext(ctx, "outbound ", detour.Tag(), " down: ", err)
+		s.markDown(detour)
+		errors = append(errors, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if len(errors) == 0 {
+		return nil, E.New("missing supported outbound")
+	}
+	return nil, E.Errors(errors...)
+}
+
+func (s *Fallback) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	return NewConnection(ctx, s, conn, metadata)
+}
+
+func (s *Fallback) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
+	return NewPacketConnection(ctx, s, conn, metadata)
+}
+
+func (s *Fallback) InterfaceUpdated() {
+	go s.group.CheckOutbounds(true)
+}
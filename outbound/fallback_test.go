@@ -0,0 +1,123 @@
+package outbound
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/urltest"
+	"github.com/sagernet/sing-box/common/urltest/udptest"
+	"github.com/sagernet/sing-box/log"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFallbackCandidates(t *testing.T) {
+	t.Parallel()
+	logger := log.NewNOPFactory().Logger()
+	var outbounds []adapter.Outbound
+	for _, tag := range []string{"a", "b", "c"} {
+		outbounds = append(outbounds, NewBlock(logger, tag))
+	}
+	history := urltest.NewHistoryStorage()
+	fallback := &Fallback{
+		myOutboundAdapter: myOutboundAdapter{logger: logger},
+		group: &URLTestGroup{
+			outbounds: outbounds,
+			history:   history,
+		},
+		down: make(map[string]time.Time),
+	}
+	require.Equal(t, "a", fallback.Now())
+
+	history.StoreURLTestHistory("b", &urltest.History{Time: time.Now(), Delay: 100})
+	require.Equal(t, "b", fallback.Now())
+	require.Equal(t, outbounds[1:2], fallback.candidates(N.NetworkTCP)[:1])
+
+	fallback.markDown(outbounds[1])
+	require.Equal(t, []adapter.Outbound{outbounds[0], outbounds[2]}, fallback.candidates(N.NetworkTCP))
+
+	_, err := fallback.DialContext(context.Background(), N.NetworkTCP, M.ParseSocksaddr("1.1.1.1:443"))
+	require.Error(t, err)
+	require.Equal(t, outbounds, fallback.candidates(N.NetworkUDP))
+
+	history.StoreURLTestHistory("a", &urltest.History{Time: time.Now().Add(time.Second), Delay: 200})
+	require.Equal(t, "a", fallback.Now())
+	require.Equal(t, outbounds[:1], fallback.candidates(N.NetworkUDP))
+}
+
+func TestFallbackPreferAvailable(t *testing.T) {
+	t.Parallel()
+	logger := log.NewNOPFactory().Logger()
+	var outbounds []adapter.Outbound
+	for _, tag := range []string{"a", "b", "c"} {
+		outbounds = append(outbounds, NewBlock(logger, tag))
+	}
+	history := urltest.NewHistoryStorage()
+	fallback := &Fallback{
+		myOutboundAdapter: myOutboundAdapter{logger: logger},
+		group: &URLTestGroup{
+			outbounds: outbounds,
+			exhausted: map[adapter.Outbound]bool{outbounds[0]: true},
+			history:   history,
+		},
+		down: make(map[string]time.Time),
+	}
+	history.StoreURLTestHistory("a", &urltest.History{Time: time.Now(), Delay: 100})
+	require.Equal(t, outbounds, fallback.candidates(N.NetworkTCP))
+
+	history.StoreURLTestHistory("c", &urltest.History{Time: time.Now(), Delay: 100})
+	require.Equal(t, []adapter.Outbound{outbounds[2], outbounds[1], outbounds[0]}, fallback.candidates(N.NetworkTCP))
+}
+
+func TestFallbackUDPTest(t *testing.T) {
+	t.Parallel()
+	logger := log.NewNOPFactory().Logger()
+	var outbounds []adapter.Outbound
+	for _, tag := range []string{"a", "b"} {
+		outbounds = append(outbounds, NewBlock(logger, tag))
+	}
+	history := urltest.NewHistoryStorage()
+	fallback := &Fallback{
+		myOutboundAdapter: myOutboundAdapter{logger: logger},
+		group: &URLTestGroup{
+			outbounds: outbounds,
+			history:   history,
+			udpTest:   &udptest.Options{},
+		},
+		down: make(map[string]time.Time),
+	}
+	history.StoreURLTestHistory("a", &urltest.History{Time: time.Now(), Delay: 100})
+	history.StoreUDPTestHistory("b", &urltest.History{Time: time.Now(), Delay: 100})
+	require.Equal(t, outbounds, fallback.candidates(N.NetworkTCP))
+	require.Equal(t, []adapter.Outbound{outbounds[1], outbounds[0]}, fallback.candidates(N.NetworkUDP))
+}
+
+func TestURLTestGroupPreferAvailable(t *testing.T) {
+	t.Parallel()
+	logger := log.NewNOPFactory().Logger()
+	var outbounds []adapter.Outbound
+	for _, tag := range []string{"a", "b"} {
+		outbounds = append(outbounds, NewBlock(logger, tag))
+	}
+	history := urltest.NewHistoryStorage()
+	group := &URLTestGroup{
+		outbounds:           outbounds,
+		exhausted:           map[adapter.Outbound]bool{outbounds[0]: true},
+		history:             history,
+		tolerance:           50,
+		selectedOutboundTCP: outbounds[0],
+	}
+	history.StoreURLTestHistory("a", &urltest.History{Time: time.Now(), Delay: 100})
+	selected, tested := group.Select(N.NetworkTCP)
+	require.True(t, tested)
+	require.Equal(t, "a", selected.Tag())
+
+	history.StoreURLTestHistory("b", &urltest.History{Time: time.Now(), Delay: 300})
+	selected, tested = group.Select(N.NetworkTCP)
+	require.True(t, tested)
+	require.Equal(t, "b", selected.Tag())
+}
@@ -17,11 +17,61 @@ type myGroupAdapter struct {
 	tags            []string
 	uses            []string
 	useAllProviders bool
+	preferAvailable bool
 	includes        []*R.Regexp
 	excludes        *R.Regexp
 	types           []string
 	ports           map[int]bool
 	providers       map[string]adapter.OutboundProvider
+	// exhausted holds outbounds of exhausted providers if
+	// prefer_available_providers is set, replaced on each pick.
+	exhausted map[adapter.Outbound]bool
+}
+
+// availableUses returns uses without providers excluded for an exhausted
+// subscription, other exhausted providers are moved to the end if
+// prefer_available_providers is set. Excluded providers are still tracked to
+// receive their updates.
+func (s *myGroupAdapter) availableUses(router adapter.Router) []string {
+	var uses, exhausted []string
+	for _, tag := range s.uses {
+		provider, loaded := router.OutboundProvider(tag)
+		if !loaded {
+			uses = append(uses, tag)
+			continue
+		}
+		status := provider.SubscriptionStatus()
+		switch {
+		case status.Excluded:
+			s.providers[tag] = provider
+		case status.Exhausted && s.preferAvailable:
+			exhausted = append(exhausted, tag)
+		default:
+			uses = append(uses, tag)
+		}
+	}
+	return append(uses, exhausted...)
+}
+
+// isExhausted reports whether outbounds of provider are picked by a delay or
+// health check only if no outbound of other providers qualifies.
+func (s *myGroupAdapter) isExhausted(provider adapter.OutboundProvider) bool {
+	return s.preferAvailable && provider.SubscriptionStatus().Exhausted
+}
+
+// preferAvailableOutbounds returns outbounds without those of exhausted
+// providers, or all of them if no other outbound is left.
+func preferAvailableOutbounds(outbounds []adapter.Outbound, exhausted map[adapter.Outbound]bool) []adapter.Outbound {
+	if len(exhausted) == 0 {
+		return outbounds
+	}
+	available := common.Filter(outbounds, func(it adapter.Outbound) bool {
+		return !exhausted[it]
+	})
+	if len(available) == 0 {
+		return outbounds
+	}
+	return available
 }
 
 func CheckType(types []string) bool {
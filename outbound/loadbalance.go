@@ -0,0 +1,335 @@
+package outbound
+
+import (
+	"context"
+	"hash/fnv"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/urltest"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/atomic"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/service"
+
+	R "github.com/dlclark/regexp2"
+	"golang.org/x/net/publicsuffix"
+)
+
+var (
+	_ adapter.Outbound      = (*LoadBalance)(nil)
+	_ adapter.OutboundGroup = (*LoadBalance)(nil)
+)
+
+type LoadBalance struct {
+	myOutboundAdapter
+	myGroupAdapter
+	strategy      string
+	useUDPHistory bool
+	outbounds     []adapter.Outbound
+	history       *urltest.HistoryStorage
+	index         atomic.Uint32
+	now           atomic.TypedValue[string]
+	access        sync.Mutex
+	down          map[string]time.Time
+}
+
+func NewLoadBalance(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.LoadBalanceOutboundOptions) (*LoadBalance, error) {
+	outbound := &LoadBalance{
+		myOutboundAdapter: myOutboundAdapter{
+			protocol:     C.TypeLoadBalance,
+			network:      []string{N.NetworkTCP, N.NetworkUDP},
+			router:       router,
+			logger:       logger,
+			tag:          tag,
+			dependencies: options.Outbounds,
+		},
+		myGroupAdapter: myGroupAdapter{
+			ctx:             ctx,
+			tags:            options.Outbounds,
+			uses:            options.Providers,
+			useAllProviders: options.UseAllProviders,
+			preferAvailable: options.PreferAvailableProviders,
+			types:           options.Types,
+			ports:           make(map[int]bool),
+			providers:       make(map[string]adapter.OutboundProvider),
+		},
+		strategy:      options.Strategy,
+		useUDPHistory: options.UseUDPHistory,
+		down:          make(map[string]time.Time),
+	}
+	if len(outbound.tags) == 0 && len(outbound.uses) == 0 && !outbound.useAllProviders {
+		return nil, E.New("missing tags and uses")
+	}
+	switch outbound.strategy {
+	case "":
+		outbound.strategy = C.LoadBalanceStrategyRoundRobin
+	case C.LoadBalanceStrategyRoundRobin, C.LoadBalanceStrategyConsistentHash, C.LoadBalanceStrategyStickySession:
+	default:
+		return nil, E.New("unknown load balance strategy: ", outbound.strategy)
+	}
+	if len(options.Includes) > 0 {
+		includes := make([]*R.Regexp, 0, len(options.Includes))
+		for i, include := range options.Includes {
+			regex, err := R.Compile(include, R.IgnoreCase)
+			if err != nil {
+				return nil, E.Cause(err, "parse includes[", i, "]")
+			}
+			includes = append(includes, regex)
+		}
+		outbound.includes = includes
+	}
+	if options.Excludes != "" {
+		regex, err := R.Compile(options.Excludes, R.IgnoreCase)
+		if err != nil {
+			return nil, E.Cause(err, "parse excludes")
+		}
+		outbound.excludes = regex
+	}
+	if !CheckType(outbound.types) {
+		return nil, E.New("invalid types")
+	}
+	if portMap, err := CreatePortsMap(options.Ports); err == nil {
+		outbound.ports = portMap
+	} else {
+		return nil, err
+	}
+	return outbound, nil
+}
+
+func (s *LoadBalance) Start() error {
+	if s.useAllProviders {
+		uses := []string{}
+		for _, provider := range s.router.OutboundProviders() {
+			uses = append(uses, provider.Tag())
+		}
+		s.uses = uses
+	}
+	if s.history = service.PtrFromContext[urltest.HistoryStorage](s.ctx); s.history != nil {
+	} else if clashServer := s.router.ClashServer(); clashServer != nil {
+		s.history = clashServer.HistoryStorage()
+	} else {
+		s.history = urltest.NewHistoryStorage()
+	}
+	outbounds, err := s.pickOutbounds()
+	if err != nil {
+		return err
+	}
+	s.outbounds = outbounds
+	return nil
+}
+
+func (s *LoadBalance) pickOutbounds() ([]adapter.Outbound, error) {
+	outbounds := []adapter.Outbound{}
+	exhausted := make(map[adapter.Outbound]bool)
+	for i, tag := range s.tags {
+		detour, loaded := s.router.Outbound(tag)
+		if !loaded {
+			return nil, E.New("outbound ", i, " not found: ", tag)
+		}
+		outbounds = append(outbounds, detour)
+	}
+	for i, tag := range s.availableUses(s.router) {
+		provider, loaded := s.router.OutboundProvider(tag)
+		if !loaded {
+			return nil, E.New("outbound provider ", i, " not found: ", tag)
+		}
+		if _, ok := s.providers[tag]; !ok {
+			s.providers[tag] = provider
+		}
+		isExhausted := s.isExhausted(provider)
+		for _, outbound := range provider.Outbounds() {
+			if !s.OutboundFilter(outbound) {
+				continue
+			}
+			if isExhausted {
+				exhausted[outbound] = true
+			}
+			outbounds = append(outbounds, outbound)
+		}
+	}
+	if len(outbounds) == 0 {
+		OUTBOUNDLESS, _ := s.router.Outbound("OUTBOUNDLESS")
+		outbounds = append(outbounds, OUTBOUNDLESS)
+	}
+	s.exhausted = exhausted
+	return outbounds, nil
+}
+
+func (s *LoadBalance) UpdateOutbounds(tag string, delta adapter.OutboundProviderDelta) error {
+	if _, ok := s.providers[tag]; ok && delta.Changed() {
+		outbounds, err := s.pickOutbounds()
+		if err != nil {
+			return E.New("update outbounds failed: ", s.tag, ", with reason: ", err)
+		}
+		s.outbounds = outbounds
+		s.access.Lock()
+		for _, removed := range delta.Removed {
+			delete(s.down, RealTag(removed))
+		}
+		s.access.Unlock()
+	}
+	return nil
+}
+
+func (s *LoadBalance) Now() string {
+	return s.now.Load()
+}
+
+func (s *LoadBalance) All() []string {
+	var all []string
+	for _, outbound := range s.outbounds {
+		all = append(all, outbound.Tag())
+	}
+	return all
+}
+
+func (s *LoadBalance) SelectedOutbound(network string) adapter.Outbound {
+	if now, loaded := s.router.OutboundWithProvider(s.now.Load()); loaded {
+		return now
+	}
+	return s.outbounds[0]
+}
+
+// available returns members supporting network with a healthy delay history,
+// members of exhausted providers are only returned if no other member is
+// healthy. A member marked down by a failed connection is skipped until a
+// later health check succeeds. UDP members are checked by the UDP test history
+// if use_udp_history is set.
+func (s *LoadBalance) available(network string) []adapter.Outbound {
+	s.access.Lock()
+	defer s.access.Unlock()
+	var outbounds []adapter.Outbound
+	for _, detour := range s.outbounds {
+		if !common.Contains(detour.Network(), network) {
+			continue
+		}
+		realTag := RealTag(detour)
+		var history *urltest.History
+		if network == N.NetworkUDP && s.useUDPHistory {
+			history = s.history.LoadUDPTestHistory(realTag)
+		} else {
+			history = s.history.LoadURLTestHistory(realTag)
+		}
+		if history == nil {
+			continue
+		}
+		if downTime, isDown := s.down[realTag]; isDown {
+			if !history.Time.After(downTime) {
+				continue
+			}
+			delete(s.down, realTag)
+		}
+		outbounds = append(outbounds, detour)
+	}
+	return preferAvailableOutbounds(outbounds, s.exhausted)
+}
+
+func (s *LoadBalance) pick(ctx context.Context, network string, destination M.Socksaddr) (adapter.Outbound, error) {
+	outbounds := s.available(network)
+	if len(outbounds) == 0 {
+		return nil, E.New("no healthy outbound available for ", network)
+	}
+	var detour adapter.Outbound
+	switch s.strategy {
+	case C.LoadBalanceStrategyConsistentHash:
+		detour = hashOutbound(outbounds, destinationHashKey(ctx, destination))
+	case C.LoadBalanceStrategyStickySession:
+		var source string
+		if metadata := adapter.ContextFrom(ctx); metadata != nil && metadata.Source.IsValid() {
+			source = metadata.Source.Addr.String()
+		}
+		detour = hashOutbound(outbounds, source)
+	default:
+		detour = outbounds[int(s.index.Add(1)-1)%len(outbounds)]
+	}
+	s.now.Store(detour.Tag())
+	return detour, nil
+}
+
+// destinationHashKey returns the registrable domain (eTLD+1) of the
+// destination, or its address if no domain is known.
+func destinationHashKey(ctx context.Context, destination M.Socksaddr) string {
+	domain := destination.Fqdn
+	if metadata := adapter.ContextFrom(ctx); metadata != nil && metadata.Domain != "" {
+		domain = metadata.Domain
+	}
+	if domain == "" {
+		return destination.Addr.String()
+	}
+	if registrable, err := publicsuffix.EffectiveTLDPlusOne(domain); err == nil {
+		return registrable
+	}
+	return domain
+}
+
+// hashOutbound picks an outbound by rendezvous hashing, so that only keys
+// mapped to a changed member move when members come and go.
+func hashOutbound(outbounds []adapter.Outbound, key string) adapter.Outbound {
+	var (
+		maxWeight uint64
+		selected  adapter.Outbound
+	)
+	for _, detour := range outbounds {
+		hash := fnv.New64a()
+		hash.Write([]byte(key))
+		hash.Write([]byte{0})
+		hash.Write([]byte(detour.Tag()))
+		if weight := hash.Sum64(); selected == nil || weight > maxWeight {
+			maxWeight = weight
+			selected = detour
+		}
+	}
+	return selected
+}
+
+// markDown keeps the group from picking detour until its next successful
+// health check, the shared delay history is left to other groups.
+func (s *LoadBalance) markDown(detour adapter.Outbound) {
+	s.access.Lock()
+	s.down[RealTag(detour)] = time.Now()
+	s.access.Unlock()
+}
+
+func (s *LoadBalance) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	detour, err := s.pick(ctx, N.NetworkName(network), destination)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := detour.DialContext(ctx, network, destination)
+	if err != nil {
+		s.logger.ErrorContext(ctx, err)
+		s.markDown(detour)
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (s *LoadBalance) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	detour, err := s.pick(ctx, N.NetworkUDP, destination)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := detour.ListenPacket(ctx, destination)
+	if err != nil {
+		s.logger.ErrorContext(ctx, err)
+		s.markDown(detour)
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (s *LoadBalance) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	return NewConnection(ctx, s, conn, metadata)
+}
+
+func (s *LoadBalance) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
+	return NewPacketConnection(ctx, s, conn, metadata)
+}
@@ -0,0 +1,125 @@
+package outbound
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/urltest"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadBalanceHash(t *testing.T) {
+	t.Parallel()
+	var outbounds []adapter.Outbound
+	for _, tag := range []string{"a", "b", "c", "d"} {
+		outbounds = append(outbounds, NewBlock(log.NewNOPFactory().Logger(), tag))
+	}
+	ctx := context.Background()
+	require.Equal(t, "example.com", destinationHashKey(ctx, M.ParseSocksaddr("www.example.com:443")))
+	require.Equal(t, "example.co.uk", destinationHashKey(ctx, M.ParseSocksaddr("a.b.example.co.uk:443")))
+	require.Equal(t, "1.2.3.4", destinationHashKey(ctx, M.ParseSocksaddr("1.2.3.4:443")))
+	ctx = adapter.WithContext(ctx, &adapter.InboundContext{Domain: "cdn.example.org"})
+	require.Equal(t, "example.org", destinationHashKey(ctx, M.ParseSocksaddr("1.2.3.4:443")))
+
+	keys := []string{"example.com", "example.org", "example.net", "1.2.3.4", "10.0.0.1", "google.com"}
+	selected := make(map[string]adapter.Outbound)
+	for _, key := range keys {
+		selected[key] = hashOutbound(outbounds, key)
+		require.Equal(t, selected[key], hashOutbound(outbounds, key))
+	}
+	removed := selected[keys[0]]
+	var remaining []adapter.Outbound
+	for _, detour := range outbounds {
+		if detour != removed {
+			remaining = append(remaining, detour)
+		}
+	}
+	for _, key := range keys {
+		if selected[key] != removed {
+			require.Equal(t, selected[key], hashOutbound(remaining, key), key)
+		}
+	}
+}
+
+func TestLoadBalanceHealthy(t *testing.T) {
+	t.Parallel()
+	loadBalance := &LoadBalance{
+		myOutboundAdapter: myOutboundAdapter{logger: log.NewNOPFactory().Logger()},
+		strategy:          C.LoadBalanceStrategyRoundRobin,
+		history:           urltest.NewHistoryStorage(),
+		down:              make(map[string]time.Time),
+	}
+	for _, tag := range []string{"a", "b", "c"} {
+		loadBalance.outbounds = append(loadBalance.outbounds, NewBlock(log.NewNOPFactory().Logger(), tag))
+	}
+	ctx := context.Background()
+	destination := M.ParseSocksaddr("example.com:443")
+	require.Empty(t, loadBalance.available(N.NetworkTCP))
+	_, err := loadBalance.pick(ctx, N.NetworkTCP, destination)
+	require.Error(t, err)
+
+	loadBalance.history.StoreURLTestHistory("b", &urltest.History{Delay: 100})
+	require.Equal(t, []adapter.Outbound{loadBalance.outbounds[1]}, loadBalance.available(N.NetworkTCP))
+	for i := 0; i < 3; i++ {
+		detour, err := loadBalance.pick(ctx, N.NetworkTCP, destination)
+		require.NoError(t, err)
+		require.Equal(t, "b", detour.Tag())
+	}
+
+	loadBalance.history.DeleteURLTestHistory("b")
+	_, err = loadBalance.pick(ctx, N.NetworkTCP, destination)
+	require.Error(t, err)
+
+	loadBalance.history.StoreURLTestHistory("b", &urltest.History{Time: time.Now(), Delay: 100})
+	_, err = loadBalance.DialContext(ctx, N.NetworkTCP, destination)
+	require.Error(t, err)
+	require.NotNil(t, loadBalance.history.LoadURLTestHistory("b"))
+	require.Empty(t, loadBalance.available(N.NetworkTCP))
+
+	loadBalance.history.StoreURLTestHistory("b", &urltest.History{Time: time.Now().Add(time.Second), Delay: 100})
+	require.Equal(t, []adapter.Outbound{loadBalance.outbounds[1]}, loadBalance.available(N.NetworkTCP))
+}
+
+func TestLoadBalancePreferAvailable(t *testing.T) {
+	t.Parallel()
+	loadBalance := &LoadBalance{
+		strategy: C.LoadBalanceStrategyRoundRobin,
+		history:  urltest.NewHistoryStorage(),
+		down:     make(map[string]time.Time),
+	}
+	for _, tag := range []string{"a", "b"} {
+		loadBalance.outbounds = append(loadBalance.outbounds, NewBlock(log.NewNOPFactory().Logger(), tag))
+	}
+	loadBalance.exhausted = map[adapter.Outbound]bool{loadBalance.outbounds[1]: true}
+	loadBalance.history.StoreURLTestHistory("b", &urltest.History{Delay: 100})
+	require.Equal(t, loadBalance.outbounds[1:], loadBalance.available(N.NetworkTCP))
+
+	loadBalance.history.StoreURLTestHistory("a", &urltest.History{Delay: 200})
+	require.Equal(t, loadBalance.outbounds[:1], loadBalance.available(N.NetworkTCP))
+}
+
+func TestLoadBalanceUDPHistory(t *testing.T) {
+	t.Parallel()
+	loadBalance := &LoadBalance{
+		strategy:      C.LoadBalanceStrategyRoundRobin,
+		useUDPHistory: true,
+		history:       urltest.NewHistoryStorage(),
+	}
+	for _, tag := range []string{"a", "b"} {
+		loadBalance.outbounds = append(loadBalance.outbounds, NewBlock(log.NewNOPFactory().Logger(), tag))
+	}
+	loadBalance.history.StoreURLTestHistory("a", &urltest.History{Delay: 100})
+	loadBalance.history.StoreUDPTestHistory("b", &urltest.History{Delay: 100})
+	require.Equal(t, loadBalance.outbounds[:1], loadBalance.available(N.NetworkTCP))
+	require.Equal(t, loadBalance.outbounds[1:], loadBalance.available(N.NetworkUDP))
+
+	loadBalance.useUDPHistory = false
+	require.Equal(t, loadBalance.outbounds[:1], loadBalance.available(N.NetworkUDP))
+}
@@ -31,6 +31,7 @@ type Selector struct {
 	outbounds                    []adapter.Outbound
 	outboundByTag                map[string]adapter.Outbound
 	selected                     adapter.Outbound
+	selectedIdentity             string
 	fallbackByDelayTest          bool
 	interruptGroup               *interrupt.Group
 	interruptExternalConnections bool
@@ -50,6 +51,7 @@ func NewSelector(ctx context.Context, router adapter.Router, logger log.ContextL
 			tags:            options.Outbounds,
 			uses:            options.Providers,
 			useAllProviders: options.UseAllProviders,
+			preferAvailable: options.PreferAvailableProviders,
 			types:           options.Types,
 			ports:           make(map[int]bool),
 			providers:       make(map[string]adapter.OutboundProvider),
@@ -111,6 +113,9 @@ func (s *Selector) Start() error {
 	outbounds, outboundByTag, err := s.pickOutbounds()
 	s.outbounds = outbounds
 	s.outboundByTag = outboundByTag
+	if err == nil {
+		s.selectedIdentity = s.outboundIdentity(s.selected)
+	}
 	return err
 }
 
@@ -127,7 +132,7 @@ func (s *Selector) pickOutbounds() ([]adapter.Outbound, map[string]adapter.Outbo
 		outboundByTag[tag] = detour
 	}
 
-	for i, tag := range s.uses {
+	for i, tag := range s.availableUses(s.router) {
 		provider, loaded := s.router.OutboundProvider(tag)
 		if !loaded {
 			return nil, nil, E.New("outbound provider ", i, " not found: ", tag)
@@ -180,24 +185,60 @@ func (s *Selector) pickOutbounds() ([]adapter.Outbound, map[string]adapter.Outbo
 	return outbounds, outboundByTag, nil
 }
 
-func (s *Selector) UpdateOutbounds(tag string) error {
-	if _, ok := s.providers[tag]; ok {
+func (s *Selector) UpdateOutbounds(tag string, delta adapter.OutboundProviderDelta) error {
+	if _, ok := s.providers[tag]; ok && delta.Changed() {
 		outbounds, outboundByTag, err := s.pickOutbounds()
 		if err != nil {
 			return E.New("update oubounds failed: ", s.tag)
 		}
 		s.outbounds = outbounds
 		s.outboundByTag = outboundByTag
+		s.remapSelected()
 	}
 	return nil
 }
 
+// remapSelected selects the outbound of the same node as the previous
+// selection, in case its tag has been changed by the provider update.
+func (s *Selector) remapSelected() {
+	identity := s.selectedIdentity
+	if identity != "" && s.outboundIdentity(s.selected) != identity {
+		for _, detour := range s.outbounds {
+			if s.outboundIdentity(detour) != identity {
+				continue
+			}
+			s.selected = detour
+			s.storeSelected(detour)
+			break
+		}
+	}
+	s.selectedIdentity = s.outboundIdentity(s.selected)
+}
+
+func (s *Selector) outboundIdentity(detour adapter.Outbound) string {
+	if detour == nil {
+		return ""
+	}
+	for _, provider := range s.providers {
+		if outbound, loaded := provider.Outbound(detour.Tag()); loaded && outbound == detour {
+			identity, _ := provider.OutboundIdentity(detour.Tag())
+			return identity
+		}
+	}
+	return ""
+}
+
 func (s *Selector) setSelected(detour adapter.Outbound) {
 	if s.selected == detour {
 		return
 	}
 	defer s.interruptGroup.Interrupt(s.interruptExternalConnections)
 	s.selected = detour
+	s.selectedIdentity = s.outboundIdentity(detour)
+	s.storeSelected(detour)
+}
+
+func (s *Selector) storeSelected(detour adapter.Outbound) {
 	if s.tag == "" {
 		return
 	}
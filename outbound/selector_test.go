@@ -0,0 +1,117 @@
+package outbound
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testIdentityProvider struct {
+	adapter.OutboundProvider
+	outbounds map[string]adapter.Outbound
+}
+
+func (p *testIdentityProvider) Outbound(tag string) (adapter.Outbound, bool) {
+	outbound, loaded := p.outbounds[tag]
+	return outbound, loaded
+}
+
+func (p *testIdentityProvider) OutboundIdentity(tag string) (string, bool) {
+	return "identity-" + tag, true
+}
+
+func TestSelectorOutboundIdentity(t *testing.T) {
+	t.Parallel()
+	node := NewBlock(log.NewNOPFactory().Logger(), "node")
+	selector := &Selector{
+		myGroupAdapter: myGroupAdapter{
+			providers: map[string]adapter.OutboundProvider{
+				"sub": &testIdentityProvider{outbounds: map[string]adapter.Outbound{"node": node}},
+			},
+		},
+	}
+	require.Equal(t, "identity-node", selector.outboundIdentity(node))
+	require.Equal(t, "", selector.outboundIdentity(NewBlock(log.NewNOPFactory().Logger(), "node")))
+	require.Equal(t, "", selector.outboundIdentity(nil))
+}
+
+type testRenamingProvider struct {
+	adapter.OutboundProvider
+	outbounds  []adapter.Outbound
+	identities map[string]string
+}
+
+func (p *testRenamingProvider) Outbounds() []adapter.Outbound {
+	return p.outbounds
+}
+
+func (p *testRenamingProvider) Outbound(tag string) (adapter.Outbound, bool) {
+	for _, outbound := range p.outbounds {
+		if outbound.Tag() == tag {
+			return outbound, true
+		}
+	}
+	return nil, false
+}
+
+func (p *testRenamingProvider) OutboundIdentity(tag string) (string, bool) {
+	identity, loaded := p.identities[tag]
+	return identity, loaded
+}
+
+func (p *testRenamingProvider) SubscriptionStatus() adapter.SubscriptionStatus {
+	return adapter.SubscriptionStatus{}
+}
+
+type testSelectedCacheFile struct {
+	adapter.CacheFile
+	selected map[string]string
+}
+
+func (c *testSelectedCacheFile) LoadSelected(group string) string {
+	return c.selected[group]
+}
+
+func (c *testSelectedCacheFile) StoreSelected(group string, selected string) error {
+	c.selected[group] = selected
+	return nil
+}
+
+func TestSelectorRemapRenamed(t *testing.T) {
+	t.Parallel()
+	logger := log.NewNOPFactory().Logger()
+	provider := &testRenamingProvider{
+		outbounds:  []adapter.Outbound{NewBlock(logger, "other"), NewBlock(logger, "name")},
+		identities: map[string]string{"other": "node-other", "name": "node-name"},
+	}
+	router := &testChainRouter{
+		providers: map[string]adapter.OutboundProvider{"sub": provider},
+	}
+	cacheFile := &testSelectedCacheFile{selected: make(map[string]string)}
+	ctx := service.ContextWithDefaultRegistry(context.Background())
+	service.MustRegister[adapter.CacheFile](ctx, cacheFile)
+	selector, err := NewSelector(ctx, router, logger, "selector", option.SelectorOutboundOptions{
+		GroupOutboundOptions: option.GroupOutboundOptions{Providers: []string{"sub"}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, selector.Start())
+	require.True(t, selector.SelectOutbound("name"))
+	require.Equal(t, "name", cacheFile.selected["selector"])
+
+	renamed := NewBlock(logger, "name[1]")
+	provider.outbounds = []adapter.Outbound{provider.outbounds[0], renamed}
+	provider.identities = map[string]string{"other": "node-other", "name[1]": "node-name"}
+	require.NoError(t, selector.UpdateOutbounds("sub", adapter.OutboundProviderDelta{
+		Added:   []adapter.Outbound{renamed},
+		Removed: []adapter.Outbound{NewBlock(logger, "name")},
+	}))
+	require.Equal(t, renamed, selector.selected)
+	require.Equal(t, "node-name", selector.selectedIdentity)
+	require.Equal(t, "name[1]", cacheFile.selected["selector"])
+}
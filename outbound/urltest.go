@@ -9,6 +9,7 @@ import (
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/interrupt"
 	"github.com/sagernet/sing-box/common/urltest"
+	"github.com/sagernet/sing-box/common/urltest/udptest"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
@@ -40,6 +41,8 @@ type URLTest struct {
 	idleTimeout                  time.Duration
 	group                        *URLTestGroup
 	interruptExternalConnections bool
+	udpTest                      *udptest.Options
+	delaySamples                 int
 }
 
 func NewURLTest(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.URLTestOutboundOptions) (*URLTest, error) {
@@ -57,6 +60,7 @@ func NewURLTest(ctx context.Context, router adapter.Router, logger log.ContextLo
 			tags:            options.Outbounds,
 			uses:            options.Providers,
 			useAllProviders: options.UseAllProviders,
+			preferAvailable: options.PreferAvailableProviders,
 			types:           options.Types,
 			ports:           make(map[int]bool),
 			providers:       make(map[string]adapter.OutboundProvider),
@@ -67,10 +71,18 @@ func NewURLTest(ctx context.Context, router adapter.Router, logger log.ContextLo
 		tolerance:                    options.Tolerance,
 		idleTimeout:                  time.Duration(options.IdleTimeout),
 		interruptExternalConnections: options.InterruptExistConnections,
+		delaySamples:                 int(options.DelaySamples),
 	}
 	if len(outbound.tags) == 0 && len(outbound.uses) == 0 && !outbound.useAllProviders {
 		return nil, E.New("missing tags and uses")
 	}
+	if options.UDPTest != nil {
+		udpTest, err := udptest.NewOptions(options.UDPTest.Type, options.UDPTest.Server)
+		if err != nil {
+			return nil, E.Cause(err, "parse udp_test")
+		}
+		outbound.udpTest = udpTest
+	}
 	if len(options.Includes) > 0 {
 		includes := make([]*R.Regexp, 0, len(options.Includes))
 		for i, include := range options.Includes {
@@ -102,6 +114,7 @@ func NewURLTest(ctx context.Context, router adapter.Router, logger log.ContextLo
 
 func (s *URLTest) pickOutbounds() ([]adapter.Outbound, error) {
 	outbounds := []adapter.Outbound{}
+	exhausted := make(map[adapter.Outbound]bool)
 	for i, tag := range s.tags {
 		detour, loaded := s.router.Outbound(tag)
 		if !loaded {
@@ -109,7 +122,7 @@ func (s *URLTest) pickOutbounds() ([]adapter.Outbound, error) {
 		}
 		outbounds = append(outbounds, detour)
 	}
-	for i, tag := range s.uses {
+	for i, tag := range s.availableUses(s.router) {
 		provider, loaded := s.router.OutboundProvider(tag)
 		if !loaded {
 			return nil, E.New("provider ", i, " not found: ", tag)
@@ -117,10 +130,14 @@ func (s *URLTest) pickOutbounds() ([]adapter.Outbound, error) {
 		if _, ok := s.providers[tag]; !ok {
 			s.providers[tag] = provider
 		}
+		isExhausted := s.isExhausted(provider)
 		for _, outbound := range provider.Outbounds() {
 			if !s.OutboundFilter(outbound) {
 				continue
 			}
+			if isExhausted {
+				exhausted[outbound] = true
+			}
 			outbounds = append(outbounds, outbound)
 		}
 	}
@@ -128,6 +145,7 @@ func (s *URLTest) pickOutbounds() ([]adapter.Outbound, error) {
 		OUTBOUNDLESS, _ := s.router.Outbound("OUTBOUNDLESS")
 		outbounds = append(outbounds, OUTBOUNDLESS)
 	}
+	s.exhausted = exhausted
 	return outbounds, nil
 }
 
@@ -153,21 +171,33 @@ func (s *URLTest) Start() error {
 		s.tolerance,
 		s.idleTimeout,
 		s.interruptExternalConnections,
+		s.udpTest,
+		s.delaySamples,
 	)
 	if err != nil {
 		return err
 	}
+	group.exhausted = s.exhausted
 	s.group = group
 	return nil
 }
 
-func (s *URLTest) UpdateOutbounds(tag string) error {
-	if _, ok := s.providers[tag]; ok {
+func (s *URLTest) UpdateOutbounds(tag string, delta adapter.OutboundProviderDelta) error {
+	if _, ok := s.providers[tag]; ok && delta.Changed() {
 		outbounds, err := s.pickOutbounds()
 		if err != nil {
 			return E.New("update outbounds failed: ", s.tag, ", with reason: ", err)
 		}
 		s.group.outbounds = outbounds
+		s.group.exhausted = s.exhausted
+		for _, removed := range delta.Removed {
+			if s.group.selectedOutboundTCP == removed {
+				s.group.selectedOutboundTCP = nil
+			}
+			if s.group.selectedOutboundUDP == removed {
+				s.group.selectedOutboundUDP = nil
+			}
+		}
 		s.group.performUpdateCheck()
 	}
 	return nil
@@ -264,6 +294,9 @@ func (s *URLTest) ListenPacket(ctx context.Context, destination M.Socksaddr) (ne
 	}
 	s.logger.ErrorContext(ctx, err)
 	s.group.history.DeleteURLTestHistory(outbound.Tag())
+	if s.udpTest != nil {
+		s.group.history.DeleteUDPTestHistory(outbound.Tag())
+	}
 	return nil, err
 }
 
@@ -294,6 +327,7 @@ type URLTestGroup struct {
 	router                       adapter.Router
 	logger                       log.Logger
 	outbounds                    []adapter.Outbound
+	exhausted                    map[adapter.Outbound]bool
 	link                         string
 	interval                     time.Duration
 	tolerance                    uint16
@@ -305,6 +339,8 @@ type URLTestGroup struct {
 	selectedOutboundUDP          adapter.Outbound
 	interruptGroup               *interrupt.Group
 	interruptExternalConnections bool
+	udpTest                      *udptest.Options
+	delaySamples                 int
 
 	access     sync.Mutex
 	ticker     *time.Ticker
@@ -323,6 +359,8 @@ func NewURLTestGroup(
 	tolerance uint16,
 	idleTimeout time.Duration,
 	interruptExternalConnections bool,
+	udpTest *udptest.Options,
+	delaySamples int,
 ) (*URLTestGroup, error) {
 	if interval == 0 {
 		interval = C.DefaultURLTestInterval
@@ -336,6 +374,9 @@ func NewURLTestGroup(
 	if interval > idleTimeout {
 		return nil, E.New("interval must be less or equal than idle_timeout")
 	}
+	if delaySamples > urltest.HistorySize {
+		return nil, E.New("delay_samples must be less or equal than ", urltest.HistorySize)
+	}
 	var history *urltest.HistoryStorage
 	if history = service.PtrFromContext[urltest.HistoryStorage](ctx); history != nil {
 	} else if clashServer := router.ClashServer(); clashServer != nil {
@@ -371,6 +412,8 @@ func NewURLTestGroup(
 		selectedOutboundTCP:          TCPOut,
 		selectedOutboundUDP:          UDPOut,
 		interruptExternalConnections: interruptExternalConnections,
+		udpTest:                      udpTest,
+		delaySamples:                 delaySamples,
 	}, nil
 }
 
@@ -406,48 +449,81 @@ func (g *URLTestGroup) Close() error {
 	return nil
 }
 
+// Select returns the outbound with the lowest delay, outbounds of exhausted
+// providers are only selected if no other outbound is tested.
 func (g *URLTestGroup) Select(network string) (adapter.Outbound, bool) {
+	if minOutbound := g.selectMinDelay(network, false); minOutbound != nil {
+		return minOutbound, true
+	}
+	if len(g.exhausted) > 0 {
+		if minOutbound := g.selectMinDelay(network, true); minOutbound != nil {
+			return minOutbound, true
+		}
+	}
+	for _, detour := range g.outbounds {
+		if !common.Contains(detour.Network(), network) {
+			continue
+		}
+		return detour, false
+	}
+	return nil, false
+}
+
+func (g *URLTestGroup) selectMinDelay(network string, withExhausted bool) adapter.Outbound {
 	var minDelay uint16
 	var minOutbound adapter.Outbound
 	switch network {
 	case N.NetworkTCP:
-		if g.selectedOutboundTCP != nil {
-			if history := g.history.LoadURLTestHistory(RealTag(g.selectedOutboundTCP)); history != nil {
+		if g.selectedOutboundTCP != nil && (withExhausted || !g.exhausted[g.selectedOutboundTCP]) {
+			if delay, loaded := g.loadDelay(N.NetworkTCP, RealTag(g.selectedOutboundTCP)); loaded {
 				minOutbound = g.selectedOutboundTCP
-				minDelay = history.Delay
+				minDelay = delay
 			}
 		}
 	case N.NetworkUDP:
-		if g.selectedOutboundUDP != nil {
-			if history := g.history.LoadURLTestHistory(RealTag(g.selectedOutboundUDP)); history != nil {
+		if g.selectedOutboundUDP != nil && (withExhausted || !g.exhausted[g.selectedOutboundUDP]) {
+			if delay, loaded := g.loadDelay(N.NetworkUDP, RealTag(g.selectedOutboundUDP)); loaded {
 				minOutbound = g.selectedOutboundUDP
-				minDelay = history.Delay
+				minDelay = delay
 			}
 		}
 	}
 	for _, detour := range g.outbounds {
-		if !common.Contains(detour.Network(), network) {
+		if !common.Contains(detour.Network(), network) || !withExhausted && g.exhausted[detour] {
 			continue
 		}
-		history := g.history.LoadURLTestHistory(RealTag(detour))
-		if history == nil {
+		delay, loaded := g.loadDelay(network, RealTag(detour))
+		if !loaded {
 			continue
 		}
-		if minDelay == 0 || minDelay > history.Delay+g.tolerance {
-			minDelay = history.Delay
+		if minDelay == 0 || minDelay > delay+g.tolerance {
+			minDelay = delay
 			minOutbound = detour
 		}
 	}
-	if minOutbound == nil {
-		for _, detour := range g.outbounds {
-			if !common.Contains(detour.Network(), network) {
-				continue
-			}
-			return detour, false
+	return minOutbound
+}
+
+// loadDelay returns the UDP test delay for UDP if udp_test is enabled,
+// otherwise the URL test delay, or the mean of the last delay_samples URL
+// tests. Outbounds failed in the last test are not loaded.
+func (g *URLTestGroup) loadDelay(network string, tag string) (uint16, bool) {
+	if network == N.NetworkUDP && g.udpTest != nil {
+		if history := g.history.LoadUDPTestHistory(tag); history != nil {
+			return history.Delay, true
 		}
-		return nil, false
+		return 0, false
+	}
+	history := g.history.LoadURLTestHistory(tag)
+	if history == nil {
+		return 0, false
 	}
-	return minOutbound, true
+	if g.delaySamples > 1 {
+		if stats := g.history.LoadURLTestStats(tag, g.delaySamples); stats != nil && stats.Mean > 0 {
+			return stats.Mean, true
+		}
+	}
+	return history.Delay, true
 }
 
 func (g *URLTestGroup) loopCheck() {
@@ -522,6 +598,19 @@ func (g *URLTestGroup) urlTest(ctx context.Context, force bool) (map[string]uint
 				result[tag] = t
 				resultAccess.Unlock()
 			}
+			if g.udpTest != nil && common.Contains(p.Network(), N.NetworkUDP) {
+				t, err = udptest.Check(g.ctx, p, *g.udpTest)
+				if err != nil {
+					g.logger.Debug("outbound ", tag, " udp unavailable: ", err)
+					g.history.DeleteUDPTestHistory(realTag)
+				} else {
+					g.logger.Debug("outbound ", tag, " udp available: ", t, "ms")
+					g.history.StoreUDPTestHistory(realTag, &urltest.History{
+						Time:  time.Now(),
+						Delay: t,
+					})
+				}
+			}
 			return nil, nil
 		})
 	}
@@ -6,12 +6,15 @@ import (
 	"net/netip"
 	"regexp"
 	"runtime"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/sagernet/sing-box/adapter"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
 	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
 
 	"gopkg.in/yaml.v3"
 )
@@ -29,7 +32,7 @@ func convertTLSOptions(proxy map[string]any) *option.OutboundTLSOptions {
 	if tls, exists := proxy["tls"].(bool); exists {
 		options.Enabled = tls
 		if insecure, exists := proxy["skip-cert-verify"].(bool); exists {
-		    options.Insecure = insecure
+			options.Insecure = insecure
 		}
 	}
 	if proxy["type"] == "trojan" {
@@ -220,6 +223,10 @@ func convertHTTPTransport(proxy map[string]any) option.V2RayHTTPOptions {
 		}
 		if pathRaw, exists := httpOpts["path"]; exists {
 			switch path := pathRaw.(type) {
+			case []any:
+				if len(path) > 0 {
+					options.Path = fmt.Sprint(path[0])
+				}
 			case []string:
 				options.Path = path[0]
 			case string:
@@ -227,12 +234,7 @@ func convertHTTPTransport(proxy map[string]any) option.V2RayHTTPOptions {
 			}
 		}
 		if hostsRaw, exists := httpOpts["host"]; exists {
-			switch hosts := hostsRaw.(type) {
-			case []string:
-				options.Host = hosts
-			case string:
-				options.Host = []string{hosts}
-			}
+			options.Host = convertStringList(hostsRaw)
 		}
 		if headers, exists := httpOpts["headers"].(map[string]any); exists {
 			for key, valueRaw := range headers {
@@ -259,17 +261,31 @@ func convertH2Transport(proxy map[string]any) option.V2RayHTTPOptions {
 	}
 	if h2Opts, exists := proxy["h2-opts"].(map[string]any); exists {
 		if hostsRaw, exists := h2Opts["host"]; exists {
-			switch hosts := hostsRaw.(type) {
-			case []string:
-				options.Host = hosts
-			case string:
-				options.Host = []string{hosts}
-			}
+			options.Host = convertStringList(hostsRaw)
+		}
+		if path, exists := h2Opts["path"].(string); exists {
+			options.Path = path
 		}
 	}
 	return options
 }
 
+func convertStringList(content any) option.Listable[string] {
+	switch value := content.(type) {
+	case []any:
+		valueArr := option.Listable[string]{}
+		for _, item := range value {
+			valueArr = append(valueArr, fmt.Sprint(item))
+		}
+		return valueArr
+	case []string:
+		return value
+	case string:
+		return []string{value}
+	}
+	return option.Listable[string]{}
+}
+
 func convertGRPCTransport(proxy map[string]any) option.V2RayGRPCOptions {
 	options := option.V2RayGRPCOptions{}
 	if grpcOpts, exists := proxy["grpc-opts"].(map[string]any); exists {
@@ -291,9 +307,9 @@ func convertDialerOption(proxy map[string]any) option.DialerOptions {
 	if name, exists := proxy["interface-name"].(string); exists {
 		options.BindInterface = name
 	}
-	if mark, exists := proxy["routing-mark"].(uint32); exists {
+	if mark, exists := proxy["routing-mark"].(int); exists {
 		if runtime.GOOS == "android" || runtime.GOOS == "linux" {
-			options.RoutingMark = mark
+			options.RoutingMark = uint32(mark)
 		}
 	}
 	if detour, exists := proxy["dialer-proxy"].(string); exists {
@@ -303,33 +319,59 @@ func convertDialerOption(proxy map[string]any) option.DialerOptions {
 		var strategy option.DomainStrategy
 		switch version {
 		case "dual":
-			strategy.UnmarshalJSON([]byte(""))
+			strategy.UnmarshalJSON([]byte(`""`))
 		case "ipv4":
-			strategy.UnmarshalJSON([]byte("ipv4_only"))
+			strategy.UnmarshalJSON([]byte(`"ipv4_only"`))
 		case "ipv6":
-			strategy.UnmarshalJSON([]byte("ipv6_only"))
+			strategy.UnmarshalJSON([]byte(`"ipv6_only"`))
 		case "ipv4-prefer":
-			strategy.UnmarshalJSON([]byte("prefer_ipv4"))
+			strategy.UnmarshalJSON([]byte(`"prefer_ipv4"`))
 		case "ipv6-prefer":
-			strategy.UnmarshalJSON([]byte("prefer_ipv6"))
+			strategy.UnmarshalJSON([]byte(`"prefer_ipv6"`))
 		}
 		options.DomainStrategy = strategy
 	}
 	return options
 }
 
-func newClashParser(content string) ([]option.Outbound, error) {
-	var outbounds []option.Outbound
+func sortedKeys(content map[string]any) []string {
+	keys := make([]string, 0, len(content))
+	for key := range content {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func newClashParser(content string) ([]option.Outbound, []adapter.SkippedOutbound, error) {
+	var (
+		outbounds []option.Outbound
+		skipped   []adapter.SkippedOutbound
+	)
 	clashConfig := &ClashConfig{
 		Proxies: []map[string]any{},
 	}
 	err := yaml.Unmarshal([]byte(content), clashConfig)
 	if err != nil {
-		return outbounds, err
-	}
-	for _, proxy := range clashConfig.Proxies {
+		return outbounds, skipped, err
+	}
+	for i, proxy := range clashConfig.Proxies {
+		name, _ := proxy["name"].(string)
+		skip := func(reason ...any) {
+			skipped = append(skipped, adapter.SkippedOutbound{
+				Index:  i,
+				Tag:    name,
+				Type:   fmt.Sprint(proxy["type"]),
+				Reason: F.ToString(reason...),
+			})
+		}
 		protocol, exists := proxy["type"]
 		if !exists {
+			skipped = append(skipped, adapter.SkippedOutbound{
+				Index:  i,
+				Tag:    name,
+				Reason: "missing type",
+			})
 			continue
 		}
 		var (
@@ -347,6 +389,7 @@ func newClashParser(content string) ([]option.Outbound, error) {
 				case "obfs", "v2ray-plugin":
 					outbound, err = newSSClashParser(proxy)
 				default:
+					skip("unsupported plugin: ", plugin)
 					continue
 				}
 			} else {
@@ -358,6 +401,7 @@ func newClashParser(content string) ([]option.Outbound, error) {
 			outbound, err = newHTTPClashParser(proxy)
 		case "tuic":
 			if _, exists := proxy["token"]; exists {
+				skip("unsupported tuic v4 token authentication")
 				continue
 			}
 			outbound, err = newTUICClashParser(proxy)
@@ -365,13 +409,15 @@ func newClashParser(content string) ([]option.Outbound, error) {
 			outbound, err = newVMessClashParser(proxy)
 		case "vless":
 			if flow, exists := proxy["flow"].(string); exists && flow != "xtls-rprx-vision" && flow != "" {
+				skip("unsupported flow: ", flow)
 				continue
 			}
 			outbound, err = newVLESSClashParser(proxy)
 		case "socks5":
 			outbound, err = newSOCKS5ClashParser(proxy)
 		case "trojan":
-			if _, exists := proxy["flow"].(string); exists {
+			if flow, exists := proxy["flow"].(string); exists {
+				skip("unsupported flow: ", flow)
 				continue
 			}
 			outbound, err = newTrojanClashParser(proxy)
@@ -382,16 +428,19 @@ func newClashParser(content string) ([]option.Outbound, error) {
 		case "wireguard":
 			outbound, err = newWireGuardClashParser(proxy)
 		default:
+			skip("unsupported type: ", protocol)
 			continue
 		}
-		if err == nil {
-			outbounds = append(outbounds, outbound)
-			if stlsPart.Type != "" {
-				outbounds = append(outbounds, stlsPart)
-			}
+		if err != nil {
+			skip(err)
+			continue
+		}
+		outbounds = append(outbounds, outbound)
+		if stlsPart.Type != "" {
+			outbounds = append(outbounds, stlsPart)
 		}
 	}
-	return outbounds, nil
+	return outbounds, skipped, nil
 }
 
 func newSSClashParser(proxy map[string]any) (option.Outbound, error) {
@@ -423,7 +472,8 @@ func newSSClashParser(proxy map[string]any) (option.Outbound, error) {
 		case "obfs":
 			options.Plugin = "obfs-local"
 			if opts, exists := proxy["plugin-opts"].(map[string]any); exists {
-				for key, value := range opts {
+				for _, key := range sortedKeys(opts) {
+					value := opts[key]
 					switch key {
 					case "mode":
 						optArr = append(optArr, fmt.Sprint("obfs=", value))
@@ -437,10 +487,11 @@ func newSSClashParser(proxy map[string]any) (option.Outbound, error) {
 		case "v2ray-plugin":
 			options.Plugin = "v2ray-plugin"
 			if opts, exists := proxy["plugin-opts"].(map[string]any); exists {
-				for key, value := range opts {
+				for _, key := range sortedKeys(opts) {
+					value := opts[key]
 					switch key {
 					case "mode":
-						optArr = append(optArr, fmt.Sprint("obfs=", value))
+						optArr = append(optArr, fmt.Sprint("mode=", value))
 					case "host":
 						host := value
 						if h, ok := proxy["ws-host"].(string); ok {
@@ -638,7 +689,7 @@ func newTUICClashParser(proxy map[string]any) (option.Outbound, error) {
 	if password, exists := proxy["password"].(string); exists {
 		options.Password = password
 	}
-	if cc, exists := proxy["congestion-controller"].(string); exists && cc == "cubic" {
+	if cc, exists := proxy["congestion-controller"].(string); exists && cc != "cubic" {
 		options.CongestionControl = cc
 	}
 	if urm, exists := proxy["udp-relay-mode"].(string); exists && urm == "quic" {
@@ -655,6 +706,7 @@ func newTUICClashParser(proxy map[string]any) (option.Outbound, error) {
 		options.Heartbeat = option.Duration(duration)
 	}
 	options.TLS = convertTLSOptions(proxy)
+	options.TLS.Enabled = true
 	options.TLS.UTLS.Enabled = false
 	options.DialerOptions = convertDialerOption(proxy)
 	outbound.TUICOptions = options
@@ -714,7 +766,9 @@ func newVMessClashParser(proxy map[string]any) (option.Outbound, error) {
 			Transport.Type = C.V2RayTransportTypeGRPC
 			Transport.GRPCOptions = convertGRPCTransport(proxy)
 		}
-		options.Transport = &Transport
+		if Transport.Type != "" {
+			options.Transport = &Transport
+		}
 	}
 	options.DialerOptions = convertDialerOption(proxy)
 	outbound.VMessOptions = options
@@ -762,7 +816,9 @@ func newVLESSClashParser(proxy map[string]any) (option.Outbound, error) {
 			Transport.Type = C.V2RayTransportTypeGRPC
 			Transport.GRPCOptions = convertGRPCTransport(proxy)
 		}
-		options.Transport = &Transport
+		if Transport.Type != "" {
+			options.Transport = &Transport
+		}
 	}
 	options.TLS = convertTLSOptions(proxy)
 	options.Multiplex = convertSMuxOptions(proxy)
@@ -844,7 +900,9 @@ func newTrojanClashParser(proxy map[string]any) (option.Outbound, error) {
 			Transport.Type = C.V2RayTransportTypeGRPC
 			Transport.GRPCOptions = convertGRPCTransport(proxy)
 		}
-		options.Transport = &Transport
+		if Transport.Type != "" {
+			options.Transport = &Transport
+		}
 	}
 	options.TLS = convertTLSOptions(proxy)
 	options.Multiplex = convertSMuxOptions(proxy)
@@ -0,0 +1,159 @@
+package provider
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-dns"
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/stretchr/testify/require"
+)
+
+func parseTestClash(t *testing.T, content string) option.Outbound {
+	outbounds, skipped, err := newClashParser(content)
+	require.NoError(t, err)
+	require.Empty(t, skipped)
+	require.Len(t, outbounds, 1)
+	return outbounds[0]
+}
+
+func TestClashTUICCongestion(t *testing.T) {
+	t.Parallel()
+	for cc, expected := range map[string]string{
+		"bbr":   "bbr",
+		"cubic": "",
+	} {
+		outbound := parseTestClash(t, `
+proxies:
+  - name: tuic
+    type: tuic
+    server: example.com
+    port: 443
+    uuid: b831381d-6324-4d53-ad4f-8cda48b30811
+    password: secret
+    congestion-controller: `+cc)
+		require.Equal(t, expected, outbound.TUICOptions.CongestionControl, cc)
+	}
+}
+
+func TestClashTUICTLS(t *testing.T) {
+	t.Parallel()
+	outbound := parseTestClash(t, `
+proxies:
+  - name: tuic
+    type: tuic
+    server: example.com
+    port: 443
+    uuid: b831381d-6324-4d53-ad4f-8cda48b30811
+    password: secret
+    sni: example.org
+`)
+	require.True(t, outbound.TUICOptions.TLS.Enabled)
+	require.Equal(t, "example.org", outbound.TUICOptions.TLS.ServerName)
+}
+
+func TestClashV2RayPlugin(t *testing.T) {
+	t.Parallel()
+	outbound := parseTestClash(t, `
+proxies:
+  - name: ss v2ray
+    type: ss
+    server: example.com
+    port: 443
+    cipher: chacha20-ietf-poly1305
+    password: secret
+    plugin: v2ray-plugin
+    plugin-opts:
+      mode: websocket
+      host: cdn.example.com
+      path: /ws
+`)
+	require.Equal(t, "v2ray-plugin", outbound.ShadowsocksOptions.Plugin)
+	require.Equal(t, "host=cdn.example.com;mode=websocket;path=/ws", outbound.ShadowsocksOptions.PluginOptions)
+}
+
+func TestClashTransportLists(t *testing.T) {
+	t.Parallel()
+	outbound := parseTestClash(t, `
+proxies:
+  - name: vmess h2
+    type: vmess
+    server: example.com
+    port: 443
+    uuid: b831381d-6324-4d53-ad4f-8cda48b30811
+    cipher: auto
+    tls: true
+    network: h2
+    h2-opts:
+      host:
+        - a.example.com
+        - b.example.com
+      path: /h2
+`)
+	require.Equal(t, option.Listable[string]{"a.example.com", "b.example.com"}, outbound.VMessOptions.Transport.HTTPOptions.Host)
+	require.Equal(t, "/h2", outbound.VMessOptions.Transport.HTTPOptions.Path)
+
+	outbound = parseTestClash(t, `
+proxies:
+  - name: vmess http
+    type: vmess
+    server: example.com
+    port: 80
+    uuid: b831381d-6324-4d53-ad4f-8cda48b30811
+    cipher: auto
+    network: http
+    http-opts:
+      path:
+        - /a
+        - /b
+      host:
+        - example.org
+`)
+	require.Equal(t, option.Listable[string]{"example.org"}, outbound.VMessOptions.Transport.HTTPOptions.Host)
+	require.Equal(t, "/a", outbound.VMessOptions.Transport.HTTPOptions.Path)
+}
+
+func TestClashDialerOptions(t *testing.T) {
+	t.Parallel()
+	for version, strategy := range map[string]dns.DomainStrategy{
+		"dual":        dns.DomainStrategyAsIS,
+		"ipv4":        dns.DomainStrategyUseIPv4,
+		"ipv6":        dns.DomainStrategyUseIPv6,
+		"ipv4-prefer": dns.DomainStrategyPreferIPv4,
+		"ipv6-prefer": dns.DomainStrategyPreferIPv6,
+	} {
+		outbound := parseTestClash(t, `
+proxies:
+  - name: socks
+    type: socks5
+    server: 127.0.0.1
+    port: 1080
+    routing-mark: 255
+    ip-version: `+version)
+		require.Equal(t, option.DomainStrategy(strategy), outbound.SocksOptions.DomainStrategy, version)
+		if runtime.GOOS == "android" || runtime.GOOS == "linux" {
+			require.Equal(t, uint32(255), outbound.SocksOptions.RoutingMark)
+		}
+	}
+}
+
+func TestClashPlainTCPTransport(t *testing.T) {
+	t.Parallel()
+	for _, proxyType := range []string{"vmess", "vless", "trojan"} {
+		outbound := parseTestClash(t, `
+proxies:
+  - name: tcp
+    type: `+proxyType+`
+    server: example.com
+    port: 443
+    uuid: b831381d-6324-4d53-ad4f-8cda48b30811
+    cipher: auto
+    password: secret
+    network: tcp
+`)
+		_, err := json.Marshal(&outbound)
+		require.NoError(t, err, proxyType)
+	}
+}
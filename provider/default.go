@@ -15,6 +15,7 @@ import (
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/taskmonitor"
 	"github.com/sagernet/sing-box/common/urltest"
+	"github.com/sagernet/sing-box/common/urltest/udptest"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
@@ -24,7 +25,9 @@ import (
 	"github.com/sagernet/sing/common/batch"
 	E "github.com/sagernet/sing/common/exceptions"
 	F "github.com/sagernet/sing/common/format"
+	N "github.com/sagernet/sing/common/network"
 	"github.com/sagernet/sing/common/rw"
+	"github.com/sagernet/sing/service"
 	"github.com/sagernet/sing/service/pause"
 
 	R "github.com/dlclark/regexp2"
@@ -49,8 +52,10 @@ type myProviderAdapter struct {
 	// Common config
 	tag                 string
 	path                string
+	format              string
 	enableHealthcheck   bool
-	healthcheckUrl      string
+	healthcheckOptions  urltest.CheckOptions
+	healthcheckUDP      *udptest.Options
 	healthcheckInterval time.Duration
 	outboundOverride    *option.OutboundOverrideOptions
 	healchcheckHistory  *urltest.HistoryStorage
@@ -58,16 +63,24 @@ type myProviderAdapter struct {
 	lastUpdated         time.Time
 	outbounds           []adapter.Outbound
 	outboundByTag       map[string]adapter.Outbound
-	includes            []*R.Regexp
-	excludes            *R.Regexp
-	types               []string
-	ports               map[int]bool
+	skipped             []adapter.SkippedOutbound
+	tagRenames          []tagRename
+	subscription        option.SubscriptionOptions
+	outboundPatches     []outboundPatch
+	outboundMatcher
 
 	// Update cache
 	checking     atomic.Bool
 	updating     atomic.Bool
 	pauseManager pause.Manager
 	lastOuts     []option.Outbound
+	lastCreated  []adapter.Outbound
+
+	// Subscription status
+	subscriptionAccess sync.Mutex
+	subscriptionTimer  *time.Timer
+	exhausted          bool
+	warnings           []string
 
 	healthCheckTicker *time.Ticker
 	close             chan struct{}
@@ -100,15 +113,107 @@ func (a *myProviderAdapter) Outbounds() []adapter.Outbound {
 	return outbounds
 }
 
-func (a *myProviderAdapter) firstStart(ports []string) error {
-	if !O.CheckType(a.types) {
-		return E.New("invalid types")
+func (a *myProviderAdapter) initOptions(options option.OutboundProvider) error {
+	if err := a.initFormat(options.Format); err != nil {
+		return err
+	}
+	if err := a.initFilter(options.FilterOptions); err != nil {
+		return err
 	}
-	if portMap, err := O.CreatePortsMap(ports); err == nil {
-		a.ports = portMap
-	} else {
+	if err := a.initTagRenames(options.OutboundOverride); err != nil {
+		return err
+	}
+	a.subscription = common.PtrValueOrDefault(options.Subscription)
+	return a.initPatches(options.OutboundPatches)
+}
+
+func (a *myProviderAdapter) initTagRenames(options *option.OutboundOverrideOptions) error {
+	if options == nil {
 		return nil
 	}
+	for i, rename := range options.TagRename {
+		regex, err := R.Compile(rename.Pattern, R.IgnoreCase)
+		if err != nil {
+			return E.Cause(err, "parse tag_rename[", i, "]")
+		}
+		a.tagRenames = append(a.tagRenames, tagRename{
+			regex:       regex,
+			replacement: rename.Replacement,
+		})
+	}
+	return nil
+}
+
+func (a *myProviderAdapter) initFilter(options option.FilterOptions) error {
+	matcher, err := newOutboundMatcher(options)
+	if err != nil {
+		return err
+	}
+	a.outboundMatcher = matcher
+	return nil
+}
+
+type tagRename struct {
+	regex       *R.Regexp
+	replacement string
+}
+
+type outboundMatcher struct {
+	includes []*R.Regexp
+	excludes *R.Regexp
+	types    []string
+	ports    map[int]bool
+}
+
+func newOutboundMatcher(options option.FilterOptions) (outboundMatcher, error) {
+	var matcher outboundMatcher
+	if len(options.Includes) > 0 {
+		includes := make([]*R.Regexp, 0, len(options.Includes))
+		for i, include := range options.Includes {
+			regex, err := R.Compile(include, R.IgnoreCase)
+			if err != nil {
+				return matcher, E.Cause(err, "parse includes[", i, "]")
+			}
+			includes = append(includes, regex)
+		}
+		matcher.includes = includes
+	}
+	if options.Excludes != "" {
+		regex, err := R.Compile(options.Excludes, R.IgnoreCase)
+		if err != nil {
+			return matcher, E.Cause(err, "parse excludes")
+		}
+		matcher.excludes = regex
+	}
+	if !O.CheckType(options.Types) {
+		return matcher, E.New("invalid types")
+	}
+	matcher.types = options.Types
+	portMap, err := O.CreatePortsMap(options.Ports)
+	if err != nil {
+		return matcher, err
+	}
+	matcher.ports = portMap
+	return matcher, nil
+}
+
+// mismatchReason returns why outbound is not matched, or an empty string if it is.
+func (m outboundMatcher) mismatchReason(outbound option.Outbound) string {
+	switch {
+	case !O.TestIncludes(outbound.Tag, m.includes):
+		return "not matched by includes"
+	case !O.TestExcludes(outbound.Tag, m.excludes):
+		return "matched by excludes"
+	case !O.TestTypes(outbound.Type, m.types):
+		return "type not in types"
+	case !O.TestPorts(outbound.Port(), m.ports):
+		return "port not in ports"
+	default:
+		return ""
+	}
+}
+
+func (a *myProviderAdapter) firstStart() error {
 	if !rw.IsFile(a.path) {
 		return nil
 	}
@@ -117,22 +222,50 @@ func (a *myProviderAdapter) firstStart(ports []string) error {
 	info, content := a.getContentFromFile(a.router)
 	a.subInfo = info
 	a.lastUpdated = fileModeTime
-	outbounds, err := a.parseOutbounds(a.ctx, a.router, decodeBase64Safe(content))
+	delta, err := a.parseOutbounds(a.ctx, a.router, decodeBase64Safe(content))
 	if err != nil {
 		return err
-	} else if outbounds == nil {
+	} else if delta == nil {
 		return nil
 	}
 	outboundByTag := make(map[string]adapter.Outbound)
-	for _, out := range outbounds {
+	for _, out := range delta.outbounds {
 		tag := out.Tag()
 		outboundByTag[tag] = out
 	}
-	a.outbounds = outbounds
+	a.outbounds = delta.outbounds
 	a.outboundByTag = outboundByTag
 	return nil
 }
 
+// loadMetadata returns the last update state saved in the cache file, it is
+// ignored if the provider file is gone.
+func (a *myProviderAdapter) loadMetadata() *adapter.SavedProviderMetadata {
+	if a.lastUpdated.IsZero() {
+		return nil
+	}
+	cacheFile := service.FromContext[adapter.CacheFile](a.ctx)
+	if cacheFile == nil {
+		return nil
+	}
+	return cacheFile.LoadProviderMetadata(a.tag)
+}
+
+// saveMetadata saves the last update state with the download state in
+// metadata set by remote providers.
+func (a *myProviderAdapter) saveMetadata(metadata adapter.SavedProviderMetadata) {
+	cacheFile := service.FromContext[adapter.CacheFile](a.ctx)
+	if cacheFile == nil {
+		return
+	}
+	metadata.LastUpdated = a.lastUpdated
+	metadata.Skipped = a.skipped
+	err := cacheFile.SaveProviderMetadata(a.tag, &metadata)
+	if err != nil && a.logger != nil {
+		a.logger.Warn("save metadata of outbound provider ", a.tag, ": ", err)
+	}
+}
+
 func getFirstLine(content string) (string, string) {
 	lines := strings.Split(content, "\n")
 	if len(lines) == 1 {
@@ -151,6 +284,12 @@ func (a *myProviderAdapter) SubInfo() map[string]int64 {
 	return info
 }
 
+func (a *myProviderAdapter) SkippedOutbounds() []adapter.SkippedOutbound {
+	var skipped []adapter.SkippedOutbound
+	skipped = append(skipped, a.skipped...)
+	return skipped
+}
+
 func parseSubInfo(infoString string) (SubInfo, bool) {
 	var info SubInfo
 	result := subInfoParser.FindStringSubmatch(infoString)
@@ -168,27 +307,67 @@ func parseSubInfo(infoString string) (SubInfo, bool) {
 	return info, false
 }
 
-func (a *myProviderAdapter) createOutbounds(ctx context.Context, router adapter.Router, outbounds []option.Outbound) ([]adapter.Outbound, error) {
-	var outs []adapter.Outbound
-	for _, outbound := range outbounds {
+// createOutbounds creates outbounds aligned with options, reusing the outbound
+// created last time for options not changed. Failed entries are left nil.
+func (a *myProviderAdapter) createOutbounds(ctx context.Context, router adapter.Router, outbounds []option.Outbound) ([]adapter.Outbound, []adapter.SkippedOutbound) {
+	var (
+		created    = make([]adapter.Outbound, len(outbounds))
+		skipped    []adapter.SkippedOutbound
+		candidates = make(map[string][]int)
+	)
+	for i, outbound := range a.lastOuts {
+		if i < len(a.lastCreated) && a.lastCreated[i] != nil {
+			candidates[outbound.Tag] = append(candidates[outbound.Tag], i)
+		}
+	}
+	for i, outbound := range outbounds {
 		otype := outbound.Type
 		tag := outbound.Tag
+		if indexes := candidates[tag]; len(indexes) > 0 {
+			reused := -1
+			for j, index := range indexes {
+				if reflect.DeepEqual(a.lastOuts[index], outbound) {
+					reused = j
+					break
+				}
+			}
+			if reused >= 0 {
+				created[i] = a.lastCreated[indexes[reused]]
+				candidates[tag] = append(indexes[:reused:reused], indexes[reused+1:]...)
+				continue
+			}
+		}
 		switch otype {
-		case C.TypeDirect, C.TypeBlock, C.TypeDNS, C.TypeSelector, C.TypeURLTest:
-			continue
+		case C.TypeDirect, C.TypeBlock, C.TypeDNS, C.TypeSelector, C.TypeURLTest, C.TypeLoadBalance, C.TypeFallback, C.TypeChain:
+			skipped = append(skipped, adapter.SkippedOutbound{
+				Index:  i,
+				Tag:    tag,
+				Type:   otype,
+				Reason: "unsupported type in provider: " + otype,
+			})
 		default:
 			out, err := O.New(ctx, router, a.logger, tag, outbound)
-			if err != nil && a.logger != nil {
-				a.logger.WarnContext(ctx, "create provider[", a.tag, "] outbound[", tag, "]/", otype, " failed: ", err)
+			if err != nil {
+				if a.logger != nil {
+					a.logger.WarnContext(ctx, "create provider[", a.tag, "] outbound[", tag, "]/", otype, " failed: ", err)
+				}
+				skipped = append(skipped, adapter.SkippedOutbound{
+					Index:  i,
+					Tag:    tag,
+					Type:   otype,
+					Reason: err.Error(),
+				})
 				continue
 			}
-			outs = append(outs, out)
+			created[i] = out
 		}
 	}
-	if len(outbounds) > 0 && len(outs) == 0 && a.logger != nil {
+	if len(outbounds) > 0 && common.All(created, func(it adapter.Outbound) bool {
+		return it == nil
+	}) && a.logger != nil {
 		a.logger.WarnContext(ctx, "parse provider[", a.tag, "] failed: missing valid outbound")
 	}
-	return outs, nil
+	return created, skipped
 }
 
 func getTrimedFile(path string) []byte {
@@ -238,42 +417,109 @@ func (p *myProviderAdapter) checkChange(outbounds []option.Outbound) bool {
 	if len(p.lastOuts) != len(outbounds) {
 		return true
 	}
-	outMap := make(map[string]option.Outbound, len(p.lastOuts))
-	for _, out := range p.lastOuts {
-		outMap[out.Tag] = out
+	for i, outbound := range outbounds {
+		if !reflect.DeepEqual(p.lastOuts[i], outbound) {
+			return true
+		}
 	}
-	return !common.All(outbounds, func(it option.Outbound) bool {
-		out, exits := outMap[it.Tag]
-		return exits && reflect.DeepEqual(out, it)
-	})
+	return false
 }
 
-func (p *myProviderAdapter) parseOutbounds(ctx context.Context, router adapter.Router, content string) ([]adapter.Outbound, error) {
-	outbounds, err := p.newParser(content)
+// outboundsDelta is the result of an update. Unchanged outbounds keep their
+// instance in outbounds and appear in neither added nor removed.
+type outboundsDelta struct {
+	outbounds []adapter.Outbound
+	adapter.OutboundProviderDelta
+}
+
+func (p *myProviderAdapter) parseOutbounds(ctx context.Context, router adapter.Router, content string) (*outboundsDelta, error) {
+	outbounds, skipped, err := p.newParser(content)
 	if err != nil {
 		return nil, err
 	}
-	finalOuts := common.Filter(outbounds, func(it option.Outbound) bool {
-		return O.TestIncludes(it.Tag, p.includes) && O.TestExcludes(it.Tag, p.excludes) && O.TestTypes(it.Type, p.types) && O.TestPorts(it.Port(), p.ports)
-	})
+	finalOuts, filtered := p.filterOutbounds(outbounds)
 	if !p.checkChange(finalOuts) {
 		return nil, nil
 	}
+	created, failed := p.createOutbounds(ctx, router, finalOuts)
+	delta := &outboundsDelta{}
+	kept := make(map[adapter.Outbound]bool)
+	for _, out := range p.lastCreated {
+		if out != nil {
+			kept[out] = false
+		}
+	}
+	for _, out := range created {
+		if out == nil {
+			continue
+		}
+		delta.outbounds = append(delta.outbounds, out)
+		if _, loaded := kept[out]; loaded {
+			kept[out] = true
+		} else {
+			delta.Added = append(delta.Added, out)
+		}
+	}
+	var lastKept []adapter.Outbound
+	for _, out := range p.lastCreated {
+		if out == nil {
+			continue
+		}
+		if kept[out] {
+			lastKept = append(lastKept, out)
+		} else {
+			delta.Removed = append(delta.Removed, out)
+		}
+	}
+	delta.Reordered = isReordered(lastKept, delta.outbounds, kept)
 	p.lastOuts = finalOuts
-	return p.createOutbounds(ctx, router, finalOuts)
+	p.lastCreated = created
+	p.skipped = append(append(skipped, filtered...), failed...)
+	if len(p.skipped) > 0 && p.logger != nil {
+		for _, entry := range p.skipped {
+			p.logger.DebugContext(ctx, "provider[", p.tag, "] skipped [", entry.Index, "] ", entry.Tag, "/", entry.Type, ": ", entry.Reason)
+		}
+		p.logger.InfoContext(ctx, "provider[", p.tag, "] loaded ", len(delta.outbounds), " outbounds, skipped ", len(p.skipped), " entries")
+	}
+	return delta, nil
+}
+
+// isReordered reports whether outbounds kept by an update are in a different
+// order than before.
+func isReordered(lastKept []adapter.Outbound, outbounds []adapter.Outbound, kept map[adapter.Outbound]bool) bool {
+	index := 0
+	for _, out := range outbounds {
+		if !kept[out] {
+			continue
+		}
+		if lastKept[index] != out {
+			return true
+		}
+		index++
+	}
+	return false
 }
 
 func (p *myProviderAdapter) updateProviderFromContent(ctx context.Context, router adapter.Router, content string) (bool, error) {
-	outbounds, err := p.parseOutbounds(ctx, router, decodeBase64Safe(content))
+	lastOuts, lastCreated, lastSkipped := p.lastOuts, p.lastCreated, p.skipped
+	delta, err := p.parseOutbounds(ctx, router, decodeBase64Safe(content))
 	if err != nil {
 		return false, err
-	} else if outbounds == nil {
+	} else if delta == nil {
 		p.logger.Debug("provider ", p.tag, " has no changes")
 		return false, nil
 	}
+	p.logger.Debug("provider ", p.tag, " updated: ", len(delta.Added), " added, ", len(delta.Removed), " removed, ", len(delta.outbounds)-len(delta.Added), " unchanged")
 
-	outbounds, outboundByTag, err := p.startOutbounds(router, outbounds)
+	rollback := func() {
+		for _, out := range delta.Added {
+			common.Close(out)
+		}
+		p.lastOuts, p.lastCreated, p.skipped = lastOuts, lastCreated, lastSkipped
+	}
+	outbounds, outboundByTag, err := p.startOutbounds(router, delta.outbounds, delta.Added)
 	if err != nil {
+		rollback()
 		return false, err
 	}
 
@@ -282,15 +528,16 @@ func (p *myProviderAdapter) updateProviderFromContent(ctx context.Context, route
 	p.outbounds = outbounds
 	p.outboundByTag = outboundByTag
 
-	if err := p.updateGroups(router); err != nil {
-		for _, out := range outbounds {
-			common.Close(out)
-		}
+	if err := p.updateGroups(router, delta.OutboundProviderDelta); err != nil {
+		rollback()
 		p.outbounds = outsBackup
 		p.outboundByTag = outByTagBackup
 		return false, err
 	}
 
+	for _, out := range delta.Removed {
+		common.Close(out)
+	}
 	return true, nil
 }
 
@@ -303,7 +550,9 @@ func (p *myProviderAdapter) UpdateOutboundByTag() {
 	p.outboundByTag = outboundByTag
 }
 
-func (p *myProviderAdapter) startOutbounds(router adapter.Router, outbounds []adapter.Outbound) ([]adapter.Outbound, map[string]adapter.Outbound, error) {
+// startOutbounds assigns unique tags to and starts added outbounds, outbounds
+// kept from the last update are already started and keep their tags.
+func (p *myProviderAdapter) startOutbounds(router adapter.Router, outbounds []adapter.Outbound, added []adapter.Outbound) ([]adapter.Outbound, map[string]adapter.Outbound, error) {
 	pTag := p.Tag()
 	outboundTag := make(map[string]bool)
 	for _, out := range router.Outbounds() {
@@ -317,7 +566,19 @@ func (p *myProviderAdapter) startOutbounds(router adapter.Router, outbounds []ad
 			outboundTag[out.Tag()] = true
 		}
 	}
+	isAdded := make(map[adapter.Outbound]bool, len(added))
+	for _, out := range added {
+		isAdded[out] = true
+	}
+	for _, out := range outbounds {
+		if !isAdded[out] {
+			outboundTag[out.Tag()] = true
+		}
+	}
 	for i, out := range outbounds {
+		if !isAdded[out] {
+			continue
+		}
 		var tag string
 		if out.Tag() == "" {
 			tag = fmt.Sprint("[", pTag, "]", F.ToString(i))
@@ -356,11 +617,11 @@ func (p *myProviderAdapter) startOutbounds(router adapter.Router, outbounds []ad
 	return outbounds, outboundByTag, nil
 }
 
-func (p *myProviderAdapter) updateGroups(router adapter.Router) error {
+func (p *myProviderAdapter) updateGroups(router adapter.Router, delta adapter.OutboundProviderDelta) error {
 	for _, outbound := range router.Outbounds() {
 		if group, ok := outbound.(adapter.OutboundGroup); ok {
 			p.logger.Debug("update outbound group[", group.Tag(), "] with outbound provider[", p.tag, "]")
-			err := group.UpdateOutbounds(p.tag)
+			err := group.UpdateOutbounds(p.tag, delta)
 			if err != nil {
 				return E.Cause(err, "update outbound group[", group.Tag(), "] with outbound provider[", p.tag, "]")
 			}
@@ -375,10 +636,9 @@ func (p *myProviderAdapter) loopHealthCheck() {
 		return
 	}
 	p.healthCheckTicker = time.NewTicker(p.healthcheckInterval)
-	ctx, _ := context.WithCancel(p.ctx)
 	for {
 		select {
-		case <-ctx.Done():
+		case <-p.ctx.Done():
 			return
 		case <-p.healthCheckTicker.C:
 			p.pauseManager.WaitActive()
@@ -395,8 +655,33 @@ func (p *myProviderAdapter) refreshURLTestSelected(router adapter.Router) {
 	}
 }
 
+func (p *myProviderAdapter) initHealthcheck(options option.HealthcheckOptions) error {
+	expectedStatus, err := urltest.ParseStatusRanges(options.HealthcheckExpectedStatus)
+	if err != nil {
+		return E.Cause(err, "parse healthcheck_expected_status")
+	}
+	p.enableHealthcheck = options.EnableHealthcheck
+	p.healthcheckInterval = time.Duration(options.HealthcheckInterval)
+	if p.healthcheckInterval == 0 {
+		p.healthcheckInterval = C.DefaultURLTestInterval
+	}
+	if options.HealthcheckUDP != nil {
+		p.healthcheckUDP, err = udptest.NewOptions(options.HealthcheckUDP.Type, options.HealthcheckUDP.Server)
+		if err != nil {
+			return E.Cause(err, "parse healthcheck_udp")
+		}
+	}
+	p.healthcheckOptions = urltest.CheckOptions{
+		URLs:           options.HealthcheckUrl,
+		ExpectedStatus: expectedStatus,
+		ExpectedBody:   options.HealthcheckExpectedBody,
+		Timeout:        time.Duration(options.HealthcheckTimeout),
+	}
+	return nil
+}
+
 func (p *myProviderAdapter) CheckOutbounds(force bool) {
-	p.Healthcheck(p.ctx, p.healthcheckUrl, force)
+	p.Healthcheck(p.ctx, "", force)
 	p.refreshURLTestSelected(p.router)
 }
 
@@ -404,14 +689,14 @@ func (p *myProviderAdapter) Healthcheck(ctx context.Context, link string, force
 	if force && p.healthCheckTicker != nil {
 		p.healthCheckTicker.Reset(p.healthcheckInterval)
 	}
-	url := p.healthcheckUrl
+	options := p.healthcheckOptions
 	if link != "" {
-		url = link
+		options.URLs = []string{link}
 	}
-	return p.healthcheck(ctx, url)
+	return p.healthcheck(ctx, options)
 }
 
-func (p *myProviderAdapter) healthcheck(ctx context.Context, link string) map[string]uint16 {
+func (p *myProviderAdapter) healthcheck(ctx context.Context, options urltest.CheckOptions) map[string]uint16 {
 	result := make(map[string]uint16)
 	if p.checking.Swap(true) {
 		return result
@@ -432,9 +717,14 @@ func (p *myProviderAdapter) healthcheck(ctx context.Context, link string) map[st
 			continue
 		}
 		b.Go(tag, func() (any, error) {
-			ctx, cancel := context.WithTimeout(log.ContextWithNewID(context.Background()), C.TCPTimeout)
+			timeout := options.Timeout
+			if timeout == 0 {
+				timeout = C.TCPTimeout
+			}
+			ctx, cancel := context.WithTimeout(log.ContextWithNewID(context.Background()), timeout)
 			defer cancel()
-			t, err := urltest.URLTest(ctx, link, detour)
+			t, err := urltest.Check(ctx, detour, options)
+			p.healchcheckHistory.RecordCheckResult(tag, err == nil)
 			if err != nil {
 				p.logger.DebugContext(ctx, "outbound ", tag, " unavailable: ", err)
 				p.healchcheckHistory.DeleteURLTestHistory(tag)
@@ -448,6 +738,21 @@ func (p *myProviderAdapter) healthcheck(ctx context.Context, link string) map[st
 				result[tag] = t
 				resultAccess.Unlock()
 			}
+			if p.healthcheckUDP != nil && common.Contains(detour.Network(), N.NetworkUDP) {
+				udpOptions := *p.healthcheckUDP
+				udpOptions.Timeout = options.Timeout
+				t, err = udptest.Check(p.ctx, detour, udpOptions)
+				if err != nil {
+					p.logger.DebugContext(ctx, "outbound ", tag, " udp unavailable: ", err)
+					p.healchcheckHistory.DeleteUDPTestHistory(tag)
+				} else {
+					p.logger.DebugContext(ctx, "outbound ", tag, " udp available: ", t, "ms")
+					p.healchcheckHistory.StoreUDPTestHistory(tag, &urltest.History{
+						Time:  time.Now(),
+						Delay: t,
+					})
+				}
+			}
 			return nil, nil
 		})
 	}
@@ -477,6 +782,11 @@ func (p *myProviderAdapter) Close() error {
 	if p.healthCheckTicker != nil {
 		p.healthCheckTicker.Stop()
 	}
+	p.subscriptionAccess.Lock()
+	if p.subscriptionTimer != nil {
+		p.subscriptionTimer.Stop()
+	}
+	p.subscriptionAccess.Unlock()
 	p.cancel()
 	return nil
 }
@@ -0,0 +1,206 @@
+package provider
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/urltest"
+	"github.com/sagernet/sing-box/common/urltest/udptest"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOutboundsDelta(t *testing.T) {
+	t.Parallel()
+	p := &myProviderAdapter{tag: "sub"}
+	content := `
+proxies:
+  - {name: a, type: socks5, server: 127.0.0.1, port: 1080}
+  - {name: b, type: socks5, server: 127.0.0.1, port: 1081}
+  - {name: b, type: socks5, server: 127.0.0.1, port: 1082}
+`
+	first, err := p.parseOutbounds(context.Background(), nil, content)
+	require.NoError(t, err)
+	require.Len(t, first.outbounds, 3)
+	require.Len(t, first.Added, 3)
+	require.Empty(t, first.Removed)
+	require.False(t, first.Reordered)
+
+	unchanged, err := p.parseOutbounds(context.Background(), nil, content)
+	require.NoError(t, err)
+	require.Nil(t, unchanged)
+
+	second, err := p.parseOutbounds(context.Background(), nil, `
+proxies:
+  - {name: b, type: socks5, server: 127.0.0.1, port: 1082}
+  - {name: a, type: socks5, server: 127.0.0.2, port: 1080}
+  - {name: c, type: socks5, server: 127.0.0.1, port: 1083}
+`)
+	require.NoError(t, err)
+	require.Len(t, second.outbounds, 3)
+	require.Same(t, first.outbounds[2], second.outbounds[0])
+	require.Equal(t, []adapter.Outbound{second.outbounds[1], second.outbounds[2]}, second.Added)
+	require.Equal(t, []adapter.Outbound{first.outbounds[0], first.outbounds[1]}, second.Removed)
+	require.False(t, second.Reordered)
+
+	reordered, err := p.parseOutbounds(context.Background(), nil, `
+proxies:
+  - {name: c, type: socks5, server: 127.0.0.1, port: 1083}
+  - {name: a, type: socks5, server: 127.0.0.2, port: 1080}
+  - {name: b, type: socks5, server: 127.0.0.1, port: 1082}
+`)
+	require.NoError(t, err)
+	require.Empty(t, reordered.Added)
+	require.Empty(t, reordered.Removed)
+	require.True(t, reordered.Reordered)
+	require.True(t, reordered.Changed())
+	require.Equal(t, []adapter.Outbound{second.outbounds[2], second.outbounds[1], second.outbounds[0]}, reordered.outbounds)
+
+	removed, err := p.parseOutbounds(context.Background(), nil, `
+proxies:
+  - {name: c, type: socks5, server: 127.0.0.1, port: 1083}
+  - {name: b, type: socks5, server: 127.0.0.1, port: 1082}
+`)
+	require.NoError(t, err)
+	require.Len(t, removed.Removed, 1)
+	require.False(t, removed.Reordered)
+}
+
+type testRouter struct {
+	adapter.Router
+}
+
+func (r *testRouter) Outbounds() []adapter.Outbound {
+	return nil
+}
+
+type testGroupRouter struct {
+	adapter.Router
+	groups []adapter.Outbound
+}
+
+func (r *testGroupRouter) Outbounds() []adapter.Outbound {
+	return r.groups
+}
+
+func (r *testGroupRouter) OutboundProviders() []adapter.OutboundProvider {
+	return nil
+}
+
+func (r *testGroupRouter) AutoDetectInterface() bool {
+	return false
+}
+
+func (r *testGroupRouter) DefaultInterface() string {
+	return ""
+}
+
+func (r *testGroupRouter) AutoRedirectOutputMark() uint32 {
+	return 0
+}
+
+func (r *testGroupRouter) DefaultMark() uint32 {
+	return 0
+}
+
+// testFailingGroup fails to update its outbounds if fail is set.
+type testFailingGroup struct {
+	adapter.OutboundGroup
+	fail bool
+}
+
+func (g *testFailingGroup) Tag() string {
+	return "group"
+}
+
+func (g *testFailingGroup) UpdateOutbounds(tag string, delta adapter.OutboundProviderDelta) error {
+	if g.fail {
+		return E.New("update failed")
+	}
+	return nil
+}
+
+func TestUpdateProviderRollback(t *testing.T) {
+	t.Parallel()
+	group := &testFailingGroup{}
+	router := &testGroupRouter{groups: []adapter.Outbound{group}}
+	p := &myProviderAdapter{
+		tag:    "sub",
+		logger: log.NewNOPFactory().Logger(),
+	}
+	updated, err := p.updateProviderFromContent(context.Background(), router, `
+proxies:
+  - {name: a, type: socks5, server: 127.0.0.1, port: 1080}
+  - {name: x, type: unknown, server: 127.0.0.1, port: 1081}
+`)
+	require.NoError(t, err)
+	require.True(t, updated)
+	require.Len(t, p.outbounds, 1)
+	skipped := p.SkippedOutbounds()
+	require.Len(t, skipped, 1)
+
+	group.fail = true
+	_, err = p.updateProviderFromContent(context.Background(), router, `
+proxies:
+  - {name: a, type: socks5, server: 127.0.0.1, port: 1080}
+  - {name: b, type: socks5, server: 127.0.0.1, port: 1082}
+  - {name: x, type: unknown, server: 127.0.0.1, port: 1081}
+  - {name: y, type: unknown, server: 127.0.0.1, port: 1083}
+`)
+	require.Error(t, err)
+	require.Len(t, p.outbounds, 1)
+	require.Equal(t, skipped, p.SkippedOutbounds())
+}
+
+// testDeadlineOutbound fails every dial and records the deadline of UDP dials.
+type testDeadlineOutbound struct {
+	adapter.Outbound
+	udpDeadline time.Time
+}
+
+func (o *testDeadlineOutbound) Tag() string {
+	return "node"
+}
+
+func (o *testDeadlineOutbound) Network() []string {
+	return []string{N.NetworkTCP, N.NetworkUDP}
+}
+
+func (o *testDeadlineOutbound) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	if network == N.NetworkUDP {
+		o.udpDeadline, _ = ctx.Deadline()
+	}
+	return nil, E.New("unavailable")
+}
+
+func TestHealthcheckUDPTimeout(t *testing.T) {
+	t.Parallel()
+	detour := &testDeadlineOutbound{}
+	p := &myProviderAdapter{
+		ctx:                context.Background(),
+		router:             &testRouter{},
+		logger:             log.NewNOPFactory().Logger(),
+		outbounds:          []adapter.Outbound{detour},
+		outboundByTag:      map[string]adapter.Outbound{"node": detour},
+		healchcheckHistory: urltest.NewHistoryStorage(),
+		healthcheckUDP:     &udptest.Options{Type: C.UDPTestTypeDNS, Server: M.ParseSocksaddr("1.1.1.1:53")},
+	}
+	for _, timeout := range []time.Duration{2 * time.Second, 0} {
+		expected := timeout
+		if expected == 0 {
+			expected = C.DNSTimeout
+		}
+		start := time.Now()
+		p.healthcheck(context.Background(), urltest.CheckOptions{Timeout: timeout})
+		require.False(t, detour.udpDeadline.IsZero())
+		require.WithinDuration(t, start.Add(expected), detour.udpDeadline, time.Second, timeout)
+	}
+}
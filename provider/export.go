@@ -0,0 +1,889 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/transport/sip003"
+	"github.com/sagernet/sing-dns"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ExportURI serializes an outbound into the share link understood by the native URI parser.
+func ExportURI(outbound option.Outbound) (string, error) {
+	switch outbound.Type {
+	case C.TypeShadowsocks:
+		return exportSSURI(outbound.Tag, outbound.ShadowsocksOptions)
+	case C.TypeShadowsocksR:
+		return exportSSRURI(outbound.Tag, outbound.ShadowsocksROptions), nil
+	case C.TypeVMess:
+		return exportVMessURI(outbound.Tag, outbound.VMessOptions)
+	case C.TypeVLESS:
+		return exportVLESSURI(outbound.Tag, outbound.VLESSOptions)
+	case C.TypeTrojan:
+		return exportTrojanURI(outbound.Tag, outbound.TrojanOptions)
+	case C.TypeTUIC:
+		return exportTUICURI(outbound.Tag, outbound.TUICOptions), nil
+	case C.TypeHysteria:
+		return exportHysteriaURI(outbound.Tag, outbound.HysteriaOptions), nil
+	case C.TypeHysteria2:
+		return exportHysteria2URI(outbound.Tag, outbound.Hysteria2Options), nil
+	default:
+		return "", E.New("unsupported outbound type: ", outbound.Type)
+	}
+}
+
+// ExportClash serializes an outbound into a proxy entry of a Clash configuration.
+func ExportClash(outbound option.Outbound) (map[string]any, error) {
+	proxy := map[string]any{
+		"name": outbound.Tag,
+	}
+	var err error
+	switch outbound.Type {
+	case C.TypeShadowsocks:
+		err = exportSSClash(proxy, outbound.ShadowsocksOptions)
+	case C.TypeShadowsocksR:
+		exportSSRClash(proxy, outbound.ShadowsocksROptions)
+	case C.TypeHTTP:
+		exportHTTPClash(proxy, outbound.HTTPOptions)
+	case C.TypeSOCKS:
+		err = exportSOCKSClash(proxy, outbound.SocksOptions)
+	case C.TypeVMess:
+		err = exportVMessClash(proxy, outbound.VMessOptions)
+	case C.TypeVLESS:
+		err = exportVLESSClash(proxy, outbound.VLESSOptions)
+	case C.TypeTrojan:
+		err = exportTrojanClash(proxy, outbound.TrojanOptions)
+	case C.TypeTUIC:
+		exportTUICClash(proxy, outbound.TUICOptions)
+	case C.TypeHysteria:
+		exportHysteriaClash(proxy, outbound.HysteriaOptions)
+	case C.TypeHysteria2:
+		exportHysteria2Clash(proxy, outbound.Hysteria2Options)
+	case C.TypeWireGuard:
+		exportWireGuardClash(proxy, outbound.WireGuardOptions)
+	default:
+		err = E.New("unsupported outbound type: ", outbound.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return proxy, nil
+}
+
+func encodeURIComponent(content string) string {
+	return strings.ReplaceAll(url.QueryEscape(content), "+", "%20")
+}
+
+func joinHostPort(server string, port uint16) string {
+	return net.JoinHostPort(server, F.ToString(port))
+}
+
+func headerValue(headers option.HTTPHeader, name string) []string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return nil
+}
+
+type uriQuery []string
+
+func (q *uriQuery) add(key string, value string) {
+	if value != "" {
+		*q = append(*q, key+"="+encodeURIComponent(value))
+	}
+}
+
+func (q *uriQuery) addBool(key string, value bool) {
+	if value {
+		*q = append(*q, key+"=1")
+	}
+}
+
+func (q uriQuery) String() string {
+	if len(q) == 0 {
+		return ""
+	}
+	return "?" + strings.Join(q, "&")
+}
+
+func websocketPath(options option.V2RayWebsocketOptions) string {
+	if options.MaxEarlyData > 0 && options.EarlyDataHeaderName == "Sec-WebSocket-Protocol" {
+		return options.Path + "?ed=" + F.ToString(options.MaxEarlyData)
+	}
+	return options.Path
+}
+
+func exportSSURI(tag string, options option.ShadowsocksOutboundOptions) (string, error) {
+	userInfo := base64.RawURLEncoding.EncodeToString([]byte(options.Method + ":" + options.Password))
+	var query uriQuery
+	if options.Plugin != "" {
+		plugin := options.Plugin
+		if options.PluginOptions != "" {
+			plugin += ";" + options.PluginOptions
+		}
+		query.add("plugin", plugin)
+	}
+	link := "ss://" + userInfo + "@" + joinHostPort(options.Server, options.ServerPort)
+	if len(query) > 0 {
+		link += "/" + query.String()
+	}
+	return link + "#" + encodeURIComponent(tag), nil
+}
+
+func exportSSRURI(tag string, options option.ShadowsocksROutboundOptions) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	content := strings.Join([]string{
+		options.Server,
+		F.ToString(options.ServerPort),
+		options.Protocol,
+		options.Method,
+		options.Obfs,
+		encode([]byte(options.Password)),
+	}, ":")
+	var query uriQuery
+	if options.ObfsParam != "" {
+		query.add("obfsparam", encode([]byte(options.ObfsParam)))
+	}
+	if options.ProtocolParam != "" {
+		query.add("protoparam", encode([]byte(options.ProtocolParam)))
+	}
+	if tag != "" {
+		query.add("remarks", encode([]byte(tag)))
+	}
+	query.addBool("tfo", options.TCPFastOpen)
+	if len(query) > 0 {
+		content += "/" + query.String()
+	}
+	return "ssr://" + encode([]byte(content))
+}
+
+type vmessShareLink struct {
+	Version        string `json:"v"`
+	Remarks        string `json:"ps"`
+	Address        string `json:"add"`
+	Port           string `json:"port"`
+	ID             string `json:"id"`
+	AlterID        string `json:"aid"`
+	Security       string `json:"scy,omitempty"`
+	Network        string `json:"net,omitempty"`
+	Type           string `json:"type,omitempty"`
+	Host           string `json:"host,omitempty"`
+	Path           string `json:"path,omitempty"`
+	TLS            string `json:"tls"`
+	SNI            string `json:"sni,omitempty"`
+	ALPN           string `json:"alpn,omitempty"`
+	Fingerprint    string `json:"fp,omitempty"`
+	Insecure       string `json:"insecure,omitempty"`
+	PacketEncoding string `json:"packet_encoding,omitempty"`
+	TCPFastOpen    string `json:"tfo,omitempty"`
+}
+
+func exportVMessURI(tag string, options option.VMessOutboundOptions) (string, error) {
+	link := vmessShareLink{
+		Version:        "2",
+		Remarks:        tag,
+		Address:        options.Server,
+		Port:           F.ToString(options.ServerPort),
+		ID:             options.UUID,
+		AlterID:        F.ToString(options.AlterId),
+		Security:       options.Security,
+		PacketEncoding: options.PacketEncoding,
+	}
+	tlsOptions := options.TLS
+	if tlsOptions != nil && tlsOptions.Enabled {
+		link.TLS = "tls"
+		link.SNI = tlsOptions.ServerName
+		link.ALPN = strings.Join(tlsOptions.ALPN, ",")
+		if tlsOptions.UTLS != nil && tlsOptions.UTLS.Enabled {
+			link.Fingerprint = tlsOptions.UTLS.Fingerprint
+		}
+		if tlsOptions.Insecure {
+			link.Insecure = "1"
+		}
+	}
+	if transport := options.Transport; transport != nil {
+		switch transport.Type {
+		case "":
+			link.Network = "tcp"
+		case C.V2RayTransportTypeWebsocket:
+			link.Network = "ws"
+			link.Host = strings.Join(headerValue(transport.WebsocketOptions.Headers, "Host"), ",")
+			link.Path = websocketPath(transport.WebsocketOptions)
+		case C.V2RayTransportTypeHTTP:
+			if link.TLS != "" {
+				link.Network = "h2"
+			} else {
+				link.Network = "tcp"
+				link.Type = "http"
+			}
+			link.Host = strings.Join(transport.HTTPOptions.Host, ",")
+			link.Path = transport.HTTPOptions.Path
+		case C.V2RayTransportTypeGRPC:
+			link.Network = "grpc"
+			link.Path = transport.GRPCOptions.ServiceName
+		default:
+			return "", E.New("unsupported transport type in vmess link: ", transport.Type)
+		}
+	}
+	if options.TCPFastOpen {
+		link.TCPFastOpen = "1"
+	}
+	content, err := json.Marshal(link)
+	if err != nil {
+		return "", err
+	}
+	return "vmess://" + base64.StdEncoding.EncodeToString(content), nil
+}
+
+func exportTransportQuery(query *uriQuery, transport *option.V2RayTransportOptions) error {
+	if transport == nil {
+		return nil
+	}
+	switch transport.Type {
+	case "":
+		query.add("type", "tcp")
+	case C.V2RayTransportTypeWebsocket:
+		query.add("type", "ws")
+		query.add("path", websocketPath(transport.WebsocketOptions))
+		query.add("host", strings.Join(headerValue(transport.WebsocketOptions.Headers, "Host"), ","))
+	case C.V2RayTransportTypeHTTP:
+		query.add("type", "http")
+		query.add("path", transport.HTTPOptions.Path)
+		query.add("host", strings.Join(transport.HTTPOptions.Host, ","))
+	case C.V2RayTransportTypeGRPC:
+		query.add("type", "grpc")
+		query.add("serviceName", transport.GRPCOptions.ServiceName)
+	default:
+		return E.New("unsupported transport type in share link: ", transport.Type)
+	}
+	return nil
+}
+
+func exportTLSQuery(query *uriQuery, tlsOptions *option.OutboundTLSOptions) {
+	if tlsOptions == nil || !tlsOptions.Enabled {
+		return
+	}
+	if tlsOptions.Reality != nil && tlsOptions.Reality.Enabled {
+		query.add("security", "reality")
+		query.add("pbk", tlsOptions.Reality.PublicKey)
+		query.add("sid", tlsOptions.Reality.ShortID)
+	} else {
+		query.add("security", "tls")
+	}
+	query.add("sni", tlsOptions.ServerName)
+	query.add("alpn", strings.Join(tlsOptions.ALPN, ","))
+	if tlsOptions.UTLS != nil && tlsOptions.UTLS.Enabled {
+		query.add("fp", tlsOptions.UTLS.Fingerprint)
+	}
+	query.addBool("allowInsecure", tlsOptions.Insecure)
+}
+
+func exportVLESSURI(tag string, options option.VLESSOutboundOptions) (string, error) {
+	var query uriQuery
+	err := exportTransportQuery(&query, options.Transport)
+	if err != nil {
+		return "", err
+	}
+	exportTLSQuery(&query, options.TLS)
+	query.add("flow", options.Flow)
+	query.addBool("tfo", options.TCPFastOpen)
+	return "vless://" + encodeURIComponent(options.UUID) + "@" + joinHostPort(options.Server, options.ServerPort) + query.String() + "#" + encodeURIComponent(tag), nil
+}
+
+func exportTrojanURI(tag string, options option.TrojanOutboundOptions) (string, error) {
+	var query uriQuery
+	err := exportTransportQuery(&query, options.Transport)
+	if err != nil {
+		return "", err
+	}
+	exportTLSQuery(&query, options.TLS)
+	query.addBool("tfo", options.TCPFastOpen)
+	return "trojan://" + encodeURIComponent(options.Password) + "@" + joinHostPort(options.Server, options.ServerPort) + query.String() + "#" + encodeURIComponent(tag), nil
+}
+
+func exportTUICURI(tag string, options option.TUICOutboundOptions) string {
+	var query uriQuery
+	query.add("congestion_control", options.CongestionControl)
+	query.add("udp_relay_mode", options.UDPRelayMode)
+	query.addBool("udp_over_stream", options.UDPOverStream)
+	query.addBool("zero_rtt_handshake", options.ZeroRTTHandshake)
+	if tlsOptions := options.TLS; tlsOptions != nil {
+		query.add("sni", tlsOptions.ServerName)
+		query.add("alpn", strings.Join(tlsOptions.ALPN, ","))
+		query.addBool("allow_insecure", tlsOptions.Insecure)
+		query.addBool("disable_sni", tlsOptions.DisableSNI)
+	}
+	query.addBool("tfo", options.TCPFastOpen)
+	return "tuic://" + encodeURIComponent(options.UUID) + ":" + encodeURIComponent(options.Password) + "@" + joinHostPort(options.Server, options.ServerPort) + query.String() + "#" + encodeURIComponent(tag)
+}
+
+func exportHysteriaURI(tag string, options option.HysteriaOutboundOptions) string {
+	var query uriQuery
+	query.add("auth", options.AuthString)
+	if tlsOptions := options.TLS; tlsOptions != nil {
+		query.add("peer", tlsOptions.ServerName)
+		query.add("alpn", strings.Join(tlsOptions.ALPN, ","))
+		query.addBool("insecure", tlsOptions.Insecure)
+	}
+	query.add("up", options.Up)
+	if options.UpMbps > 0 {
+		query.add("upmbps", F.ToString(options.UpMbps))
+	}
+	query.add("down", options.Down)
+	if options.DownMbps > 0 {
+		query.add("downmbps", F.ToString(options.DownMbps))
+	}
+	if options.Obfs != "" {
+		query.add("obfs", "xplus")
+		query.add("obfsParam", options.Obfs)
+	}
+	query.addBool("tfo", options.TCPFastOpen)
+	return "hysteria://" + joinHostPort(options.Server, options.ServerPort) + query.String() + "#" + encodeURIComponent(tag)
+}
+
+func exportHysteria2URI(tag string, options option.Hysteria2OutboundOptions) string {
+	var query uriQuery
+	if tlsOptions := options.TLS; tlsOptions != nil {
+		query.add("sni", tlsOptions.ServerName)
+		query.addBool("insecure", tlsOptions.Insecure)
+	}
+	if options.Obfs != nil && options.Obfs.Type != "" {
+		query.add("obfs", options.Obfs.Type)
+		query.add("obfs-password", options.Obfs.Password)
+	}
+	if options.UpMbps > 0 {
+		query.add("up", F.ToString(options.UpMbps))
+	}
+	if options.DownMbps > 0 {
+		query.add("down", F.ToString(options.DownMbps))
+	}
+	link := "hysteria2://" + encodeURIComponent(options.Password) + "@" + joinHostPort(options.Server, options.ServerPort)
+	if len(query) > 0 {
+		link += "/" + query.String()
+	}
+	return link + "#" + encodeURIComponent(tag)
+}
+
+func exportClashServer(proxy map[string]any, proxyType string, options option.ServerOptions) {
+	proxy["type"] = proxyType
+	proxy["server"] = options.Server
+	proxy["port"] = int(options.ServerPort)
+}
+
+func exportClashDialer(proxy map[string]any, options option.DialerOptions) {
+	if options.TCPFastOpen {
+		proxy["tfo"] = true
+	}
+	if options.BindInterface != "" {
+		proxy["interface-name"] = options.BindInterface
+	}
+	if options.RoutingMark != 0 {
+		proxy["routing-mark"] = int(options.RoutingMark)
+	}
+	if options.Detour != "" {
+		proxy["dialer-proxy"] = options.Detour
+	}
+	switch dns.DomainStrategy(options.DomainStrategy) {
+	case dns.DomainStrategyUseIPv4:
+		proxy["ip-version"] = "ipv4"
+	case dns.DomainStrategyUseIPv6:
+		proxy["ip-version"] = "ipv6"
+	case dns.DomainStrategyPreferIPv4:
+		proxy["ip-version"] = "ipv4-prefer"
+	case dns.DomainStrategyPreferIPv6:
+		proxy["ip-version"] = "ipv6-prefer"
+	}
+}
+
+func exportClashTLS(proxy map[string]any, tlsOptions *option.OutboundTLSOptions, serverNameKey string) {
+	if tlsOptions == nil {
+		return
+	}
+	if tlsOptions.Enabled {
+		proxy["tls"] = true
+		if tlsOptions.Insecure {
+			proxy["skip-cert-verify"] = true
+		}
+	}
+	if tlsOptions.ServerName != "" {
+		proxy[serverNameKey] = tlsOptions.ServerName
+	}
+	if tlsOptions.DisableSNI {
+		proxy["disable-sni"] = true
+	}
+	if len(tlsOptions.ALPN) > 0 {
+		proxy["alpn"] = []string(tlsOptions.ALPN)
+	}
+	if tlsOptions.UTLS != nil && tlsOptions.UTLS.Enabled && tlsOptions.UTLS.Fingerprint != "" {
+		proxy["client-fingerprint"] = tlsOptions.UTLS.Fingerprint
+	}
+	if tlsOptions.Reality != nil && tlsOptions.Reality.Enabled {
+		realityOptions := map[string]any{
+			"public-key": tlsOptions.Reality.PublicKey,
+		}
+		if tlsOptions.Reality.ShortID != "" {
+			realityOptions["short-id"] = tlsOptions.Reality.ShortID
+		}
+		proxy["reality-opts"] = realityOptions
+	}
+}
+
+func exportClashSMux(proxy map[string]any, options *option.OutboundMultiplexOptions) {
+	if options == nil || !options.Enabled {
+		return
+	}
+	smux := map[string]any{
+		"enabled": true,
+	}
+	if options.Protocol != "" {
+		smux["protocol"] = options.Protocol
+	}
+	if options.MaxConnections > 0 {
+		smux["max-connections"] = options.MaxConnections
+	}
+	if options.MinStreams > 0 {
+		smux["min-streams"] = options.MinStreams
+	}
+	if options.MaxStreams > 0 {
+		smux["max-streams"] = options.MaxStreams
+	}
+	proxy["smux"] = smux
+}
+
+func exportClashHeaders(headers option.HTTPHeader) map[string]any {
+	result := make(map[string]any, len(headers))
+	for key, value := range headers {
+		if len(value) == 1 {
+			result[key] = value[0]
+		} else {
+			result[key] = []string(value)
+		}
+	}
+	return result
+}
+
+func exportClashTransport(proxy map[string]any, transport *option.V2RayTransportOptions, tlsEnabled bool) error {
+	if transport == nil {
+		return nil
+	}
+	switch transport.Type {
+	case "":
+		proxy["network"] = "tcp"
+	case C.V2RayTransportTypeWebsocket:
+		wsOptions := map[string]any{}
+		if transport.WebsocketOptions.Path != "" {
+			wsOptions["path"] = transport.WebsocketOptions.Path
+		}
+		if len(transport.WebsocketOptions.Headers) > 0 {
+			wsOptions["headers"] = exportClashHeaders(transport.WebsocketOptions.Headers)
+		}
+		if transport.WebsocketOptions.MaxEarlyData > 0 {
+			wsOptions["max-early-data"] = int(transport.WebsocketOptions.MaxEarlyData)
+		}
+		if transport.WebsocketOptions.EarlyDataHeaderName != "" {
+			wsOptions["early-data-header-name"] = transport.WebsocketOptions.EarlyDataHeaderName
+		}
+		proxy["network"] = "ws"
+		proxy["ws-opts"] = wsOptions
+	case C.V2RayTransportTypeHTTPUpgrade:
+		wsOptions := map[string]any{
+			"v2ray-http-upgrade": true,
+		}
+		if transport.HTTPUpgradeOptions.Path != "" {
+			wsOptions["path"] = transport.HTTPUpgradeOptions.Path
+		}
+		headers := exportClashHeaders(transport.HTTPUpgradeOptions.Headers)
+		if transport.HTTPUpgradeOptions.Host != "" {
+			headers["Host"] = transport.HTTPUpgradeOptions.Host
+		}
+		if len(headers) > 0 {
+			wsOptions["headers"] = headers
+		}
+		proxy["network"] = "ws"
+		proxy["ws-opts"] = wsOptions
+	case C.V2RayTransportTypeHTTP:
+		if tlsEnabled {
+			h2Options := map[string]any{}
+			if len(transport.HTTPOptions.Host) > 0 {
+				h2Options["host"] = []string(transport.HTTPOptions.Host)
+			}
+			if transport.HTTPOptions.Path != "" {
+				h2Options["path"] = transport.HTTPOptions.Path
+			}
+			proxy["network"] = "h2"
+			proxy["h2-opts"] = h2Options
+		} else {
+			httpOptions := map[string]any{}
+			if transport.HTTPOptions.Method != "" {
+				httpOptions["method"] = transport.HTTPOptions.Method
+			}
+			if transport.HTTPOptions.Path != "" {
+				httpOptions["path"] = []string{transport.HTTPOptions.Path}
+			}
+			if len(transport.HTTPOptions.Host) > 0 {
+				httpOptions["host"] = []string(transport.HTTPOptions.Host)
+			}
+			if len(transport.HTTPOptions.Headers) > 0 {
+				httpOptions["headers"] = exportClashHeaders(transport.HTTPOptions.Headers)
+			}
+			proxy["network"] = "http"
+			proxy["http-opts"] = httpOptions
+		}
+	case C.V2RayTransportTypeGRPC:
+		proxy["network"] = "grpc"
+		proxy["grpc-opts"] = map[string]any{
+			"grpc-service-name": transport.GRPCOptions.ServiceName,
+		}
+	default:
+		return E.New("unsupported transport type in clash proxy: ", transport.Type)
+	}
+	return nil
+}
+
+func exportSSClash(proxy map[string]any, options option.ShadowsocksOutboundOptions) error {
+	exportClashServer(proxy, "ss", options.ServerOptions)
+	proxy["cipher"] = options.Method
+	proxy["password"] = options.Password
+	if options.Plugin != "" {
+		pluginArgs, err := sip003.ParsePluginOptions(options.PluginOptions)
+		if err != nil {
+			return E.Cause(err, "parse plugin_opts")
+		}
+		pluginOptions := map[string]any{}
+		switch options.Plugin {
+		case "obfs-local":
+			proxy["plugin"] = "obfs"
+			for key, values := range pluginArgs {
+				switch key {
+				case "obfs":
+					pluginOptions["mode"] = values[len(values)-1]
+				case "obfs-host":
+					pluginOptions["host"] = values[len(values)-1]
+				default:
+					pluginOptions[key] = values[len(values)-1]
+				}
+			}
+		case "v2ray-plugin":
+			proxy["plugin"] = "v2ray-plugin"
+			for key, values := range pluginArgs {
+				switch key {
+				case "tls":
+					pluginOptions["tls"] = true
+				case "mux":
+				case "headers":
+					var headers map[string]any
+					if json.Unmarshal([]byte(values[len(values)-1]), &headers) == nil {
+						pluginOptions["headers"] = headers
+					}
+				default:
+					pluginOptions[key] = values[len(values)-1]
+				}
+			}
+		default:
+			return E.New("unsupported plugin: ", options.Plugin)
+		}
+		if len(pluginOptions) > 0 {
+			proxy["plugin-opts"] = pluginOptions
+		}
+	}
+	if options.UDPOverTCP != nil && options.UDPOverTCP.Enabled {
+		proxy["udp-over-tcp"] = true
+	}
+	exportClashSMux(proxy, options.Multiplex)
+	exportClashDialer(proxy, options.DialerOptions)
+	return nil
+}
+
+func exportSSRClash(proxy map[string]any, options option.ShadowsocksROutboundOptions) {
+	exportClashServer(proxy, "ssr", options.ServerOptions)
+	proxy["cipher"] = options.Method
+	proxy["password"] = options.Password
+	proxy["obfs"] = options.Obfs
+	proxy["protocol"] = options.Protocol
+	if options.ObfsParam != "" {
+		proxy["obfs-param"] = options.ObfsParam
+	}
+	if options.ProtocolParam != "" {
+		proxy["protocol-param"] = options.ProtocolParam
+	}
+	exportClashDialer(proxy, options.DialerOptions)
+}
+
+func exportHTTPClash(proxy map[string]any, options option.HTTPOutboundOptions) {
+	exportClashServer(proxy, "http", options.ServerOptions)
+	if options.Username != "" {
+		proxy["username"] = options.Username
+	}
+	if options.Password != "" {
+		proxy["password"] = options.Password
+	}
+	if len(options.Headers) > 0 {
+		proxy["headers"] = exportClashHeaders(options.Headers)
+	}
+	exportClashTLS(proxy, options.TLS, "sni")
+	exportClashDialer(proxy, options.DialerOptions)
+}
+
+func exportSOCKSClash(proxy map[string]any, options option.SocksOutboundOptions) error {
+	if options.Version != "" && options.Version != "5" {
+		return E.New("unsupported socks version: ", options.Version)
+	}
+	exportClashServer(proxy, "socks5", options.ServerOptions)
+	if options.Username != "" {
+		proxy["username"] = options.Username
+	}
+	if options.Password != "" {
+		proxy["password"] = options.Password
+	}
+	if options.UDPOverTCP != nil && options.UDPOverTCP.Enabled {
+		proxy["udp-over-tcp"] = true
+	}
+	exportClashDialer(proxy, options.DialerOptions)
+	return nil
+}
+
+func exportVMessClash(proxy map[string]any, options option.VMessOutboundOptions) error {
+	exportClashServer(proxy, "vmess", options.ServerOptions)
+	proxy["uuid"] = options.UUID
+	proxy["alterId"] = options.AlterId
+	proxy["cipher"] = options.Security
+	if proxy["cipher"] == "" {
+		proxy["cipher"] = "auto"
+	}
+	exportClashTLS(proxy, options.TLS, "servername")
+	err := exportClashTransport(proxy, options.Transport, options.TLS != nil && options.TLS.Enabled)
+	if err != nil {
+		return err
+	}
+	exportClashSMux(proxy, options.Multiplex)
+	exportClashDialer(proxy, options.DialerOptions)
+	return nil
+}
+
+func exportVLESSClash(proxy map[string]any, options option.VLESSOutboundOptions) error {
+	exportClashServer(proxy, "vless", options.ServerOptions)
+	proxy["uuid"] = options.UUID
+	if options.Flow != "" {
+		proxy["flow"] = options.Flow
+	}
+	exportClashTLS(proxy, options.TLS, "servername")
+	err := exportClashTransport(proxy, options.Transport, options.TLS != nil && options.TLS.Enabled)
+	if err != nil {
+		return err
+	}
+	exportClashSMux(proxy, options.Multiplex)
+	exportClashDialer(proxy, options.DialerOptions)
+	return nil
+}
+
+func exportTrojanClash(proxy map[string]any, options option.TrojanOutboundOptions) error {
+	exportClashServer(proxy, "trojan", options.ServerOptions)
+	proxy["password"] = options.Password
+	exportClashTLS(proxy, options.TLS, "sni")
+	err := exportClashTransport(proxy, options.Transport, true)
+	if err != nil {
+		return err
+	}
+	exportClashSMux(proxy, options.Multiplex)
+	exportClashDialer(proxy, options.DialerOptions)
+	return nil
+}
+
+func exportTUICClash(proxy map[string]any, options option.TUICOutboundOptions) {
+	exportClashServer(proxy, "tuic", options.ServerOptions)
+	proxy["uuid"] = options.UUID
+	proxy["password"] = options.Password
+	if options.CongestionControl != "" {
+		proxy["congestion-controller"] = options.CongestionControl
+	}
+	if options.UDPRelayMode != "" {
+		proxy["udp-relay-mode"] = options.UDPRelayMode
+	}
+	if options.UDPOverStream {
+		proxy["udp-over-stream"] = true
+	}
+	if options.ZeroRTTHandshake {
+		proxy["reduce-rtt"] = true
+	}
+	if options.Heartbeat > 0 {
+		proxy["heartbeat-interval"] = int(time.Duration(options.Heartbeat).Milliseconds())
+	}
+	exportClashTLS(proxy, options.TLS, "sni")
+	exportClashDialer(proxy, options.DialerOptions)
+}
+
+func exportClashCertificate(proxy map[string]any, tlsOptions *option.OutboundTLSOptions) {
+	if tlsOptions == nil {
+		return
+	}
+	if tlsOptions.CertificatePath != "" {
+		proxy["ca"] = tlsOptions.CertificatePath
+	}
+	if len(tlsOptions.Certificate) > 0 {
+		proxy["ca-str"] = []string(tlsOptions.Certificate)
+	}
+}
+
+func exportHysteriaClash(proxy map[string]any, options option.HysteriaOutboundOptions) {
+	exportClashServer(proxy, "hysteria", options.ServerOptions)
+	if options.Up != "" {
+		proxy["up"] = options.Up
+	} else if options.UpMbps > 0 {
+		proxy["up"] = options.UpMbps
+	}
+	if options.Down != "" {
+		proxy["down"] = options.Down
+	} else if options.DownMbps > 0 {
+		proxy["down"] = options.DownMbps
+	}
+	if options.AuthString != "" {
+		proxy["auth-str"] = options.AuthString
+	}
+	if options.Obfs != "" {
+		proxy["obfs"] = options.Obfs
+	}
+	if options.ReceiveWindowConn > 0 {
+		proxy["recv-window-conn"] = int(options.ReceiveWindowConn)
+	}
+	if options.ReceiveWindow > 0 {
+		proxy["recv-window"] = int(options.ReceiveWindow)
+	}
+	if options.DisableMTUDiscovery {
+		proxy["disable_mtu_discovery"] = true
+	}
+	exportClashTLS(proxy, options.TLS, "sni")
+	exportClashCertificate(proxy, options.TLS)
+	exportClashDialer(proxy, options.DialerOptions)
+}
+
+func exportHysteria2Clash(proxy map[string]any, options option.Hysteria2OutboundOptions) {
+	exportClashServer(proxy, "hysteria2", options.ServerOptions)
+	proxy["password"] = options.Password
+	if options.UpMbps > 0 {
+		proxy["up"] = options.UpMbps
+	}
+	if options.DownMbps > 0 {
+		proxy["down"] = options.DownMbps
+	}
+	if options.Obfs != nil && options.Obfs.Type != "" {
+		proxy["obfs"] = options.Obfs.Type
+		proxy["obfs-password"] = options.Obfs.Password
+	}
+	exportClashTLS(proxy, options.TLS, "sni")
+	exportClashCertificate(proxy, options.TLS)
+	exportClashDialer(proxy, options.DialerOptions)
+}
+
+func exportWireGuardClash(proxy map[string]any, options option.WireGuardOutboundOptions) {
+	exportClashServer(proxy, "wireguard", options.ServerOptions)
+	for _, prefix := range options.LocalAddress {
+		if prefix.Addr().Is4() {
+			if _, loaded := proxy["ip"]; !loaded {
+				proxy["ip"] = prefix.String()
+			}
+		} else if _, loaded := proxy["ipv6"]; !loaded {
+			proxy["ipv6"] = prefix.String()
+		}
+	}
+	proxy["private-key"] = options.PrivateKey
+	proxy["public-key"] = options.PeerPublicKey
+	if options.PreSharedKey != "" {
+		proxy["pre-shared-key"] = options.PreSharedKey
+	}
+	if len(options.Reserved) > 0 {
+		reserved := make([]int, 0, len(options.Reserved))
+		for _, item := range options.Reserved {
+			reserved = append(reserved, int(item))
+		}
+		proxy["reserved"] = reserved
+	}
+	if options.MTU > 0 {
+		proxy["mtu"] = int(options.MTU)
+	}
+	if len(options.Peers) > 0 {
+		peers := make([]map[string]any, 0, len(options.Peers))
+		for _, peer := range options.Peers {
+			peerMap := map[string]any{
+				"server":     peer.Server,
+				"port":       int(peer.ServerPort),
+				"public-key": peer.PublicKey,
+			}
+			if peer.PreSharedKey != "" {
+				peerMap["pre-shared-key"] = peer.PreSharedKey
+			}
+			if len(peer.AllowedIPs) > 0 {
+				peerMap["allowed_ips"] = []string(peer.AllowedIPs)
+			}
+			peers = append(peers, peerMap)
+		}
+		proxy["peers"] = peers
+	}
+	exportClashDialer(proxy, options.DialerOptions)
+}
+
+// ExportContent serializes outbounds into a subscription body of the given format,
+// which is either "uri" (one share link per line) or "clash" (a Clash proxies section).
+// Outbounds that cannot be represented in the format are reported as skipped.
+func ExportContent(outbounds []option.Outbound, format string) ([]byte, []adapter.SkippedOutbound, error) {
+	var skipped []adapter.SkippedOutbound
+	skip := func(index int, outbound option.Outbound, err error) {
+		skipped = append(skipped, adapter.SkippedOutbound{
+			Index:  index,
+			Tag:    outbound.Tag,
+			Type:   outbound.Type,
+			Reason: err.Error(),
+		})
+	}
+	switch format {
+	case "", "uri":
+		var links []string
+		for i, outbound := range outbounds {
+			link, err := ExportURI(outbound)
+			if err != nil {
+				skip(i, outbound, err)
+				continue
+			}
+			links = append(links, link)
+		}
+		if len(links) == 0 {
+			return nil, skipped, nil
+		}
+		return []byte(strings.Join(links, "\n") + "\n"), skipped, nil
+	case "clash":
+		clashConfig := ClashConfig{
+			Proxies: []map[string]any{},
+		}
+		for i, outbound := range outbounds {
+			proxy, err := ExportClash(outbound)
+			if err != nil {
+				skip(i, outbound, err)
+				continue
+			}
+			clashConfig.Proxies = append(clashConfig.Proxies, proxy)
+		}
+		buffer := new(bytes.Buffer)
+		encoder := yaml.NewEncoder(buffer)
+		encoder.SetIndent(2)
+		err := encoder.Encode(clashConfig)
+		if err != nil {
+			return nil, skipped, err
+		}
+		return buffer.Bytes(), skipped, nil
+	default:
+		return nil, nil, E.New("unknown export format: ", format)
+	}
+}
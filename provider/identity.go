@@ -0,0 +1,75 @@
+package provider
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	F "github.com/sagernet/sing/common/format"
+)
+
+// outboundIdentity identifies the physical node behind outbound by protocol,
+// server address and a hash of credentials, regardless of its tag. An empty
+// string is returned for outbounds without a server.
+func outboundIdentity(outbound option.Outbound) string {
+	rawOptions, err := outbound.RawOptions()
+	if err != nil {
+		return ""
+	}
+	serverWrapper, isWrapper := rawOptions.(option.ServerOptionsWrapper)
+	if !isWrapper {
+		return ""
+	}
+	server := serverWrapper.TakeServerOptions()
+	if server.Server == "" {
+		return ""
+	}
+	var credentials []string
+	switch outbound.Type {
+	case C.TypeSOCKS:
+		credentials = []string{outbound.SocksOptions.Username, outbound.SocksOptions.Password}
+	case C.TypeHTTP:
+		credentials = []string{outbound.HTTPOptions.Username, outbound.HTTPOptions.Password}
+	case C.TypeShadowsocks:
+		credentials = []string{outbound.ShadowsocksOptions.Method, outbound.ShadowsocksOptions.Password}
+	case C.TypeShadowsocksR:
+		options := outbound.ShadowsocksROptions
+		credentials = []string{options.Method, options.Password, options.Protocol, options.ProtocolParam, options.Obfs, options.ObfsParam}
+	case C.TypeVMess:
+		credentials = []string{outbound.VMessOptions.UUID}
+	case C.TypeVLESS:
+		credentials = []string{outbound.VLESSOptions.UUID}
+	case C.TypeTrojan:
+		credentials = []string{outbound.TrojanOptions.Password}
+	case C.TypeTUIC:
+		credentials = []string{outbound.TUICOptions.UUID, outbound.TUICOptions.Password}
+	case C.TypeHysteria:
+		credentials = []string{outbound.HysteriaOptions.AuthString}
+	case C.TypeHysteria2:
+		credentials = []string{outbound.Hysteria2Options.Password}
+	case C.TypeShadowTLS:
+		credentials = []string{outbound.ShadowTLSOptions.Password}
+	case C.TypeSSH:
+		credentials = []string{outbound.SSHOptions.User, outbound.SSHOptions.Password, outbound.SSHOptions.PrivateKeyPath}
+	case C.TypeWireGuard:
+		credentials = []string{outbound.WireGuardOptions.PrivateKey, outbound.WireGuardOptions.PeerPublicKey}
+	}
+	hash := sha256.Sum256([]byte(strings.Join(credentials, "\x00")))
+	return F.ToString(outbound.Type, "/", joinHostPort(server.Server, server.ServerPort), "/", hex.EncodeToString(hash[:8]))
+}
+
+func (a *myProviderAdapter) OutboundIdentity(tag string) (string, bool) {
+	outbound, loaded := a.outboundByTag[tag]
+	if !loaded {
+		return "", false
+	}
+	withOptions, isWithOptions := outbound.(adapter.OutboundWithOptions)
+	if !isWithOptions {
+		return "", false
+	}
+	identity := outboundIdentity(withOptions.Options())
+	return identity, identity != ""
+}
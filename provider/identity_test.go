@@ -0,0 +1,28 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutboundIdentity(t *testing.T) {
+	t.Parallel()
+	outbounds, _, err := newNativeURIParser(`trojan://password@example.com:443#node
+trojan://password@example.com:443?sni=example.org#node%5B1%5D
+trojan://another@example.com:443#node
+trojan://password@example.com:8443#node
+vless://password@example.com:443#node`)
+	require.NoError(t, err)
+	require.Len(t, outbounds, 5)
+	identities := make([]string, 0, len(outbounds))
+	for _, outbound := range outbounds {
+		identity := outboundIdentity(outbound)
+		require.NotEmpty(t, identity)
+		identities = append(identities, identity)
+	}
+	require.Equal(t, identities[0], identities[1])
+	require.NotEqual(t, identities[0], identities[2])
+	require.NotEqual(t, identities[0], identities[3])
+	require.NotEqual(t, identities[0], identities[4])
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"os"
 	"runtime"
-	"time"
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/urltest"
@@ -15,8 +14,6 @@ import (
 	"github.com/sagernet/sing/common/rw"
 	"github.com/sagernet/sing/service"
 	"github.com/sagernet/sing/service/pause"
-
-	R "github.com/dlclark/regexp2"
 )
 
 var (
@@ -30,56 +27,31 @@ type LocalProvider struct {
 
 func NewLocalProvider(ctx context.Context, router adapter.Router, logger log.ContextLogger, options option.OutboundProvider, path string) (*LocalProvider, error) {
 	localOptions := options.LocalOptions
-	interval := time.Duration(localOptions.HealthcheckInterval)
-	healthcheckUrl := localOptions.HealthcheckUrl
-	if healthcheckUrl == "" {
-		healthcheckUrl = "https://www.gstatic.com/generate_204"
-	}
-	if interval == 0 {
-		interval = C.DefaultURLTestInterval
-	}
 	ctx, cancel := context.WithCancel(ctx)
 	provider := &LocalProvider{
 		myProviderAdapter: myProviderAdapter{
-			ctx:                 ctx,
-			cancel:              cancel,
-			router:              router,
-			logger:              logger,
-			tag:                 options.Tag,
-			path:                path,
-			enableHealthcheck:   localOptions.EnableHealthcheck,
-			healthcheckUrl:      localOptions.HealthcheckUrl,
-			healthcheckInterval: interval,
-			outboundOverride:    options.OutboundOverride,
-			types:               options.Types,
-			ports:               make(map[int]bool),
-			providerType:        C.ProviderTypeLocal,
-			close:               make(chan struct{}),
-			pauseManager:        service.FromContext[pause.Manager](ctx),
-			subInfo:             SubInfo{},
-			outbounds:           []adapter.Outbound{},
-			outboundByTag:       make(map[string]adapter.Outbound),
+			ctx:              ctx,
+			cancel:           cancel,
+			router:           router,
+			logger:           logger,
+			tag:              options.Tag,
+			path:             path,
+			outboundOverride: options.OutboundOverride,
+			providerType:     C.ProviderTypeLocal,
+			close:            make(chan struct{}),
+			pauseManager:     service.FromContext[pause.Manager](ctx),
+			subInfo:          SubInfo{},
+			outbounds:        []adapter.Outbound{},
+			outboundByTag:    make(map[string]adapter.Outbound),
 		},
 	}
-	if len(options.Includes) > 0 {
-		includes := make([]*R.Regexp, 0, len(options.Includes))
-		for i, include := range options.Includes {
-			regex, err := R.Compile(include, R.IgnoreCase)
-			if err != nil {
-				return nil, E.Cause(err, "parse includes[", i, "]")
-			}
-			includes = append(includes, regex)
-		}
-		provider.includes = includes
+	if err := provider.initOptions(options); err != nil {
+		return nil, err
 	}
-	if options.Excludes != "" {
-		regex, err := R.Compile(options.Excludes, R.IgnoreCase)
-		if err != nil {
-			return nil, E.Cause(err, "parse excludes")
-		}
-		provider.excludes = regex
+	if err := provider.initHealthcheck(localOptions.HealthcheckOptions); err != nil {
+		return nil, err
 	}
-	if err := provider.firstStart(options.Ports); err != nil {
+	if err := provider.firstStart(); err != nil {
 		return nil, err
 	}
 	return provider, nil
@@ -94,6 +66,8 @@ func (p *LocalProvider) Start() error {
 		history = urltest.NewHistoryStorage()
 	}
 	p.healchcheckHistory = history
+	p.saveMetadata(adapter.SavedProviderMetadata{})
+	p.checkSubscription()
 	return nil
 }
 
@@ -132,6 +106,8 @@ func (p *LocalProvider) UpdateProvider(ctx context.Context, router adapter.Route
 
 	p.subInfo = info
 	p.lastUpdated = fileModeTime
+	p.checkSubscription()
+	p.saveMetadata(adapter.SavedProviderMetadata{})
 	p.logger.InfoContext(ctx, "update outbound provider ", p.tag, " success")
 
 	return nil
@@ -8,24 +8,29 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/sagernet/sing-box/adapter"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
 	E "github.com/sagernet/sing/common/exceptions"
 )
 
 var (
-	ssParser        = regexp.MustCompile(`^(.*?)@(.*?):(\d+)(?:(?:\/|\?|\/\?)(.*?))?(?:#(.*?))$`)
-	ssrParser       = regexp.MustCompile(`^(.*?):(.*?):(.*?):(.*?):(.*?):(.*?)(?:(?:\/|\?|\/\?)(.*?))?$`)
-	tuicParser      = regexp.MustCompile(`^(.*?):(.*?)@(.*?):(\d+)(?:(?:\/|\?|\/\?)(.*?))?(?:#(.*?))$`)
-	vmessParser     = regexp.MustCompile(`^(.*?)@(.*?):(\d+)(?:(?:\/|\?|\/\?)(.*?))?(?:#(.*?))$`)
-	vlessParser     = regexp.MustCompile(`^(.*?)@(.*?):(\d+)(?:(?:\/|\?|\/\?)(.*?))?(?:#(.*?))$`)
-	trojanParser    = regexp.MustCompile(`^(.*?)@(.*?):(\d+)(?:(?:\/|\?|\/\?)(.*?))?(?:#(.*?))$`)
-	hysteriaParser  = regexp.MustCompile(`^(.*?):(\d+)(?:(?:\/|\?|\/\?)(.*?))?(?:#(.*?))?$`)
-	hysteria2Parser = regexp.MustCompile(`^(.+?)@(.+?)(?::(\d+))?(?:(?:\/|\?|\/\?)(.*?))?(?:#(.*?))?$`)
+	ssParser        = regexp.MustCompile(`^(.*?)@(.*?):(\d+)(?:(?:\/\?|\?|\/)(.*?))?(?:#(.*?))$`)
+	ssrParser       = regexp.MustCompile(`^(.*?):(.*?):(.*?):(.*?):(.*?):(.*?)(?:(?:\/\?|\?|\/)(.*?))?$`)
+	tuicParser      = regexp.MustCompile(`^(.*?):(.*?)@(.*?):(\d+)(?:(?:\/\?|\?|\/)(.*?))?(?:#(.*?))$`)
+	vmessParser     = regexp.MustCompile(`^(.*?)@(.*?):(\d+)(?:(?:\/\?|\?|\/)(.*?))?(?:#(.*?))$`)
+	vlessParser     = regexp.MustCompile(`^(.*?)@(.*?):(\d+)(?:(?:\/\?|\?|\/)(.*?))?(?:#(.*?))$`)
+	trojanParser    = regexp.MustCompile(`^(.*?)@(.*?):(\d+)(?:(?:\/\?|\?|\/)(.*?))?(?:#(.*?))$`)
+	hysteriaParser  = regexp.MustCompile(`^(.*?):(\d+)(?:(?:\/\?|\?|\/)(.*?))?(?:#(.*?))?$`)
+	hysteria2Parser = regexp.MustCompile(`^(.+?)@(.+?)(?::(\d+))?(?:(?:\/\?|\?|\/)(.*?))?(?:#(.*?))?$`)
 )
 
-func newNativeURIParser(content string) ([]option.Outbound, error) {
-	var outbounds []option.Outbound
+func newNativeURIParser(content string) ([]option.Outbound, []adapter.SkippedOutbound, error) {
+	var (
+		outbounds []option.Outbound
+		skipped   []adapter.SkippedOutbound
+		index     int
+	)
 	for _, proxyRaw := range strings.Split(content, "\n") {
 		proxyRaw = strings.TrimSpace(proxyRaw)
 		if !strings.Contains(proxyRaw, "://") {
@@ -56,13 +61,21 @@ func newNativeURIParser(content string) ([]option.Outbound, error) {
 		case "hy2", "hysteria2":
 			outbound, err = newHysteria2NativeParser(parsedProxy)
 		default:
-			continue
+			err = E.New("unsupported protocol: ", protocol)
 		}
-		if err == nil {
+		if err != nil {
+			skipped = append(skipped, adapter.SkippedOutbound{
+				Index:  index,
+				Tag:    outbound.Tag,
+				Type:   protocol,
+				Reason: err.Error(),
+			})
+		} else {
 			outbounds = append(outbounds, outbound)
 		}
+		index++
 	}
-	return outbounds, nil
+	return outbounds, skipped, nil
 }
 
 func stringToUint16(content string) uint16 {
@@ -85,6 +98,14 @@ func decodeURIComponent(content string) string {
 	return result
 }
 
+func unescapeQueryValue(content string) string {
+	result, err := url.QueryUnescape(content)
+	if err != nil {
+		return content
+	}
+	return result
+}
+
 func splitKeyValueWithEqual(content string) (string, string) {
 	if !strings.Contains(content, "=") {
 		return trimBlank(content), "1"
@@ -113,18 +134,26 @@ func newSSNativeParser(content string) (option.Outbound, error) {
 	options := option.ShadowsocksOutboundOptions{}
 	options.Server = result[2]
 	options.ServerPort = stringToUint16(result[3])
-	cryptoArr := strings.Split(decodeBase64Safe(result[1]), ":")
-	options.Method, options.Password = cryptoArr[0], cryptoArr[1]
+	method, password, found := strings.Cut(decodeBase64Safe(decodeURIComponent(result[1])), ":")
+	if !found {
+		return outbound, E.New("invalid ss user info")
+	}
+	options.Method, options.Password = method, password
 	plugin := ""
 	pluginArr := []string{}
 	for _, addon := range strings.Split(decodeURIComponent(result[4]), "&") {
-		key, value := splitKeyValueWithEqual(addon)
+		key, _ := splitKeyValueWithEqual(addon)
 		switch key {
 		case "plugin":
-			if strings.Contains(value, "obfs") {
-				plugin = "obfs-local"
-			} else if strings.Contains(value, "obfs") {
+			_, value, _ := strings.Cut(addon, "=")
+			pluginName, pluginOptions, _ := strings.Cut(value, ";")
+			if strings.Contains(pluginName, "obfs") {
 				plugin = "obfs-local"
+			} else if strings.Contains(pluginName, "v2ray") {
+				plugin = "v2ray-plugin"
+			}
+			if pluginOptions != "" {
+				pluginArr = append(pluginArr, pluginOptions)
 			}
 		default:
 			pluginArr = append(pluginArr, addon)
@@ -188,13 +217,14 @@ func newTuicNativeParser(content string) (option.Outbound, error) {
 		UTLS:    &option.OutboundUTLSOptions{},
 		Reality: &option.OutboundRealityOptions{},
 	}
-	options.UUID = result[1]
-	options.Password = result[2]
+	options.UUID = decodeURIComponent(result[1])
+	options.Password = decodeURIComponent(result[2])
 	options.Server = result[3]
 	TLSOptions.ServerName = result[3]
 	options.ServerPort = stringToUint16(result[4])
 	for _, addon := range strings.Split(result[5], "&") {
 		key, value := splitKeyValueWithEqual(addon)
+		value = unescapeQueryValue(value)
 		switch key {
 		case "congestion_control":
 			if value != "cubic" {
@@ -312,6 +342,8 @@ func newVMessNativeParser(content string) (option.Outbound, error) {
 		case "fp":
 			TLSOptions.UTLS.Enabled = true
 			TLSOptions.UTLS.Fingerprint = value
+		case "alpn":
+			TLSOptions.ALPN = strings.Split(value, ",")
 		case "net":
 			Transport := option.V2RayTransportOptions{
 				Type: "",
@@ -350,7 +382,7 @@ func newVMessNativeParser(content string) (option.Outbound, error) {
 				Transport.Type = C.V2RayTransportTypeHTTP
 				TLSOptions.Enabled = true
 				if host, exists := proxy["host"]; exists && host != "" {
-					Transport.HTTPOptions.Host = []string{host}
+					Transport.HTTPOptions.Host = strings.Split(host, ",")
 				}
 				if path, exists := proxy["path"]; exists && path != "" {
 					Transport.HTTPOptions.Path = path
@@ -363,7 +395,7 @@ func newVMessNativeParser(content string) (option.Outbound, error) {
 							Transport.HTTPOptions.Method = method
 						}
 						if host, exists := proxy["host"]; exists && host != "" {
-							Transport.HTTPOptions.Host = []string{host}
+							Transport.HTTPOptions.Host = strings.Split(host, ",")
 						}
 						if path, exists := proxy["path"]; exists && path != "" {
 							Transport.HTTPOptions.Path = path
@@ -384,17 +416,24 @@ func newVMessNativeParser(content string) (option.Outbound, error) {
 				}
 			case "grpc":
 				Transport.Type = C.V2RayTransportTypeGRPC
-				if host, exists := proxy["host"]; exists && host != "" {
+				if path, exists := proxy["path"]; exists && path != "" {
+					Transport.GRPCOptions.ServiceName = path
+				} else if host, exists := proxy["host"]; exists && host != "" {
 					Transport.GRPCOptions.ServiceName = host
 				}
 			}
-			options.Transport = &Transport
+			if Transport.Type != "" {
+				options.Transport = &Transport
+			}
 		case "tfo", "tcp-fast-open", "tcp_fast_open":
 			if value == "1" || value == "true" {
 				options.TCPFastOpen = true
 			}
 		}
 	}
+	if sni, exists := proxy["sni"]; exists && sni != "" {
+		TLSOptions.ServerName = sni
+	}
 	options.TLS = &TLSOptions
 	outbound.VMessOptions = options
 	return outbound, nil
@@ -422,12 +461,7 @@ func newVLESSNativeParser(content string) (option.Outbound, error) {
 	proxy := map[string]string{}
 	for _, addon := range strings.Split(result[4], "&") {
 		key, value := splitKeyValueWithEqual(addon)
-		switch key {
-		case "key", "alpn", "seed", "path", "host":
-			proxy[key] = decodeURIComponent(value)
-		default:
-			proxy[key] = value
-		}
+		proxy[key] = unescapeQueryValue(value)
 	}
 	for key, value := range proxy {
 		switch key {
@@ -483,7 +517,9 @@ func newVLESSNativeParser(content string) (option.Outbound, error) {
 					Transport.GRPCOptions.ServiceName = serviceName
 				}
 			}
-			options.Transport = &Transport
+			if Transport.Type != "" {
+				options.Transport = &Transport
+			}
 		case "security":
 			if value == "tls" {
 				TLSOptions.Enabled = true
@@ -491,11 +527,11 @@ func newVLESSNativeParser(content string) (option.Outbound, error) {
 				TLSOptions.Enabled = true
 				TLSOptions.Reality.Enabled = true
 			}
-		case "insecure", "skip-cert-verify":
+		case "insecure", "allowInsecure", "skip-cert-verify":
 			if value == "1" || value == "true" {
 				TLSOptions.Insecure = true
 			}
-		case "serviceName", "sni", "peer":
+		case "sni", "peer":
 			TLSOptions.ServerName = value
 		case "alpn":
 			TLSOptions.ALPN = strings.Split(value, ",")
@@ -552,7 +588,7 @@ func newTrojanNativeParser(content string) (option.Outbound, error) {
 			if value == "1" || value == "true" {
 				TLSOptions.Insecure = true
 			}
-		case "serviceName", "sni", "peer":
+		case "sni", "peer":
 			TLSOptions.ServerName = value
 		case "alpn":
 			TLSOptions.ALPN = strings.Split(value, ",")
@@ -597,11 +633,15 @@ func newTrojanNativeParser(content string) (option.Outbound, error) {
 				}
 			case "grpc":
 				Transport.Type = C.V2RayTransportTypeGRPC
-				if serviceName, exists := proxy["grpc-service-name"]; exists && serviceName != "" {
+				if serviceName, exists := proxy["serviceName"]; exists && serviceName != "" {
+					Transport.GRPCOptions.ServiceName = serviceName
+				} else if serviceName, exists := proxy["grpc-service-name"]; exists && serviceName != "" {
 					Transport.GRPCOptions.ServiceName = serviceName
 				}
 			}
-			options.Transport = &Transport
+			if Transport.Type != "" {
+				options.Transport = &Transport
+			}
 		case "tfo", "tcp-fast-open", "tcp_fast_open":
 			if value == "1" || value == "true" {
 				options.TCPFastOpen = true
@@ -621,7 +661,7 @@ func newHysteriaNativeParser(content string) (option.Outbound, error) {
 	if len(result) == 0 {
 		return outbound, E.New("invalid hysteria uri")
 	}
-	outbound.Tag = result[4]
+	outbound.Tag = decodeURIComponent(result[4])
 	options := option.HysteriaOutboundOptions{}
 	TLSOptions := option.OutboundTLSOptions{
 		Enabled: true,
@@ -634,6 +674,7 @@ func newHysteriaNativeParser(content string) (option.Outbound, error) {
 	options.ServerPort = stringToUint16(result[2])
 	for _, addon := range strings.Split(result[3], "&") {
 		key, value := splitKeyValueWithEqual(addon)
+		value = unescapeQueryValue(value)
 		switch key {
 		case "auth":
 			options.AuthString = value
@@ -647,14 +688,18 @@ func newHysteriaNativeParser(content string) (option.Outbound, error) {
 			TLSOptions.Certificate = strings.Split(value, "\n")
 		case "up":
 			options.Up = value
-		case "up_mbps":
+		case "up_mbps", "upmbps":
 			options.UpMbps, _ = strconv.Atoi(value)
 		case "down":
 			options.Down = value
-		case "down_mbps":
+		case "down_mbps", "downmbps":
 			options.DownMbps, _ = strconv.Atoi(value)
-		case "obfs", "obfsParam":
+		case "obfsParam":
 			options.Obfs = value
+		case "obfs":
+			if value != "xplus" {
+				options.Obfs = value
+			}
 		case "insecure", "skip-cert-verify":
 			if value == "1" || value == "true" {
 				TLSOptions.Insecure = true
@@ -678,8 +723,9 @@ func newHysteria2NativeParser(content string) (option.Outbound, error) {
 	if len(result) == 0 {
 		return outbound, E.New("invalid hysteria2 uri")
 	}
-	outbound.Tag = result[5]
+	outbound.Tag = decodeURIComponent(result[5])
 	options := option.Hysteria2OutboundOptions{}
+	obfsOptions := option.Hysteria2Obfs{}
 	TLSOptions := option.OutboundTLSOptions{
 		Enabled: true,
 		ECH:     &option.OutboundECHOptions{},
@@ -689,15 +735,16 @@ func newHysteria2NativeParser(content string) (option.Outbound, error) {
 	options.ServerPort = uint16(443)
 	options.Server = result[2]
 	TLSOptions.ServerName = result[2]
-	options.Password = result[1]
+	options.Password = decodeURIComponent(result[1])
 	if strings.Contains(result[1], ":") {
-		options.Password = strings.Split(result[1], ":")[1]
+		options.Password = decodeURIComponent(strings.Split(result[1], ":")[1])
 	}
 	if result[3] != "" {
 		options.ServerPort = stringToUint16(result[3])
 	}
-	for _, addon := range strings.Split(result[4], "\n") {
+	for _, addon := range strings.Split(result[4], "&") {
 		key, value := splitKeyValueWithEqual(addon)
+		value = unescapeQueryValue(value)
 		switch key {
 		case "up":
 			options.UpMbps, _ = strconv.Atoi(value)
@@ -705,16 +752,21 @@ func newHysteria2NativeParser(content string) (option.Outbound, error) {
 			options.DownMbps, _ = strconv.Atoi(value)
 		case "obfs":
 			if value == "salamander" {
-				options.Obfs.Type = "salamander"
+				obfsOptions.Type = "salamander"
 			}
 		case "obfs-password":
-			options.Obfs.Password = value
+			obfsOptions.Password = value
+		case "sni", "peer":
+			TLSOptions.ServerName = value
 		case "insecure", "skip-cert-verify":
 			if value == "1" || value == "true" {
 				TLSOptions.Insecure = true
 			}
 		}
 	}
+	if obfsOptions.Type != "" {
+		options.Obfs = &obfsOptions
+	}
 	options.TLS = &TLSOptions
 	outbound.Hysteria2Options = options
 	return outbound, nil
@@ -4,54 +4,147 @@ import (
 	"reflect"
 	"strings"
 
+	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/betterjson"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
-	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+
+	"gopkg.in/yaml.v3"
 )
 
-func (p *myProviderAdapter) newParser(content string) ([]option.Outbound, error) {
-	var outbounds []option.Outbound
-	var err error
-	switch true {
-	case strings.Contains(content, "outbounds"):
+// ParseContent parses subscription content the same way an outbound provider
+// created with options does, without creating any outbound.
+func ParseContent(content string, options option.OutboundProvider) ([]option.Outbound, []adapter.SkippedOutbound, error) {
+	p := &myProviderAdapter{
+		tag:              options.Tag,
+		outboundOverride: options.OutboundOverride,
+	}
+	if err := p.initOptions(options); err != nil {
+		return nil, nil, err
+	}
+	content = decodeBase64Safe(trimBlank(content))
+	firstLine, others := getFirstLine(content)
+	if _, ok := parseSubInfo(firstLine); ok {
+		content = decodeBase64Safe(others)
+	}
+	outbounds, skipped, err := p.newParser(content)
+	if err != nil {
+		return nil, nil, err
+	}
+	outbounds, filtered := p.filterOutbounds(outbounds)
+	return outbounds, append(skipped, filtered...), nil
+}
+
+func (p *myProviderAdapter) initFormat(format string) error {
+	switch format {
+	case "", C.ProviderFormatAuto:
+		p.format = C.ProviderFormatAuto
+	case C.ProviderFormatSingBox, C.ProviderFormatClash, C.ProviderFormatURI, C.ProviderFormatSIP008:
+		p.format = format
+	default:
+		return E.New("unknown provider format: ", format)
+	}
+	return nil
+}
+
+// detectFormat probes the structure of content: a JSON object is a sing-box
+// provider file unless it only carries SIP008 servers, a YAML mapping with a
+// proxies or outbounds list is a Clash or sing-box configuration, anything
+// else is a list of share links.
+func detectFormat(content string) string {
+	if strings.HasPrefix(strings.TrimSpace(content), "{") {
+		var probe map[string]json.RawMessage
+		if json.Unmarshal([]byte(content), &probe) == nil {
+			_, hasOutbounds := probe["outbounds"]
+			_, hasServers := probe["servers"]
+			if hasServers && !hasOutbounds {
+				return C.ProviderFormatSIP008
+			}
+		}
+		return C.ProviderFormatSingBox
+	}
+	var probe map[string]any
+	if yaml.Unmarshal([]byte(content), &probe) == nil {
+		if _, isList := probe["proxies"].([]any); isList {
+			return C.ProviderFormatClash
+		}
+		if _, isList := probe["outbounds"].([]any); isList {
+			return C.ProviderFormatSingBox
+		}
+	}
+	return C.ProviderFormatURI
+}
+
+func (p *myProviderAdapter) newParser(content string) ([]option.Outbound, []adapter.SkippedOutbound, error) {
+	format := p.format
+	if format == "" || format == C.ProviderFormatAuto {
+		format = detectFormat(content)
+	}
+	outbounds, skipped, err := parseFormat(format, content)
+	if err != nil {
+		if p.format != format {
+			return nil, nil, E.Cause(err, "parse ", format, " content")
+		}
+		return nil, nil, E.Cause(err, "parse content as declared format ", format)
+	}
+	if p.format == format && len(outbounds) == 0 && len(skipped) == 0 {
+		return nil, nil, E.New("parse content as declared format ", format, ": no outbounds found")
+	}
+	return p.overrideOutbounds(outbounds), skipped, nil
+}
+
+func parseFormat(format string, content string) ([]option.Outbound, []adapter.SkippedOutbound, error) {
+	switch format {
+	case C.ProviderFormatSingBox:
 		var options option.OutboundProviderOptions
 		if parsedContent, err := betterjson.PreConvert([]byte(content)); err != nil {
-			return nil, E.Cause(err, "decode config at ")
+			return nil, nil, E.Cause(err, "decode config")
 		} else if err := options.UnmarshalJSON(parsedContent); err != nil {
-			return nil, E.Cause(err, "decode config at ")
-		}
-		outbounds = options.Outbounds
-	case strings.Contains(content, "proxies"):
-		outbounds, err = newClashParser(content)
-		if err != nil {
-			return nil, err
+			return nil, nil, E.Cause(err, "decode config")
 		}
+		return options.Outbounds, nil, nil
+	case C.ProviderFormatSIP008:
+		return newSIP008Parser(content)
+	case C.ProviderFormatClash:
+		return newClashParser(content)
+	case C.ProviderFormatURI:
+		return newNativeURIParser(content)
 	default:
-		outbounds, err = newNativeURIParser(content)
-		if err != nil {
-			return nil, err
+		return nil, nil, E.New("unknown provider format: ", format)
+	}
+}
+
+func (p *myProviderAdapter) filterOutbounds(outbounds []option.Outbound) ([]option.Outbound, []adapter.SkippedOutbound) {
+	var (
+		filtered []option.Outbound
+		skipped  []adapter.SkippedOutbound
+	)
+	for i, outbound := range outbounds {
+		reason := p.mismatchReason(outbound)
+		if reason == "" {
+			filtered = append(filtered, outbound)
+			continue
 		}
+		skipped = append(skipped, adapter.SkippedOutbound{
+			Index:  i,
+			Tag:    outbound.Tag,
+			Type:   outbound.Type,
+			Reason: reason,
+		})
 	}
-	return p.overrideOutbounds(outbounds), nil
+	return filtered, skipped
 }
 
 func (p *myProviderAdapter) overrideOutbounds(outbounds []option.Outbound) []option.Outbound {
-	var tags []string
+	tags := make(map[string]string, len(outbounds))
 	for _, outbound := range outbounds {
-		tags = append(tags, outbound.Tag)
+		tags[outbound.Tag] = p.overrideTag(outbound.Tag)
 	}
 	var parsedOutbounds []option.Outbound
 	for _, outbound := range outbounds {
-		if p.outboundOverride != nil {
-			if p.outboundOverride.TagPrefix != "" {
-				outbound.Tag = p.outboundOverride.TagPrefix + outbound.Tag
-			}
-			if p.outboundOverride.TagSuffix != "" {
-				outbound.Tag = outbound.Tag + p.outboundOverride.TagSuffix
-			}
-		}
+		outbound.Tag = tags[outbound.Tag]
 		switch outbound.Type {
 		case C.TypeHTTP:
 			dialer := outbound.HTTPOptions.DialerOptions
@@ -90,16 +183,35 @@ func (p *myProviderAdapter) overrideOutbounds(outbounds []option.Outbound) []opt
 			dialer := outbound.ShadowsocksROptions.DialerOptions
 			outbound.ShadowsocksROptions.DialerOptions = p.overrideDialerOption(dialer, tags)
 		}
-		parsedOutbounds = append(parsedOutbounds, outbound)
+		parsedOutbounds = append(parsedOutbounds, p.patchOutbound(outbound))
 	}
 	return parsedOutbounds
 }
 
-func (p *myProviderAdapter) overrideDialerOption(options option.DialerOptions, tags []string) option.DialerOptions {
-	if options.Detour != "" && !common.Any(tags, func(tag string) bool {
-		return options.Detour == tag
-	}) {
-		options.Detour = ""
+// overrideTag applies tag_rename rules in order, then tag_prefix and tag_suffix.
+func (p *myProviderAdapter) overrideTag(tag string) string {
+	if p.outboundOverride == nil {
+		return tag
+	}
+	for _, rename := range p.tagRenames {
+		if renamed, err := rename.regex.Replace(tag, rename.replacement, -1, -1); err == nil {
+			tag = renamed
+		}
+	}
+	if p.outboundOverride.TagPrefix != "" {
+		tag = p.outboundOverride.TagPrefix + tag
+	}
+	if p.outboundOverride.TagSuffix != "" {
+		tag = tag + p.outboundOverride.TagSuffix
+	}
+	return tag
+}
+
+// overrideDialerOption points detour to the overridden tag of the outbound in
+// the same provider, detour to any other outbound is dropped.
+func (p *myProviderAdapter) overrideDialerOption(options option.DialerOptions, tags map[string]string) option.DialerOptions {
+	if options.Detour != "" {
+		options.Detour = tags[options.Detour]
 	}
 	var defaultOptions option.OverrideDialerOptions
 	if p.outboundOverride == nil || p.outboundOverride.OverrideDialerOptions == nil || reflect.DeepEqual(*p.outboundOverride.OverrideDialerOptions, defaultOptions) {
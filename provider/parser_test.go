@@ -0,0 +1,176 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/base64"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files of parser tests")
+
+type parserResult struct {
+	Outbounds []option.Outbound         `json:"outbounds"`
+	Skipped   []adapter.SkippedOutbound `json:"skipped,omitempty"`
+}
+
+func parseToGolden(t *testing.T, content string) []byte {
+	outbounds, skipped, err := ParseContent(content, option.OutboundProvider{})
+	require.NoError(t, err)
+	buffer := new(bytes.Buffer)
+	encoder := json.NewEncoder(buffer)
+	encoder.SetIndent("", "  ")
+	require.NoError(t, encoder.Encode(parserResult{
+		Outbounds: outbounds,
+		Skipped:   skipped,
+	}))
+	return buffer.Bytes()
+}
+
+// TestParserGolden parses every testdata/parser/*.input file and compares the
+// result with the matching .golden.json file. Run with -update to rewrite them.
+func TestParserGolden(t *testing.T) {
+	t.Parallel()
+	inputs, err := filepath.Glob(filepath.Join("testdata", "parser", "*.input"))
+	require.NoError(t, err)
+	require.NotEmpty(t, inputs)
+	for _, input := range inputs {
+		input := input
+		t.Run(strings.TrimSuffix(filepath.Base(input), ".input"), func(t *testing.T) {
+			content, err := os.ReadFile(input)
+			require.NoError(t, err)
+			actual := parseToGolden(t, string(content))
+			goldenPath := strings.TrimSuffix(input, ".input") + ".golden.json"
+			if *updateGolden {
+				require.NoError(t, os.WriteFile(goldenPath, actual, 0o644))
+				return
+			}
+			expected, err := os.ReadFile(goldenPath)
+			require.NoError(t, err)
+			require.Equal(t, string(expected), string(actual))
+		})
+	}
+}
+
+func TestParserEncodedSubscription(t *testing.T) {
+	t.Parallel()
+	content, err := os.ReadFile(filepath.Join("testdata", "parser", "uri.input"))
+	require.NoError(t, err)
+	expected := parseToGolden(t, string(content))
+	encoded := base64.StdEncoding.EncodeToString(content)
+	var wrapped strings.Builder
+	for line := encoded; line != ""; {
+		size := 76
+		if len(line) < size {
+			size = len(line)
+		}
+		wrapped.WriteString(line[:size])
+		wrapped.WriteString("\n")
+		line = line[size:]
+	}
+	userInfo := "upload=1024; download=2048; total=1073741824; expire=1893427200\n"
+	variants := map[string]string{
+		"std":              encoded,
+		"raw std":          base64.RawStdEncoding.EncodeToString(content),
+		"url":              base64.URLEncoding.EncodeToString(content),
+		"raw url":          base64.RawURLEncoding.EncodeToString(content),
+		"wrapped":          wrapped.String(),
+		"userinfo":         userInfo + encoded,
+		"userinfo plain":   userInfo + string(content),
+		"surrounding tabs": "\t" + encoded + "\t",
+	}
+	for name, variant := range variants {
+		require.Equal(t, string(expected), string(parseToGolden(t, variant)), name)
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]string{
+		`{"outbounds":[]}`:                                                    C.ProviderFormatSingBox,
+		"{\n  // comment\n  outbounds: [],\n}":                                C.ProviderFormatSingBox,
+		`{"version":1,"servers":[]}`:                                          C.ProviderFormatSIP008,
+		"proxies:\n  - name: a\n":                                             C.ProviderFormatClash,
+		"ss://YWVzLTEyOC1nY206dGVzdA@example.com:443#proxies\n":               C.ProviderFormatURI,
+		"trojan://password@example.com:443#outbounds\nproxies: []\n":          C.ProviderFormatURI,
+		"vmess://eyJ2IjoiMiJ9\nhysteria2://letmein@example.com:8443/#servers": C.ProviderFormatURI,
+	}
+	for content, format := range testCases {
+		require.Equal(t, format, detectFormat(content), content)
+	}
+}
+
+func TestParseDeclaredFormat(t *testing.T) {
+	t.Parallel()
+	uriContent := "trojan://password@example.com:443#proxies\ntrojan://password@example.org:443#outbounds"
+	outbounds, _, err := ParseContent(uriContent, option.OutboundProvider{})
+	require.NoError(t, err)
+	require.Len(t, outbounds, 2)
+	outbounds, _, err = ParseContent(uriContent, option.OutboundProvider{Format: C.ProviderFormatURI})
+	require.NoError(t, err)
+	require.Len(t, outbounds, 2)
+	_, _, err = ParseContent(uriContent, option.OutboundProvider{Format: C.ProviderFormatClash})
+	require.ErrorContains(t, err, "declared format clash")
+	_, _, err = ParseContent("proxies:\n  - name: a\n    type: ss\n", option.OutboundProvider{Format: C.ProviderFormatURI})
+	require.ErrorContains(t, err, "no outbounds found")
+	_, _, err = ParseContent(`{"outbounds":[]}`, option.OutboundProvider{Format: C.ProviderFormatSIP008})
+	require.ErrorContains(t, err, "declared format sip008")
+	_, _, err = ParseContent(uriContent, option.OutboundProvider{Format: "surge"})
+	require.ErrorContains(t, err, "unknown provider format")
+}
+
+func TestTagRename(t *testing.T) {
+	t.Parallel()
+	content := `
+proxies:
+  - name: "🇭🇰 香港 01 | IPLC"
+    type: socks5
+    server: 127.0.0.1
+    port: 1080
+  - name: "🇺🇸 美国 02"
+    type: socks5
+    server: 127.0.0.1
+    port: 1081
+    dialer-proxy: "🇭🇰 香港 01 | IPLC"
+  - name: "剩余流量: 100G"
+    type: socks5
+    server: 127.0.0.1
+    port: 1082
+    dialer-proxy: upstream
+`
+	outbounds, skipped, err := ParseContent(content, option.OutboundProvider{
+		OutboundOverride: &option.OutboundOverrideOptions{
+			TagRename: []option.TagRenameOptions{
+				{Pattern: `^\S+\s+香港\s*(?<index>\d+).*$`, Replacement: "HK-${index}"},
+				{Pattern: `^\S+\s+美国\s*(\d+)$`, Replacement: "US-$1"},
+			},
+			TagPrefix: "sub/",
+		},
+		FilterOptions: option.FilterOptions{
+			Includes: []string{`^sub/(HK|US)-\d+$`},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, outbounds, 2)
+	require.Equal(t, "sub/HK-01", outbounds[0].Tag)
+	require.Equal(t, "sub/US-02", outbounds[1].Tag)
+	require.Equal(t, "sub/HK-01", outbounds[1].SocksOptions.Detour)
+	require.Len(t, skipped, 1)
+	require.Equal(t, "sub/剩余流量: 100G", skipped[0].Tag)
+	_, _, err = ParseContent(content, option.OutboundProvider{
+		OutboundOverride: &option.OutboundOverrideOptions{
+			TagRename: []option.TagRenameOptions{{Pattern: `(`}},
+		},
+	})
+	require.ErrorContains(t, err, "tag_rename[0]")
+}
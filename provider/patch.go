@@ -0,0 +1,156 @@
+package provider
+
+import (
+	"net/http"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+type outboundPatch struct {
+	outboundMatcher
+	options option.OutboundPatchOptions
+}
+
+func (a *myProviderAdapter) initPatches(patches []option.OutboundPatchOptions) error {
+	for i, options := range patches {
+		matcher, err := newOutboundMatcher(options.FilterOptions)
+		if err != nil {
+			return E.Cause(err, "parse outbound_patches[", i, "]")
+		}
+		a.outboundPatches = append(a.outboundPatches, outboundPatch{
+			outboundMatcher: matcher,
+			options:         options,
+		})
+	}
+	return nil
+}
+
+func (a *myProviderAdapter) patchOutbound(outbound option.Outbound) option.Outbound {
+	for _, patch := range a.outboundPatches {
+		if patch.mismatchReason(outbound) == "" {
+			outbound = patch.apply(outbound)
+		}
+	}
+	return outbound
+}
+
+func (p outboundPatch) apply(outbound option.Outbound) option.Outbound {
+	if p.options.TLS != nil {
+		if rawOptions, err := outbound.RawOptions(); err == nil {
+			if tlsOptions, containsTLSOptions := rawOptions.(option.OutboundTLSOptionsWrapper); containsTLSOptions {
+				tlsOptions.ReplaceOutboundTLSOptions(patchTLSOptions(tlsOptions.TakeOutboundTLSOptions(), p.options.TLS))
+			}
+		}
+	}
+	if p.options.Multiplex != nil {
+		var multiplex *option.OutboundMultiplexOptions
+		if p.options.Multiplex.Enabled {
+			patched := *p.options.Multiplex
+			multiplex = &patched
+		}
+		switch outbound.Type {
+		case C.TypeShadowsocks:
+			outbound.ShadowsocksOptions.Multiplex = multiplex
+		case C.TypeVMess:
+			outbound.VMessOptions.Multiplex = multiplex
+		case C.TypeTrojan:
+			outbound.TrojanOptions.Multiplex = multiplex
+		case C.TypeVLESS:
+			outbound.VLESSOptions.Multiplex = multiplex
+		}
+	}
+	if p.options.UDPOverTCP != nil {
+		var udpOverTCP *option.UDPOverTCPOptions
+		if p.options.UDPOverTCP.Enabled {
+			patched := *p.options.UDPOverTCP
+			udpOverTCP = &patched
+		}
+		switch outbound.Type {
+		case C.TypeShadowsocks:
+			outbound.ShadowsocksOptions.UDPOverTCP = udpOverTCP
+		case C.TypeSOCKS:
+			outbound.SocksOptions.UDPOverTCP = udpOverTCP
+		}
+	}
+	if len(p.options.TransportHeaders) > 0 {
+		switch outbound.Type {
+		case C.TypeVMess:
+			outbound.VMessOptions.Transport = patchTransportHeaders(outbound.VMessOptions.Transport, p.options.TransportHeaders)
+		case C.TypeTrojan:
+			outbound.TrojanOptions.Transport = patchTransportHeaders(outbound.TrojanOptions.Transport, p.options.TransportHeaders)
+		case C.TypeVLESS:
+			outbound.VLESSOptions.Transport = patchTransportHeaders(outbound.VLESSOptions.Transport, p.options.TransportHeaders)
+		}
+	}
+	return outbound
+}
+
+// patchTLSOptions only patches outbounds with TLS enabled, an empty ALPN list
+// or uTLS fingerprint deletes the existing value.
+func patchTLSOptions(options *option.OutboundTLSOptions, patch *option.OutboundTLSPatchOptions) *option.OutboundTLSOptions {
+	if options == nil || !options.Enabled {
+		return options
+	}
+	patched := *options
+	if patch.ServerName != nil {
+		patched.ServerName = *patch.ServerName
+	}
+	if patch.Insecure != nil {
+		patched.Insecure = *patch.Insecure
+	}
+	if patch.ALPN != nil {
+		patched.ALPN = *patch.ALPN
+	}
+	if patch.UTLSFingerprint != nil {
+		if *patch.UTLSFingerprint == "" {
+			patched.UTLS = nil
+		} else {
+			patched.UTLS = &option.OutboundUTLSOptions{
+				Enabled:     true,
+				Fingerprint: *patch.UTLSFingerprint,
+			}
+		}
+	}
+	return &patched
+}
+
+// patchTransportHeaders merges headers into HTTP, WebSocket and HTTPUpgrade
+// transports, a header with no value deletes the existing one.
+func patchTransportHeaders(options *option.V2RayTransportOptions, headers option.HTTPHeader) *option.V2RayTransportOptions {
+	if options == nil {
+		return nil
+	}
+	patched := *options
+	switch patched.Type {
+	case C.V2RayTransportTypeHTTP:
+		patched.HTTPOptions.Headers = mergeHTTPHeader(patched.HTTPOptions.Headers, headers)
+	case C.V2RayTransportTypeWebsocket:
+		patched.WebsocketOptions.Headers = mergeHTTPHeader(patched.WebsocketOptions.Headers, headers)
+	case C.V2RayTransportTypeHTTPUpgrade:
+		patched.HTTPUpgradeOptions.Headers = mergeHTTPHeader(patched.HTTPUpgradeOptions.Headers, headers)
+	}
+	return &patched
+}
+
+func mergeHTTPHeader(origin option.HTTPHeader, headers option.HTTPHeader) option.HTTPHeader {
+	merged := make(option.HTTPHeader, len(origin)+len(headers))
+	for key, value := range origin {
+		merged[key] = value
+	}
+	for key, value := range headers {
+		for originKey := range merged {
+			if http.CanonicalHeaderKey(originKey) == http.CanonicalHeaderKey(key) {
+				delete(merged, originKey)
+			}
+		}
+		if len(value) > 0 {
+			merged[key] = value
+		}
+	}
+	if len(merged) == 0 {
+		return nil
+	}
+	return merged
+}
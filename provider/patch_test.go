@@ -0,0 +1,94 @@
+package provider
+
+import (
+	"testing"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutboundPatches(t *testing.T) {
+	t.Parallel()
+	content := `
+proxies:
+  - name: vmess hk
+    type: vmess
+    server: example.com
+    port: 443
+    uuid: b831381d-6324-4d53-ad4f-8cda48b30811
+    alterId: 0
+    cipher: auto
+    tls: true
+    alpn:
+      - h2
+    client-fingerprint: firefox
+    network: ws
+    ws-opts:
+      path: /ray
+      headers:
+        host: cdn.example.com
+        X-Forwarded-For: 1.1.1.1
+  - name: trojan us
+    type: trojan
+    server: example.com
+    port: 443
+    password: secret
+    sni: example.org
+  - name: ss hk
+    type: ss
+    server: example.com
+    port: 8388
+    cipher: aes-128-gcm
+    password: secret
+    udp-over-tcp: true
+`
+	outbounds, _, err := ParseContent(content, option.OutboundProvider{
+		OutboundPatches: []option.OutboundPatchOptions{
+			{
+				FilterOptions: option.FilterOptions{Includes: []string{"hk"}},
+				TLS: &option.OutboundTLSPatchOptions{
+					Insecure:        common.Ptr(true),
+					ALPN:            &option.Listable[string]{},
+					UTLSFingerprint: common.Ptr("chrome"),
+				},
+				Multiplex: &option.OutboundMultiplexOptions{
+					Enabled:  true,
+					Protocol: "smux",
+				},
+				UDPOverTCP: &option.UDPOverTCPOptions{},
+				TransportHeaders: option.HTTPHeader{
+					"Host":            {"patched.example.com"},
+					"X-Forwarded-For": {},
+				},
+			},
+			{
+				FilterOptions: option.FilterOptions{Types: []string{C.TypeTrojan}},
+				TLS: &option.OutboundTLSPatchOptions{
+					UTLSFingerprint: common.Ptr(""),
+					ServerName:      common.Ptr("patched.example.org"),
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, outbounds, 3)
+
+	vmessOptions := outbounds[0].VMessOptions
+	require.True(t, vmessOptions.TLS.Insecure)
+	require.Empty(t, vmessOptions.TLS.ALPN)
+	require.Equal(t, "chrome", vmessOptions.TLS.UTLS.Fingerprint)
+	require.Equal(t, "smux", vmessOptions.Multiplex.Protocol)
+	require.Equal(t, option.HTTPHeader{"Host": {"patched.example.com"}}, vmessOptions.Transport.WebsocketOptions.Headers)
+
+	trojanOptions := outbounds[1].TrojanOptions
+	require.Nil(t, trojanOptions.TLS.UTLS)
+	require.False(t, trojanOptions.TLS.Insecure)
+	require.Equal(t, "patched.example.org", trojanOptions.TLS.ServerName)
+
+	ssOptions := outbounds[2].ShadowsocksOptions
+	require.Nil(t, ssOptions.UDPOverTCP)
+	require.Equal(t, "smux", ssOptions.Multiplex.Protocol)
+}
@@ -12,17 +12,17 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/tls"
 	"github.com/sagernet/sing-box/common/urltest"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
 	"github.com/sagernet/sing/service"
 	"github.com/sagernet/sing/service/pause"
-
-	R "github.com/dlclark/regexp2"
 )
 
 var (
@@ -32,45 +32,106 @@ var (
 
 type RemoteProvider struct {
 	myProviderAdapter
-	url      string
+	sources  []*remoteSource
+	race     bool
+	lastURL  string
 	ua       string
+	headers  http.Header
+	timeout  time.Duration
+	maxSize  int64
 	interval time.Duration
-	lastEtag string
-	detour   string
-	dialer   N.Dialer
 
 	updateTicker     *time.Ticker
 	firstStartCancel context.CancelFunc
 }
 
+// remoteSource is a download URL of the provider, the ETag and modification
+// time of it are only sent if the provider content was downloaded from it.
+type remoteSource struct {
+	url          string
+	detour       string
+	dialer       N.Dialer
+	tlsConfig    tls.Config
+	lastEtag     string
+	lastModified string
+}
+
+type remoteResponse struct {
+	source      *remoteSource
+	notModified bool
+	subInfo     string
+	content     []byte
+}
+
+func parseDownloadURL(rawURL string) (string, error) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	switch parsedURL.Scheme {
+	case "":
+		parsedURL.Scheme = "http"
+	case "http", "https":
+	default:
+		return "", E.New("invalid url scheme: ", rawURL)
+	}
+	return parsedURL.String(), nil
+}
+
+// downloadNextProtos removes h2 from the ALPN of the download TLS config, as
+// subscriptions are downloaded over HTTP/1.1 only.
+func downloadNextProtos(nextProtos []string) []string {
+	nextProtos = common.Filter(nextProtos, func(it string) bool {
+		return it != "h2"
+	})
+	if len(nextProtos) == 0 {
+		return []string{"http/1.1"}
+	}
+	return nextProtos
+}
+
 func NewRemoteProvider(ctx context.Context, router adapter.Router, logger log.ContextLogger, options option.OutboundProvider, path string) (*RemoteProvider, error) {
 	remoteOptions := options.RemoteOptions
 	if remoteOptions.Url == "" {
 		return nil, E.New("missing url")
 	}
-	healthcheckInterval := time.Duration(remoteOptions.HealthcheckInterval)
-	healthcheckUrl := options.LocalOptions.HealthcheckUrl
-	parsedURL, err := url.Parse(remoteOptions.Url)
-	ua := remoteOptions.UserAgent
-	downloadInterval := time.Duration(options.RemoteOptions.Interval)
+	downloadURL, err := parseDownloadURL(remoteOptions.Url)
 	if err != nil {
 		return nil, err
 	}
-	switch parsedURL.Scheme {
-	case "":
-		parsedURL.Scheme = "http"
-	case "http", "https":
-	default:
-		return nil, E.New("invalid url scheme")
+	sources := []*remoteSource{{url: downloadURL, detour: remoteOptions.Detour}}
+	for i, mirror := range remoteOptions.Mirrors {
+		mirrorURL, err := parseDownloadURL(mirror.URL)
+		if err != nil {
+			return nil, E.Cause(err, "parse download_mirrors[", i, "]")
+		}
+		detour := mirror.Detour
+		if detour == "" {
+			detour = remoteOptions.Detour
+		}
+		sources = append(sources, &remoteSource{url: mirrorURL, detour: detour})
 	}
-	if ua == "" {
-		ua = "sing-box " + C.Version + "; PuerNya fork"
+	if remoteOptions.TLS != nil && remoteOptions.TLS.Enabled {
+		for _, source := range sources {
+			sourceURL, _ := url.Parse(source.url)
+			if sourceURL.Scheme != "https" {
+				continue
+			}
+			source.tlsConfig, err = tls.NewClient(ctx, sourceURL.Hostname(), common.PtrValueOrDefault(remoteOptions.TLS))
+			if err != nil {
+				return nil, E.Cause(err, "create download tls for ", source.url)
+			}
+			source.tlsConfig.SetNextProtos(downloadNextProtos(source.tlsConfig.NextProtos()))
+		}
 	}
-	if healthcheckUrl == "" {
-		healthcheckUrl = "https://www.gstatic.com/generate_204"
+	ua := remoteOptions.UserAgent
+	downloadInterval := time.Duration(options.RemoteOptions.Interval)
+	timeout := time.Duration(remoteOptions.Timeout)
+	if timeout == 0 {
+		timeout = C.DefaultDownloadTimeout
 	}
-	if healthcheckInterval == 0 {
-		healthcheckInterval = C.DefaultURLTestInterval
+	if ua == "" {
+		ua = "sing-box " + C.Version + "; PuerNya fork"
 	}
 	if downloadInterval < C.DefaultDonloadInterval {
 		downloadInterval = C.DefaultDonloadInterval
@@ -78,75 +139,71 @@ func NewRemoteProvider(ctx context.Context, router adapter.Router, logger log.Co
 	ctx, cancel := context.WithCancel(ctx)
 	provider := &RemoteProvider{
 		myProviderAdapter: myProviderAdapter{
-			ctx:                 ctx,
-			cancel:              cancel,
-			router:              router,
-			logger:              logger,
-			tag:                 options.Tag,
-			path:                path,
-			enableHealthcheck:   remoteOptions.EnableHealthcheck,
-			healthcheckUrl:      healthcheckUrl,
-			healthcheckInterval: healthcheckInterval,
-			types:               options.Types,
-			ports:               make(map[int]bool),
-			providerType:        C.ProviderTypeRemote,
-			outboundOverride:    options.OutboundOverride,
-			close:               make(chan struct{}),
-			pauseManager:        service.FromContext[pause.Manager](ctx),
-			subInfo:             SubInfo{},
-			outbounds:           []adapter.Outbound{},
-			outboundByTag:       make(map[string]adapter.Outbound),
+			ctx:              ctx,
+			cancel:           cancel,
+			router:           router,
+			logger:           logger,
+			tag:              options.Tag,
+			path:             path,
+			providerType:     C.ProviderTypeRemote,
+			outboundOverride: options.OutboundOverride,
+			close:            make(chan struct{}),
+			pauseManager:     service.FromContext[pause.Manager](ctx),
+			subInfo:          SubInfo{},
+			outbounds:        []adapter.Outbound{},
+			outboundByTag:    make(map[string]adapter.Outbound),
 		},
-		url:      parsedURL.String(),
+		sources:  sources,
+		race:     remoteOptions.Race,
 		ua:       ua,
+		headers:  remoteOptions.Headers.Build(),
+		timeout:  timeout,
+		maxSize:  int64(remoteOptions.MaxSize),
 		interval: downloadInterval,
-		detour:   remoteOptions.Detour,
 	}
-	if len(options.Includes) > 0 {
-		includes := make([]*R.Regexp, 0, len(options.Includes))
-		for _, include := range options.Includes {
-			regex, err := R.Compile(include, R.IgnoreCase)
-			if err != nil {
-				return nil, E.Cause(err, "parse includes")
-			}
-			includes = append(includes, regex)
-		}
-		provider.includes = includes
+	if err := provider.initOptions(options); err != nil {
+		return nil, err
 	}
-	if options.Excludes != "" {
-		regex, err := R.Compile(options.Excludes, R.IgnoreCase)
-		if err != nil {
-			return nil, E.Cause(err, "parse excludes")
-		}
-		provider.excludes = regex
+	if err := provider.initHealthcheck(remoteOptions.HealthcheckOptions); err != nil {
+		return nil, err
 	}
-	if err := provider.firstStart(options.Ports); err != nil {
+	if err := provider.firstStart(); err != nil {
 		return nil, err
 	}
 	return provider, nil
 }
 
 func (p *RemoteProvider) PostStart() error {
-	var dialer N.Dialer
-	if p.detour != "" {
-		outbound, loaded := p.router.Outbound(p.detour)
-		if !loaded {
-			return E.New("download_detour not found: ", p.detour)
-		}
-		dialer = outbound
-	} else {
-		outbound, err := p.router.DefaultOutbound(N.NetworkTCP)
-		if err != nil {
-			return err
-		}
-		dialer = outbound
+	if err := p.initDialers(); err != nil {
+		return err
 	}
-	p.dialer = dialer
 	go p.loopUpdateCheck()
 	go p.loopHealthCheck()
 	return nil
 }
 
+func (p *RemoteProvider) initDialers() error {
+	for _, source := range p.sources {
+		if source.dialer != nil {
+			continue
+		}
+		if source.detour != "" {
+			outbound, loaded := p.router.Outbound(source.detour)
+			if !loaded {
+				return E.New("download_detour not found: ", source.detour)
+			}
+			source.dialer = outbound
+		} else {
+			outbound, err := p.router.DefaultOutbound(N.NetworkTCP)
+			if err != nil {
+				return err
+			}
+			source.dialer = outbound
+		}
+	}
+	return nil
+}
+
 func (p *RemoteProvider) Start() error {
 	var history *urltest.HistoryStorage
 	if history = service.PtrFromContext[urltest.HistoryStorage](p.ctx); history != nil {
@@ -156,6 +213,18 @@ func (p *RemoteProvider) Start() error {
 		history = urltest.NewHistoryStorage()
 	}
 	p.healchcheckHistory = history
+	if metadata := p.loadMetadata(); metadata != nil {
+		if metadata.LastUpdated.After(p.lastUpdated) {
+			p.lastUpdated = metadata.LastUpdated
+		}
+		p.lastURL = metadata.LastURL
+		for _, source := range p.sources {
+			source.lastEtag = metadata.Etags[source.url]
+			source.lastModified = metadata.LastModified[source.url]
+		}
+	}
+	p.saveRemoteMetadata()
+	p.checkSubscription()
 	return nil
 }
 
@@ -193,6 +262,7 @@ func (p *RemoteProvider) updateCacheFileModTime(subInfo string) {
 		return
 	}
 	p.subInfo = info
+	p.checkSubscription()
 
 	contentRaw := getTrimedFile(p.path)
 	content := decodeBase64Safe(string(contentRaw))
@@ -205,65 +275,50 @@ func (p *RemoteProvider) updateCacheFileModTime(subInfo string) {
 	os.WriteFile(p.path, []byte(content), 0o666)
 }
 
-func (p *RemoteProvider) fetchOnce(ctx context.Context, router adapter.Router) error {
-	defer runtime.GC()
-	p.lastUpdated = time.Now()
-
-	httpClient := &http.Client{
-		Transport: &http.Transport{
-			TLSHandshakeTimeout: C.TCPTimeout,
-			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-				return p.dialer.DialContext(ctx, network, M.ParseSocksaddr(addr))
-			},
-		},
+func (p *RemoteProvider) saveRemoteMetadata() {
+	metadata := adapter.SavedProviderMetadata{
+		LastURL:      p.lastURL,
+		Etags:        make(map[string]string),
+		LastModified: make(map[string]string),
 	}
-
-	request, err := http.NewRequest("GET", p.url, nil)
-	if err != nil {
-		return err
+	for _, source := range p.sources {
+		if source.lastEtag != "" {
+			metadata.Etags[source.url] = source.lastEtag
+		}
+		if source.lastModified != "" {
+			metadata.LastModified[source.url] = source.lastModified
+		}
 	}
+	p.saveMetadata(metadata)
+}
 
-	if p.lastEtag != "" {
-		request.Header.Set("If-None-Match", p.lastEtag)
-	}
+// LastURL returns the URL the provider content was downloaded from.
+func (p *RemoteProvider) LastURL() string {
+	return p.lastURL
+}
 
-	request.Header.Set("User-Agent", p.ua)
+func (p *RemoteProvider) fetchOnce(ctx context.Context, router adapter.Router) error {
+	defer runtime.GC()
+	p.lastUpdated = time.Now()
 
-	response, err := httpClient.Do(request)
+	var (
+		response *remoteResponse
+		err      error
+	)
+	response, err = p.fetch(ctx, true)
 	if err != nil {
-		uErr := err.(*url.Error)
-		return uErr.Err
+		return err
 	}
 
-	subInfo := response.Header.Get("subscription-userinfo")
-
-	switch response.StatusCode {
-	case http.StatusOK:
-	case http.StatusNotModified:
+	if response.notModified {
 		p.logger.InfoContext(ctx, "update outbound provider ", p.tag, ": not modified")
-		p.updateCacheFileModTime(subInfo)
+		p.updateCacheFileModTime(response.subInfo)
+		p.saveRemoteMetadata()
 		return nil
-	default:
-		return E.New("unexpected status: ", response.Status)
 	}
 
-	defer response.Body.Close()
-
-	contentRaw, err := io.ReadAll(response.Body)
-	if err != nil {
-		return err
-	}
-	if len(contentRaw) == 0 {
-		return E.New("empty response")
-	}
-
-	eTagHeader := response.Header.Get("Etag")
-	if eTagHeader != "" {
-		p.lastEtag = eTagHeader
-	}
-
-	content := decodeBase64Safe(string(contentRaw))
-	info, hasSubInfo := parseSubInfo(subInfo)
+	content := decodeBase64Safe(string(response.content))
+	info, hasSubInfo := parseSubInfo(response.subInfo)
 
 	if !hasSubInfo {
 		var ok bool
@@ -279,19 +334,182 @@ func (p *RemoteProvider) fetchOnce(ctx context.Context, router adapter.Router) e
 		return err
 	}
 
+	p.lastURL = response.source.url
 	p.subInfo = info
-	p.logger.InfoContext(ctx, "update outbound provider ", p.tag, " success")
+	p.checkSubscription()
+	p.logger.InfoContext(ctx, "update outbound provider ", p.tag, " success from ", p.lastURL)
 
 	if hasSubInfo {
-		subInfo = fmt.Sprint("# upload=", info.upload, "; download=", info.download, "; total=", info.total, "; expire=", info.expire, ";")
+		subInfo := fmt.Sprint("# upload=", info.upload, "; download=", info.download, "; total=", info.total, "; expire=", info.expire, ";")
 		content = subInfo + "\n" + content
 	}
 
 	os.WriteFile(p.path, []byte(content), 0o666)
+	p.saveRemoteMetadata()
 
 	return nil
 }
 
+// Fetch downloads the provider content as the provider updates itself, but
+// without the ETag and modification time of the last download, and returns it
+// without updating the provider.
+func (p *RemoteProvider) Fetch(ctx context.Context) ([]byte, error) {
+	if err := p.initDialers(); err != nil {
+		return nil, err
+	}
+	response, err := p.fetch(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	return response.content, nil
+}
+
+func (p *RemoteProvider) fetch(ctx context.Context, conditional bool) (*remoteResponse, error) {
+	if p.race && len(p.sources) > 1 {
+		return p.fetchRace(ctx, conditional)
+	}
+	return p.fetchSequential(ctx, conditional)
+}
+
+// fetchSequential tries sources in order and returns the first response.
+func (p *RemoteProvider) fetchSequential(ctx context.Context, conditional bool) (*remoteResponse, error) {
+	var errors []error
+	for _, source := range p.sources {
+		response, err := p.fetchSource(ctx, source, conditional)
+		if err == nil {
+			return response, nil
+		}
+		if len(p.sources) > 1 {
+			p.logger.WarnContext(ctx, "download outbound provider ", p.tag, " from ", source.url, ": ", err)
+		}
+		errors = append(errors, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, E.Errors(errors...)
+}
+
+// fetchRace requests all sources at the same time and returns the first
+// response, other requests are canceled.
+func (p *RemoteProvider) fetchRace(ctx context.Context, conditional bool) (*remoteResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type result struct {
+		response *remoteResponse
+		err      error
+	}
+	results := make(chan result, len(p.sources))
+	for _, source := range p.sources {
+		go func(source *remoteSource) {
+			response, err := p.fetchSource(ctx, source, conditional)
+			results <- result{response, err}
+		}(source)
+	}
+	var errors []error
+	for range p.sources {
+		result := <-results
+		if result.err == nil {
+			return result.response, nil
+		}
+		errors = append(errors, result.err)
+	}
+	return nil, E.Errors(errors...)
+}
+
+func (p *RemoteProvider) fetchSource(ctx context.Context, source *remoteSource, conditional bool) (*remoteResponse, error) {
+	transport := &http.Transport{
+		TLSHandshakeTimeout: C.TCPTimeout,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return source.dialer.DialContext(ctx, network, M.ParseSocksaddr(addr))
+		},
+	}
+	if source.tlsConfig != nil {
+		transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := source.dialer.DialContext(ctx, network, M.ParseSocksaddr(addr))
+			if err != nil {
+				return nil, err
+			}
+			tlsConn, err := tls.ClientHandshake(ctx, conn, source.tlsConfig)
+			if err != nil {
+				conn.Close()
+				return nil, err
+			}
+			return tlsConn, nil
+		}
+	}
+	httpClient := &http.Client{
+		Transport: transport,
+		Timeout:   p.timeout,
+	}
+	defer httpClient.CloseIdleConnections()
+
+	request, err := http.NewRequestWithContext(ctx, "GET", source.url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if conditional && source.url == p.lastURL {
+		if source.lastEtag != "" {
+			request.Header.Set("If-None-Match", source.lastEtag)
+		}
+		if source.lastModified != "" {
+			request.Header.Set("If-Modified-Since", source.lastModified)
+		}
+	}
+
+	request.Header.Set("User-Agent", p.ua)
+
+	for name, values := range p.headers {
+		request.Header[name] = values
+	}
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		uErr := err.(*url.Error)
+		return nil, uErr.Err
+	}
+	defer response.Body.Close()
+
+	result := &remoteResponse{
+		source:  source,
+		subInfo: response.Header.Get("subscription-userinfo"),
+	}
+
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		result.notModified = true
+		return result, nil
+	default:
+		return nil, E.New("unexpected status: ", response.Status)
+	}
+
+	var body io.Reader = response.Body
+	if p.maxSize > 0 {
+		body = io.LimitReader(response.Body, p.maxSize+1)
+	}
+	result.content, err = io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.content) == 0 {
+		return nil, E.New("empty response")
+	}
+	if p.maxSize > 0 && int64(len(result.content)) > p.maxSize {
+		return nil, E.New("response body exceeds ", p.maxSize, " bytes")
+	}
+
+	if eTagHeader := response.Header.Get("Etag"); eTagHeader != "" {
+		source.lastEtag = eTagHeader
+	}
+	if lastModified := response.Header.Get("Last-Modified"); lastModified != "" {
+		source.lastModified = lastModified
+	}
+
+	return result, nil
+}
+
 func (p *RemoteProvider) UpdateProvider(ctx context.Context, router adapter.Router) error {
 	if err := p.updateProvider(ctx, router); err != nil {
 		return err
@@ -0,0 +1,169 @@
+package provider
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteFetchMirrors(t *testing.T) {
+	t.Parallel()
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer broken.Close()
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == "v1" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Etag", "v1")
+		w.Write([]byte("content"))
+	}))
+	defer mirror.Close()
+	p := &RemoteProvider{
+		myProviderAdapter: myProviderAdapter{logger: log.NewNOPFactory().Logger()},
+		sources: []*remoteSource{
+			{url: broken.URL, dialer: N.SystemDialer},
+			{url: mirror.URL, dialer: N.SystemDialer},
+		},
+	}
+	response, err := p.fetchSequential(context.Background(), true)
+	require.NoError(t, err)
+	require.Equal(t, mirror.URL, response.source.url)
+	require.Equal(t, "content", string(response.content))
+	require.Equal(t, "v1", p.sources[1].lastEtag)
+
+	p.lastURL = mirror.URL
+	response, err = p.fetchSequential(context.Background(), true)
+	require.NoError(t, err)
+	require.True(t, response.notModified)
+
+	p.sources = p.sources[:1]
+	_, err = p.fetchSequential(context.Background(), true)
+	require.Error(t, err)
+}
+
+func TestRemoteFetchRace(t *testing.T) {
+	t.Parallel()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+		w.Write([]byte("slow"))
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("fast"))
+	}))
+	defer fast.Close()
+	p := &RemoteProvider{
+		myProviderAdapter: myProviderAdapter{logger: log.NewNOPFactory().Logger()},
+		sources: []*remoteSource{
+			{url: slow.URL, dialer: N.SystemDialer},
+			{url: fast.URL, dialer: N.SystemDialer},
+		},
+	}
+	response, err := p.fetchRace(context.Background(), true)
+	require.NoError(t, err)
+	require.Equal(t, fast.URL, response.source.url)
+	require.Equal(t, "fast", string(response.content))
+}
+
+func TestRemoteFetchOptions(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("If-Modified-Since") == "Mon, 02 Jan 2006 15:04:05 GMT" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		w.Write([]byte("0123456789"))
+	}))
+	defer server.Close()
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	p, err := NewRemoteProvider(context.Background(), nil, log.NewNOPFactory().Logger(), option.OutboundProvider{
+		Type: C.ProviderTypeRemote,
+		Tag:  "remote",
+		RemoteOptions: option.RemoteProviderOptions{
+			Url:     server.URL,
+			Headers: option.HTTPHeader{"Authorization": {"Bearer token"}},
+			TLS: &option.OutboundTLSOptions{
+				Enabled:     true,
+				ServerName:  "example.com",
+				Certificate: option.Listable[string]{string(certificate)},
+			},
+			MaxSize: 16,
+		},
+	}, filepath.Join(t.TempDir(), "remote.txt"))
+	require.NoError(t, err)
+	p.sources[0].dialer = N.SystemDialer
+	response, err := p.fetchSequential(context.Background(), true)
+	require.NoError(t, err)
+	require.Equal(t, "0123456789", string(response.content))
+
+	p.lastURL = p.sources[0].url
+	response, err = p.fetchSequential(context.Background(), true)
+	require.NoError(t, err)
+	require.True(t, response.notModified)
+
+	content, err := p.Fetch(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "0123456789", string(content))
+
+	p.lastURL = ""
+	p.maxSize = 8
+	_, err = p.fetchSequential(context.Background(), true)
+	require.Error(t, err)
+	_, err = p.Fetch(context.Background())
+	require.Error(t, err)
+}
+
+func TestRemoteFetchALPN(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, []string{"http/1.1"}, downloadNextProtos(nil))
+	require.Equal(t, []string{"http/1.1"}, downloadNextProtos([]string{"h2"}))
+	require.Equal(t, []string{"http/1.1"}, downloadNextProtos([]string{"h2", "http/1.1"}))
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	p, err := NewRemoteProvider(context.Background(), nil, log.NewNOPFactory().Logger(), option.OutboundProvider{
+		Type: C.ProviderTypeRemote,
+		Tag:  "remote",
+		RemoteOptions: option.RemoteProviderOptions{
+			Url: server.URL,
+			TLS: &option.OutboundTLSOptions{
+				Enabled:     true,
+				ServerName:  "example.com",
+				ALPN:        option.Listable[string]{"h2", "http/1.1"},
+				Certificate: option.Listable[string]{string(certificate)},
+			},
+		},
+	}, filepath.Join(t.TempDir(), "remote.txt"))
+	require.NoError(t, err)
+	p.sources[0].dialer = N.SystemDialer
+	content, err := p.Fetch(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "HTTP/1.1", string(content))
+}
@@ -0,0 +1,107 @@
+package provider
+
+import (
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/transport/sip003"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+)
+
+// SIP008Config is the Shadowsocks online configuration delivery format,
+// see https://shadowsocks.org/doc/sip008.html.
+type SIP008Config struct {
+	Version int            `json:"version"`
+	Servers []SIP008Server `json:"servers"`
+}
+
+type SIP008Server struct {
+	ID         string          `json:"id,omitempty"`
+	Remarks    string          `json:"remarks,omitempty"`
+	Server     string          `json:"server"`
+	ServerPort json.RawMessage `json:"server_port"`
+	Password   string          `json:"password"`
+	Method     string          `json:"method"`
+	Plugin     string          `json:"plugin,omitempty"`
+	PluginOpts string          `json:"plugin_opts,omitempty"`
+}
+
+func newSIP008Parser(content string) ([]option.Outbound, []adapter.SkippedOutbound, error) {
+	var (
+		outbounds []option.Outbound
+		skipped   []adapter.SkippedOutbound
+		config    SIP008Config
+	)
+	err := json.Unmarshal([]byte(content), &config)
+	if err != nil {
+		return nil, nil, E.Cause(err, "decode sip008 config")
+	}
+	if config.Version != 1 {
+		return nil, nil, E.New("unsupported sip008 version: ", config.Version)
+	}
+	for i, server := range config.Servers {
+		outbound, err := newSIP008ServerParser(server)
+		if err != nil {
+			skipped = append(skipped, adapter.SkippedOutbound{
+				Index:  i,
+				Tag:    outbound.Tag,
+				Type:   C.TypeShadowsocks,
+				Reason: err.Error(),
+			})
+			continue
+		}
+		outbounds = append(outbounds, outbound)
+	}
+	return outbounds, skipped, nil
+}
+
+func newSIP008ServerParser(server SIP008Server) (option.Outbound, error) {
+	outbound := option.Outbound{
+		Type: C.TypeShadowsocks,
+		Tag:  server.Remarks,
+	}
+	if outbound.Tag == "" {
+		outbound.Tag = server.ID
+	}
+	if server.Server == "" {
+		return outbound, E.New("missing server")
+	}
+	if server.Method == "" {
+		return outbound, E.New("missing method")
+	}
+	options := option.ShadowsocksOutboundOptions{
+		Method:   server.Method,
+		Password: server.Password,
+	}
+	options.Server = server.Server
+	if len(server.ServerPort) == 0 {
+		return outbound, E.New("missing server port")
+	}
+	options.ServerPort = stringToUint16(strings.Trim(string(server.ServerPort), "\""))
+	if options.ServerPort == 0 {
+		return outbound, E.New("invalid server port: ", string(server.ServerPort))
+	}
+	if outbound.Tag == "" {
+		outbound.Tag = joinHostPort(options.Server, options.ServerPort)
+	}
+	if server.Plugin != "" {
+		switch server.Plugin {
+		case "obfs-local", "simple-obfs":
+			options.Plugin = "obfs-local"
+		default:
+			options.Plugin = server.Plugin
+		}
+		if !sip003.PluginExists(options.Plugin) {
+			return outbound, E.New("unsupported plugin: ", server.Plugin)
+		}
+		if _, err := sip003.ParsePluginOptions(server.PluginOpts); err != nil {
+			return outbound, E.Cause(err, "parse plugin_opts")
+		}
+		options.PluginOptions = server.PluginOpts
+	}
+	outbound.ShadowsocksOptions = options
+	return outbound, nil
+}
@@ -0,0 +1,105 @@
+package provider
+
+import (
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	F "github.com/sagernet/sing/common/format"
+)
+
+func (info SubInfo) expireTime() time.Time {
+	if info.expire <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(info.expire, 0)
+}
+
+// exhausted reports whether the traffic quota is used up or the subscription
+// has expired.
+func (info SubInfo) exhausted(now time.Time) bool {
+	if info.total > 0 && info.upload+info.download >= info.total {
+		return true
+	}
+	expireTime := info.expireTime()
+	return !expireTime.IsZero() && !now.Before(expireTime)
+}
+
+func (info SubInfo) warnings(options option.SubscriptionOptions, now time.Time) []string {
+	var warnings []string
+	if info.total > 0 {
+		used := info.upload + info.download
+		if used >= info.total {
+			warnings = append(warnings, "traffic quota exhausted")
+		} else if options.UsageWarning > 0 && used*100 >= info.total*int64(options.UsageWarning) {
+			warnings = append(warnings, F.ToString("traffic usage exceeded ", options.UsageWarning, "%"))
+		}
+	}
+	if expireTime := info.expireTime(); !expireTime.IsZero() {
+		if !now.Before(expireTime) {
+			warnings = append(warnings, "subscription expired")
+		} else if options.ExpireWarning > 0 && expireTime.Sub(now) <= time.Duration(options.ExpireWarning) {
+			warnings = append(warnings, "subscription expires at "+expireTime.Format(time.DateTime))
+		}
+	}
+	return warnings
+}
+
+func (a *myProviderAdapter) SubscriptionStatus() adapter.SubscriptionStatus {
+	a.subscriptionAccess.Lock()
+	defer a.subscriptionAccess.Unlock()
+	return adapter.SubscriptionStatus{
+		Exhausted: a.exhausted,
+		Excluded:  a.exhausted && a.subscription.ExcludeExhausted,
+		Warnings:  append([]string(nil), a.warnings...),
+	}
+}
+
+// checkSubscription updates the subscription status, logs new warnings and
+// lets groups pick outbounds again if the provider becomes exhausted or
+// available. It is scheduled again for the next expiry warning.
+func (a *myProviderAdapter) checkSubscription() {
+	a.subscriptionAccess.Lock()
+	now := time.Now()
+	warnings := a.subInfo.warnings(a.subscription, now)
+	for _, warning := range warnings {
+		if !common.Contains(a.warnings, warning) {
+			a.logger.Warn("outbound provider ", a.tag, ": ", warning)
+		}
+	}
+	a.warnings = warnings
+	exhausted := a.subInfo.exhausted(now)
+	changed := exhausted != a.exhausted
+	a.exhausted = exhausted
+	if a.subscriptionTimer != nil {
+		a.subscriptionTimer.Stop()
+		a.subscriptionTimer = nil
+	}
+	if expireTime := a.subInfo.expireTime(); !expireTime.IsZero() {
+		next := expireTime
+		if warnTime := expireTime.Add(-time.Duration(a.subscription.ExpireWarning)); warnTime.After(now) {
+			next = warnTime
+		}
+		if next.After(now) && a.ctx.Err() == nil {
+			a.subscriptionTimer = time.AfterFunc(next.Sub(now), a.checkSubscription)
+		}
+	}
+	a.subscriptionAccess.Unlock()
+	if !changed {
+		return
+	}
+	delta := adapter.OutboundProviderDelta{Reordered: true}
+	if a.subscription.ExcludeExhausted {
+		if exhausted {
+			a.logger.Warn("outbound provider ", a.tag, " excluded from groups")
+			delta.Removed = a.Outbounds()
+		} else {
+			delta.Added = a.Outbounds()
+		}
+	}
+	err := a.updateGroups(a.router, delta)
+	if err != nil {
+		a.logger.Error(err)
+	}
+}
@@ -0,0 +1,37 @@
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionWarnings(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	options := option.SubscriptionOptions{
+		UsageWarning:  80,
+		ExpireWarning: option.Duration(24 * time.Hour),
+	}
+	info := SubInfo{upload: 10, download: 60, total: 100}
+	require.False(t, info.exhausted(now))
+	require.Empty(t, info.warnings(options, now))
+
+	info.download = 75
+	require.False(t, info.exhausted(now))
+	require.Equal(t, []string{"traffic usage exceeded 80%"}, info.warnings(options, now))
+
+	info.download = 90
+	require.True(t, info.exhausted(now))
+	require.Equal(t, []string{"traffic quota exhausted"}, info.warnings(options, now))
+
+	info = SubInfo{expire: now.Add(time.Hour).Unix()}
+	require.False(t, info.exhausted(now))
+	require.Len(t, info.warnings(options, now), 1)
+	require.Empty(t, info.warnings(option.SubscriptionOptions{}, now))
+	require.True(t, info.exhausted(now.Add(2*time.Hour)))
+	require.Equal(t, []string{"subscription expired"}, info.warnings(options, now.Add(2*time.Hour)))
+}
@@ -10,6 +10,8 @@ import (
 	"os/user"
 	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
@@ -20,6 +22,7 @@ import (
 	"github.com/sagernet/sing-box/common/process"
 	"github.com/sagernet/sing-box/common/sniff"
 	"github.com/sagernet/sing-box/common/taskmonitor"
+	"github.com/sagernet/sing-box/common/tracing"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/experimental/libbox/platform"
 	"github.com/sagernet/sing-box/log"
@@ -44,6 +47,7 @@ import (
 	"github.com/sagernet/sing/common/uot"
 	"github.com/sagernet/sing/common/winpowrprof"
 	"github.com/sagernet/sing/service"
+	"github.com/sagernet/sing/service/filemanager"
 	"github.com/sagernet/sing/service/pause"
 )
 
@@ -59,8 +63,9 @@ type Router struct {
 	outboundByTag                      map[string]adapter.Outbound
 	outboundProviders                  []adapter.OutboundProvider
 	outboundProviderByTag              map[string]adapter.OutboundProvider
-	rules                              []adapter.Rule
+	ruleList                           *ruleList
 	routeRuleByUUID                    map[string]adapter.Rule
+	routeRuleOptions                   map[string]option.Rule
 	defaultDetour                      string
 	defaultOutboundForConnection       adapter.Outbound
 	defaultOutboundForPacketConnection adapter.Outbound
@@ -75,11 +80,14 @@ type Router struct {
 	stopFindProcess                    bool
 	dnsClient                          *dns.Client
 	defaultDomainStrategy              dns.DomainStrategy
-	dnsRules                           []adapter.DNSRule
+	ipv6Override                       atomic.Int32
 	dnsRuleByUUID                      map[string]adapter.DNSRule
+	dnsRuleOptions                     map[string]option.DNSRule
+	ruleAccess                         sync.RWMutex
+	rulesOverlayPath                   string
+	rulesOverlayBase                   string
 	ruleSets                           []adapter.RuleSet
 	ruleSetMap                         map[string]adapter.RuleSet
-	sniffOverrideRules                 map[string][]adapter.Rule
 	defaultTransport                   dns.Transport
 	transports                         []dns.Transport
 	transportMap                       map[string]dns.Transport
@@ -98,6 +106,7 @@ type Router struct {
 	packageManager                     tun.PackageManager
 	powerListener                      winpowrprof.EventListener
 	processSearcher                    process.Searcher
+	tracer                             *tracing.Tracer
 	timeService                        *ntp.Service
 	pauseManager                       pause.Manager
 	clashServer                        adapter.ClashServer
@@ -118,18 +127,34 @@ func NewRouter(
 	inbounds []option.Inbound,
 	platformInterface platform.Interface,
 ) (*Router, error) {
+	logger := logFactory.NewLogger("router")
+	var rulesOverlayPath, rulesOverlayBase string
+	if options.RulesOverlay != "" {
+		rulesOverlayPath = filemanager.BasePath(ctx, options.RulesOverlay)
+		var err error
+		options.Rules, dnsOptions.Rules, rulesOverlayBase, err = loadRulesOverlay(logger, rulesOverlayPath, options.Rules, dnsOptions.Rules)
+		if err != nil {
+			return nil, err
+		}
+	}
 	router := &Router{
 		ctx:                   ctx,
-		logger:                logFactory.NewLogger("router"),
+		logger:                logger,
 		dnsLogger:             logFactory.NewLogger("dns"),
 		overrideLogger:        logFactory.NewLogger("override"),
 		outboundByTag:         make(map[string]adapter.Outbound),
 		outboundProviderByTag: make(map[string]adapter.OutboundProvider),
-		rules:                 make([]adapter.Rule, 0, len(options.Rules)),
+		ruleList: &ruleList{
+			rules:              make([]adapter.Rule, 0, len(options.Rules)),
+			dnsRules:           make([]adapter.DNSRule, 0, len(dnsOptions.Rules)),
+			sniffOverrideRules: make(map[string][]adapter.Rule),
+		},
 		routeRuleByUUID:       make(map[string]adapter.Rule),
-		dnsRules:              make([]adapter.DNSRule, 0, len(dnsOptions.Rules)),
+		routeRuleOptions:      make(map[string]option.Rule),
 		dnsRuleByUUID:         make(map[string]adapter.DNSRule),
-		sniffOverrideRules:    make(map[string][]adapter.Rule),
+		dnsRuleOptions:        make(map[string]option.DNSRule),
+		rulesOverlayPath:      rulesOverlayPath,
+		rulesOverlayBase:      rulesOverlayBase,
 		ruleSetMap:            make(map[string]adapter.RuleSet),
 		needGeoIPDatabase:     hasRule(options.Rules, isGeoIPRule) || hasDNSRule(dnsOptions.Rules, isGeoIPDNSRule) || hasDNSFallbackRuleUseGeoIP(dnsOptions.Rules),
 		needGeositeDatabase:   hasRule(options.Rules, isGeositeRule) || hasDNSRule(dnsOptions.Rules, isGeositeDNSRule),
@@ -140,6 +165,7 @@ func NewRouter(
 		stopFindProcess:       options.FindProcess != nil && !*options.FindProcess,
 		defaultDetour:         options.Final,
 		defaultDomainStrategy: dns.DomainStrategy(dnsOptions.Strategy),
+		tracer:                tracing.NewTracer(),
 		interfaceFinder:       control.NewDefaultInterfaceFinder(),
 		stopAlwaysResolveUDP:  options.StopAlwaysResolveUDP,
 		autoDetectInterface:   options.AutoDetectInterface,
@@ -203,7 +229,7 @@ func NewRouter(
 			}
 			rules = append(rules, sniffOverrdideRule)
 		}
-		router.sniffOverrideRules[tag] = rules
+		router.ruleList.sniffOverrideRules[tag] = rules
 	}
 	for i, ruleOptions := range options.Rules {
 		routeRule, err := NewRule(router, router.logger, ruleOptions, true)
@@ -211,8 +237,9 @@ func NewRouter(
 			return nil, E.Cause(err, "parse rule[", i, "]")
 		}
 		uuid := routeRule.UUID()
-		router.rules = append(router.rules, routeRule)
+		router.ruleList.rules = append(router.ruleList.rules, routeRule)
 		router.routeRuleByUUID[uuid] = routeRule
+		router.routeRuleOptions[uuid] = ruleOptions
 	}
 	for i, dnsRuleOptions := range dnsOptions.Rules {
 		dnsRule, err := NewDNSRule(router, router.logger, dnsRuleOptions, true)
@@ -220,8 +247,9 @@ func NewRouter(
 			return nil, E.Cause(err, "parse dns rule[", i, "]")
 		}
 		uuid := dnsRule.UUID()
-		router.dnsRules = append(router.dnsRules, dnsRule)
+		router.ruleList.dnsRules = append(router.ruleList.dnsRules, dnsRule)
 		router.dnsRuleByUUID[uuid] = dnsRule
+		router.dnsRuleOptions[uuid] = dnsRuleOptions
 	}
 	for i, ruleSetOptions := range options.RuleSet {
 		if _, exists := router.ruleSetMap[ruleSetOptions.Tag]; exists {
@@ -526,7 +554,7 @@ func (r *Router) Initialize(inbounds []adapter.Inbound, outboundProviders []adap
 	r.outboundByTag = outboundByTag
 	r.outboundProviderByTag = outboundProviderByTag
 	r.outboundProviders = outboundProviders
-	for i, rule := range r.rules {
+	for i, rule := range r.ruleList.rules {
 		if _, loaded := outboundByTag[rule.Outbound()]; !loaded {
 			return E.New("outbound not found for rule[", i, "]: ", rule.Outbound())
 		}
@@ -598,19 +626,19 @@ func (r *Router) Start() error {
 		}
 	}
 	if r.needGeositeDatabase {
-		for _, rule := range r.rules {
+		for _, rule := range r.ruleList.rules {
 			err := rule.UpdateGeosite()
 			if err != nil {
 				r.logger.Error("failed to initialize geosite: ", err)
 			}
 		}
-		for _, rule := range r.dnsRules {
+		for _, rule := range r.ruleList.dnsRules {
 			err := rule.UpdateGeosite()
 			if err != nil {
 				r.logger.Error("failed to initialize geosite: ", err)
 			}
 		}
-		for _, rules := range r.sniffOverrideRules {
+		for _, rules := range r.ruleList.sniffOverrideRules {
 			for _, rule := range rules {
 				err := rule.UpdateGeosite()
 				if err != nil {
@@ -670,7 +698,7 @@ func (r *Router) Start() error {
 		r.packageManager = packageManager
 	}
 
-	for i, rule := range r.dnsRules {
+	for i, rule := range r.ruleList.dnsRules {
 		monitor.Start("initialize DNS rule[", i, "]")
 		err := rule.Start()
 		monitor.Finish()
@@ -678,7 +706,7 @@ func (r *Router) Start() error {
 			return E.Cause(err, "initialize DNS rule[", i, "]")
 		}
 	}
-	for in, rules := range r.sniffOverrideRules {
+	for in, rules := range r.ruleList.sniffOverrideRules {
 		for i, rule := range rules {
 			monitor.Start("initialize inbound[", in, "] sniff_overrride_rule[", i, "]")
 			err := rule.Start()
@@ -710,14 +738,17 @@ func (r *Router) Start() error {
 func (r *Router) Close() error {
 	monitor := taskmonitor.New(r.logger, C.StopTimeout)
 	var err error
-	for i, rule := range r.rules {
+	r.ruleAccess.RLock()
+	rules := r.ruleList
+	r.ruleAccess.RUnlock()
+	for i, rule := range rules.rules {
 		monitor.Start("close rule[", i, "]")
 		err = E.Append(err, rule.Close(), func(err error) error {
 			return E.Cause(err, "close rule[", i, "]")
 		})
 		monitor.Finish()
 	}
-	for i, rule := range r.dnsRules {
+	for i, rule := range rules.dnsRules {
 		monitor.Start("close dns rule[", i, "]")
 		err = E.Append(err, rule.Close(), func(err error) error {
 			return E.Cause(err, "close dns rule[", i, "]")
@@ -780,6 +811,9 @@ func (r *Router) Close() error {
 		})
 		monitor.Finish()
 	}
+	err = E.Append(err, r.tracer.Close(), func(err error) error {
+		return E.Cause(err, "close tracer")
+	})
 	return err
 }
 
@@ -860,7 +894,7 @@ func (r *Router) PostStart() error {
 		r.updateWIFIState()
 		monitor.Finish()
 	}
-	for i, rule := range r.rules {
+	for i, rule := range r.ruleList.rules {
 		monitor.Start("initialize rule[", i, "]")
 		err := rule.Start()
 		monitor.Finish()
@@ -948,7 +982,7 @@ func (r *Router) NeedWIFIState() bool {
 	return r.needWIFIState
 }
 
-func (r *Router) RouteConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+func (r *Router) RouteConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) (err error) {
 	if r.pauseManager.IsDevicePaused() {
 		return E.New("reject connection to ", metadata.Destination, " while device paused")
 	}
@@ -979,6 +1013,10 @@ func (r *Router) RouteConnection(ctx context.Context, conn net.Conn, metadata ad
 	}
 	conntrack.KillerCheck()
 	metadata.Network = N.NetworkTCP
+	ctx, trace := r.tracer.Start(ctx)
+	defer func() {
+		r.finishTrace(ctx, trace, &metadata, nil, err)
+	}()
 	switch metadata.Destination.Fqdn {
 	case mux.Destination.Fqdn:
 		return E.New("global multiplex is deprecated since sing-box v1.7.0, enable multiplex in inbound options instead.")
@@ -1065,9 +1103,9 @@ func (r *Router) RouteConnection(ctx context.Context, conn net.Conn, metadata ad
 	if metadata.Destination.IsFqdn() {
 		metadata.Destination.Fqdn = r.dnsClient.GetExactDomainFromHosts(ctx, metadata.Destination.Fqdn, false)
 		inboundStrategy := dns.DomainStrategy(metadata.InboundOptions.DomainStrategy)
-		strategy := inboundStrategy
+		strategy := r.limitDomainStrategy(inboundStrategy)
 		if strategy == dns.DomainStrategyAsIS {
-			strategy = r.defaultDomainStrategy
+			strategy = r.domainStrategy()
 		}
 		if responseAddrs := r.dnsClient.GetAddrsFromHosts(ctx, metadata.Destination.Fqdn, strategy, false); len(responseAddrs) > 0 {
 			metadata.DestinationAddresses = responseAddrs
@@ -1101,10 +1139,17 @@ func (r *Router) RouteConnection(ctx context.Context, conn net.Conn, metadata ad
 			conn = statsService.RoutedConnection(metadata.Inbound, detour.Tag(), metadata.User, conn)
 		}
 	}
-	return detour.NewConnection(ctx, conn, metadata)
+	if trace != nil {
+		conn = &tracedConn{Conn: conn, finish: func(err error) {
+			r.finishTrace(ctx, trace, &metadata, detour, err)
+		}}
+	}
+	err = detour.NewConnection(ctx, conn, metadata)
+	r.finishTrace(ctx, trace, &metadata, detour, err)
+	return err
 }
 
-func (r *Router) RoutePacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
+func (r *Router) RoutePacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) (err error) {
 	if r.pauseManager.IsDevicePaused() {
 		return E.New("reject packet connection to ", metadata.Destination, " while device paused")
 	}
@@ -1134,6 +1179,10 @@ func (r *Router) RoutePacketConnection(ctx context.Context, conn N.PacketConn, m
 	}
 	conntrack.KillerCheck()
 	metadata.Network = N.NetworkUDP
+	ctx, trace := r.tracer.Start(ctx)
+	defer func() {
+		r.finishTrace(ctx, trace, &metadata, nil, err)
+	}()
 
 	var destOverride bool
 
@@ -1246,9 +1295,9 @@ func (r *Router) RoutePacketConnection(ctx context.Context, conn N.PacketConn, m
 	if metadata.Destination.IsFqdn() {
 		metadata.Destination.Fqdn = r.dnsClient.GetExactDomainFromHosts(ctx, metadata.Destination.Fqdn, false)
 		inboundStrategy := dns.DomainStrategy(metadata.InboundOptions.DomainStrategy)
-		strategy := inboundStrategy
+		strategy := r.limitDomainStrategy(inboundStrategy)
 		if strategy == dns.DomainStrategyAsIS {
-			strategy = r.defaultDomainStrategy
+			strategy = r.domainStrategy()
 		}
 		if responseAddrs := r.dnsClient.GetAddrsFromHosts(ctx, metadata.Destination.Fqdn, strategy, false); len(responseAddrs) > 0 {
 			metadata.DestinationAddresses = responseAddrs
@@ -1293,7 +1342,14 @@ func (r *Router) RoutePacketConnection(ctx context.Context, conn N.PacketConn, m
 		metadata.DestinationAddresses = addresses
 		r.dnsLogger.DebugContext(ctx, "resolved [", strings.Join(F.MapToString(metadata.DestinationAddresses), " "), "]")
 	}
-	return detour.NewPacketConnection(ctx, conn, metadata)
+	if trace != nil {
+		conn = &tracedPacketConn{PacketConn: conn, finish: func(err error) {
+			r.finishTrace(ctx, trace, &metadata, detour, err)
+		}}
+	}
+	err = detour.NewPacketConnection(ctx, conn, metadata)
+	r.finishTrace(ctx, trace, &metadata, detour, err)
+	return err
 }
 
 func (r *Router) mustResolve(detour adapter.Outbound, metadata *adapter.InboundContext) bool {
@@ -1371,7 +1427,10 @@ func (r *Router) match0(ctx context.Context, metadata *adapter.InboundContext, d
 			metadata.DestinationAddresses = []netip.Addr{}
 		}
 	}()
-	for i, rule := range r.rules {
+	rules := r.acquireRules()
+	defer rules.release()
+	trace := tracing.TraceFromContext(ctx)
+	for i, rule := range rules.rules {
 		if rule.Disabled() {
 			continue
 		}
@@ -1386,7 +1445,11 @@ func (r *Router) match0(ctx context.Context, metadata *adapter.InboundContext, d
 			}
 			metadata.ResetRuleCache()
 		}
-		if rule.Match(metadata) {
+		matched := rule.Match(metadata)
+		if trace != nil {
+			trace.AddRule(tracing.RuleResult{Index: i, Rule: rule.String(), Outbound: rule.Outbound(), Matched: matched})
+		}
+		if matched {
 			detour := rule.Outbound()
 			r.logger.DebugContext(ctx, "match[", i, "] ", rule.String(), " => ", detour)
 			var loaded bool
@@ -1487,19 +1550,27 @@ func (r *Router) DefaultMark() uint32 {
 }
 
 func (r *Router) Rules() []adapter.Rule {
-	return r.rules
+	r.ruleAccess.RLock()
+	defer r.ruleAccess.RUnlock()
+	return r.ruleList.rules
 }
 
 func (r *Router) Rule(uuid string) (adapter.Rule, bool) {
+	r.ruleAccess.RLock()
+	defer r.ruleAccess.RUnlock()
 	rule, exists := r.routeRuleByUUID[uuid]
 	return rule, exists
 }
 
 func (r *Router) DNSRules() []adapter.DNSRule {
-	return r.dnsRules
+	r.ruleAccess.RLock()
+	defer r.ruleAccess.RUnlock()
+	return r.ruleList.dnsRules
 }
 
 func (r *Router) DNSRule(uuid string) (adapter.DNSRule, bool) {
+	r.ruleAccess.RLock()
+	defer r.ruleAccess.RUnlock()
 	rule, exists := r.dnsRuleByUUID[uuid]
 	return rule, exists
 }
@@ -1524,6 +1595,10 @@ func (r *Router) PackageManager() tun.PackageManager {
 	return r.packageManager
 }
 
+func (r *Router) Tracer() *tracing.Tracer {
+	return r.tracer
+}
+
 func (r *Router) ClashServer() adapter.ClashServer {
 	return r.clashServer
 }
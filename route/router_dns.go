@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/tracing"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-dns"
 	"github.com/sagernet/sing/common/cache"
@@ -42,8 +43,10 @@ func (r *Router) matchDNS(ctx context.Context, allowFakeIP bool, index int, isAd
 	if metadata == nil {
 		panic("no context")
 	}
-	if index < len(r.dnsRules) {
-		dnsRules := r.dnsRules
+	rules := r.acquireRules()
+	defer rules.release()
+	if index < len(rules.dnsRules) {
+		dnsRules := rules.dnsRules
 		if index != -1 {
 			dnsRules = dnsRules[index+1:]
 		}
@@ -68,6 +71,9 @@ func (r *Router) matchDNS(ctx context.Context, allowFakeIP bool, index int, isAd
 					ruleIndex += index + 1
 				}
 				r.dnsLogger.DebugContext(ctx, "match[", ruleIndex, "] ", rule.String(), " => ", detour)
+				if trace := tracing.TraceFromContext(ctx); trace != nil {
+					trace.AddDNS(tracing.DNSDecision{Domain: metadata.Domain, RuleIndex: ruleIndex, Rule: rule.String(), Server: detour, FakeIP: isFakeIP})
+				}
 				if isFakeIP {
 					ctx = dns.ContextWithDisableCache(ctx, true)
 					ctx = dns.ContextWithRewriteTTL(ctx, 1)
@@ -82,17 +88,20 @@ func (r *Router) matchDNS(ctx context.Context, allowFakeIP bool, index int, isAd
 					ctx = dns.ContextWithClientSubnet(ctx, *clientSubnet)
 				}
 				if domainStrategy, dsLoaded := r.transportDomainStrategy[transport]; dsLoaded {
-					return ctx, transport, domainStrategy, rule, ruleIndex, isFakeIP
+					return ctx, transport, r.limitDomainStrategy(domainStrategy), rule, ruleIndex, isFakeIP
 				} else {
-					return ctx, transport, r.defaultDomainStrategy, rule, ruleIndex, isFakeIP
+					return ctx, transport, r.domainStrategy(), rule, ruleIndex, isFakeIP
 				}
 			}
 		}
 	}
+	if trace := tracing.TraceFromContext(ctx); trace != nil {
+		trace.AddDNS(tracing.DNSDecision{Domain: metadata.Domain, RuleIndex: -1, Server: r.defaultTransport.Name()})
+	}
 	if domainStrategy, dsLoaded := r.transportDomainStrategy[r.defaultTransport]; dsLoaded {
-		return ctx, r.defaultTransport, domainStrategy, nil, -1, false
+		return ctx, r.defaultTransport, r.limitDomainStrategy(domainStrategy), nil, -1, false
 	} else {
-		return ctx, r.defaultTransport, r.defaultDomainStrategy, nil, -1, false
+		return ctx, r.defaultTransport, r.domainStrategy(), nil, -1, false
 	}
 }
 
@@ -123,6 +132,9 @@ func (r *Router) matchFallbackRules(ctx context.Context, domain string, addrs []
 				}
 			}
 			r.dnsLogger.DebugContext(ctx, "match fallback_rule: ", rule.String())
+			if trace := tracing.TraceFromContext(ctx); trace != nil {
+				trace.AddDNS(tracing.DNSDecision{Domain: domain, RuleIndex: -1, Rule: rule.String(), Fallback: true, Server: detour, FakeIP: isFakeIP})
+			}
 			if isFakeIP {
 				ctx = dns.ContextWithDisableCache(ctx, true)
 				ctx = dns.ContextWithRewriteTTL(ctx, 1)
@@ -139,9 +151,9 @@ func (r *Router) matchFallbackRules(ctx context.Context, domain string, addrs []
 			if detour == "" {
 				return ctx, nil, dns.DomainStrategyAsIS, rule, false
 			} else if domainStrategy, dsLoaded := r.transportDomainStrategy[transport]; dsLoaded {
-				return ctx, transport, domainStrategy, rule, isFakeIP
+				return ctx, transport, r.limitDomainStrategy(domainStrategy), rule, isFakeIP
 			} else {
-				return ctx, transport, r.defaultDomainStrategy, rule, isFakeIP
+				return ctx, transport, r.domainStrategy(), rule, isFakeIP
 			}
 		}
 	}
@@ -201,7 +213,7 @@ func (r *Router) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, er
 			response.Answer = append(records, response.Answer...)
 		}()
 	}
-	if response = r.dnsClient.SearchIPHosts(ctx, message, r.defaultDomainStrategy); response != nil {
+	if response = r.dnsClient.SearchIPHosts(ctx, message, r.domainStrategy()); response != nil {
 		return response, nil
 	}
 	var needUpdate bool
@@ -348,6 +360,7 @@ func (r *Router) LookupDefault(ctx context.Context, domain string) ([]netip.Addr
 }
 
 func (r *Router) lookup(ctx context.Context, domain string, strategy dns.DomainStrategy) ([]netip.Addr, error) {
+	strategy = r.limitDomainStrategy(strategy)
 	if responseAddrs, cached, needUpdate := r.dnsClient.LookupCache(ctx, domain, strategy); cached {
 		if needUpdate {
 			go r.lookupFunc(createUpdateCacheContext(ctx), domain, strategy, true)
@@ -456,6 +469,13 @@ func (r *Router) lookupFunc(ctx context.Context, domain string, strategy dns.Dom
 	if err == nil {
 		r.dnsLogger.InfoContext(ctx, "finally lookup succeed for ", domain, ": ", strings.Join(F.MapToString(responseAddrs), " "))
 	}
+	if trace := tracing.TraceFromContext(ctx); trace != nil {
+		decision := tracing.DNSDecision{Domain: domain, RuleIndex: ruleIndex, Addresses: F.MapToString(responseAddrs)}
+		if err != nil {
+			decision.Error = err.Error()
+		}
+		trace.AddDNS(decision)
+	}
 	return responseAddrs, err
 }
 
@@ -470,6 +490,61 @@ func (r *Router) ClearDNSCache() {
 	}
 }
 
+const (
+	ipv6OverrideNone int32 = iota
+	ipv6OverrideDisabled
+	ipv6OverrideEnabled
+)
+
+func (r *Router) IPv6Enabled() bool {
+	switch r.ipv6Override.Load() {
+	case ipv6OverrideDisabled:
+		return false
+	case ipv6OverrideEnabled:
+		return true
+	default:
+		return r.defaultDomainStrategy != dns.DomainStrategyUseIPv4
+	}
+}
+
+// SetIPv6Enabled forces ipv4_only for all lookups if disabled, or relaxes a
+// configured ipv4_only default strategy to prefer_ipv4 if enabled. Strategies
+// set by inbounds, outbounds and DNS servers are only restricted.
+func (r *Router) SetIPv6Enabled(enabled bool) {
+	if enabled == r.IPv6Enabled() {
+		return
+	}
+	if enabled {
+		r.ipv6Override.Store(ipv6OverrideEnabled)
+	} else {
+		r.ipv6Override.Store(ipv6OverrideDisabled)
+	}
+	r.ClearDNSCache()
+}
+
+// domainStrategy returns the default domain strategy with the IPv6 setting
+// applied.
+func (r *Router) domainStrategy() dns.DomainStrategy {
+	switch r.ipv6Override.Load() {
+	case ipv6OverrideDisabled:
+		return dns.DomainStrategyUseIPv4
+	case ipv6OverrideEnabled:
+		if r.defaultDomainStrategy == dns.DomainStrategyUseIPv4 {
+			return dns.DomainStrategyPreferIPv4
+		}
+	}
+	return r.defaultDomainStrategy
+}
+
+// limitDomainStrategy returns ipv4_only instead of strategy if IPv6 is
+// disabled.
+func (r *Router) limitDomainStrategy(strategy dns.DomainStrategy) dns.DomainStrategy {
+	if r.ipv6Override.Load() == ipv6OverrideDisabled {
+		return dns.DomainStrategyUseIPv4
+	}
+	return strategy
+}
+
 func isAddressQuery(message *mDNS.Msg) bool {
 	for _, question := range message.Question {
 		if question.Qtype == mDNS.TypeA || question.Qtype == mDNS.TypeAAAA || question.Qtype == mDNS.TypeHTTPS {
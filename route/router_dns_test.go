@@ -0,0 +1,58 @@
+package route
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-dns"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetIPv6Enabled(t *testing.T) {
+	t.Parallel()
+	router := newTestRouter(t, nil, []option.DNSRule{
+		parseTestDNSRule(t, `{"domain": "a.com", "server": "remote"}`),
+	}, "")
+	router.defaultDomainStrategy = dns.DomainStrategyUseIPv4
+	router.transportDomainStrategy = map[dns.Transport]dns.DomainStrategy{
+		router.transportMap["remote"]: dns.DomainStrategyPreferIPv6,
+	}
+	matchStrategy := func(domain string) dns.DomainStrategy {
+		metadata := adapter.InboundContext{Domain: domain}
+		_, _, strategy, _, _, _ := router.matchDNS(adapter.WithContext(context.Background(), &metadata), true, -1, true)
+		return strategy
+	}
+
+	require.False(t, router.IPv6Enabled())
+	require.Equal(t, dns.DomainStrategyUseIPv4, matchStrategy("b.com"))
+	require.Equal(t, dns.DomainStrategyPreferIPv6, matchStrategy("a.com"))
+
+	router.SetIPv6Enabled(true)
+	require.True(t, router.IPv6Enabled())
+	require.Equal(t, dns.DomainStrategyPreferIPv4, matchStrategy("b.com"))
+	require.Equal(t, dns.DomainStrategyPreferIPv6, matchStrategy("a.com"))
+	require.Equal(t, dns.DomainStrategyUseIPv6, router.limitDomainStrategy(dns.DomainStrategyUseIPv6))
+
+	router.SetIPv6Enabled(false)
+	require.False(t, router.IPv6Enabled())
+	require.Equal(t, dns.DomainStrategyUseIPv4, matchStrategy("b.com"))
+	require.Equal(t, dns.DomainStrategyUseIPv4, matchStrategy("a.com"))
+	require.Equal(t, dns.DomainStrategyUseIPv4, router.limitDomainStrategy(dns.DomainStrategyUseIPv6))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			router.SetIPv6Enabled(i%2 == 0)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		matchStrategy("a.com")
+	}
+	wg.Wait()
+}
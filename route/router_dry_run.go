@@ -0,0 +1,90 @@
+package route
+
+import (
+	"context"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+// TestRoute matches metadata against route and DNS rules like a new
+// connection without resolving or opening it, so IP rules are only matched if
+// addresses are given.
+func (r *Router) TestRoute(ctx context.Context, metadata adapter.InboundContext) (*adapter.RouteTestResult, error) {
+	if metadata.Inbound != "" {
+		inbound, loaded := r.inboundByTag[metadata.Inbound]
+		if !loaded {
+			return nil, E.New("inbound not found: ", metadata.Inbound)
+		}
+		metadata.InboundType = inbound.Type()
+	}
+	var defaultOutbound adapter.Outbound
+	switch metadata.Network {
+	case "", N.NetworkTCP:
+		metadata.Network = N.NetworkTCP
+		defaultOutbound = r.defaultOutboundForConnection
+	case N.NetworkUDP:
+		defaultOutbound = r.defaultOutboundForPacketConnection
+	default:
+		return nil, E.New("unknown network: ", metadata.Network)
+	}
+	if r.fakeIPStore != nil && r.fakeIPStore.Contains(metadata.Destination.Addr) {
+		domain, loaded := r.fakeIPStore.Lookup(metadata.Destination.Addr)
+		if loaded {
+			metadata.OriginDestination = metadata.Destination
+			metadata.Destination = M.Socksaddr{
+				Fqdn: domain,
+				Port: metadata.Destination.Port,
+			}
+			metadata.DNSMode = C.DNSModeFakeIP
+		}
+	}
+	if metadata.Destination.IsIPv4() {
+		metadata.IPVersion = 4
+	} else if metadata.Destination.IsIPv6() {
+		metadata.IPVersion = 6
+	}
+	result := &adapter.RouteTestResult{
+		RuleIndex:    -1,
+		DNSRuleIndex: -1,
+	}
+	rules := r.acquireRules()
+	defer rules.release()
+	detour := defaultOutbound
+	for i, rule := range rules.rules {
+		if rule.Disabled() {
+			continue
+		}
+		metadata.ResetRuleCache()
+		if !rule.Match(&metadata) {
+			continue
+		}
+		outbound, loaded := r.Outbound(rule.Outbound())
+		if !loaded {
+			continue
+		}
+		result.RuleIndex = i
+		result.Rule = rule.String()
+		detour = outbound
+		break
+	}
+	result.Outbound = detour.Tag()
+	result.Chain = outboundChain(detour, metadata.Network)
+	if metadata.Destination.IsFqdn() {
+		dnsMetadata := metadata
+		dnsMetadata.Domain = metadata.Destination.Fqdn
+		dnsMetadata.Destination = M.Socksaddr{}
+		dnsMetadata.DestinationAddresses = nil
+		_, transport, _, rule, ruleIndex, isFakeIP := r.matchDNS(adapter.WithContext(ctx, &dnsMetadata), true, -1, true)
+		result.DNSServer = transport.Name()
+		if rule != nil {
+			result.DNSRuleIndex = ruleIndex
+			result.DNSRule = rule.String()
+		}
+		result.FakeIP = isFakeIP
+	}
+	return result, nil
+}
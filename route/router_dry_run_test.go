@@ -0,0 +1,111 @@
+package route
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/process"
+	"github.com/sagernet/sing-box/option"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTestRoute(t *testing.T) {
+	t.Parallel()
+	router := newTestRouter(t, []option.Rule{
+		parseTestRule(t, `{"domain": "a.com", "outbound": "block"}`),
+		parseTestRule(t, `{"ip_cidr": "10.0.0.0/8", "outbound": "block"}`),
+		parseTestRule(t, `{"process_name": "curl", "outbound": "block"}`),
+		parseTestRule(t, `{"inbound": "mixed-in", "outbound": "block"}`),
+	}, []option.DNSRule{
+		parseTestDNSRule(t, `{"domain": "a.com", "server": "remote"}`),
+	}, "")
+	for _, testCase := range []struct {
+		name         string
+		metadata     adapter.InboundContext
+		ruleIndex    int
+		outbound     string
+		dnsRuleIndex int
+		dnsServer    string
+	}{
+		{
+			name:         "domain",
+			metadata:     adapter.InboundContext{Destination: M.ParseSocksaddrHostPort("a.com", 443)},
+			ruleIndex:    0,
+			outbound:     "block",
+			dnsRuleIndex: 0,
+			dnsServer:    "remote",
+		},
+		{
+			name:         "final",
+			metadata:     adapter.InboundContext{Destination: M.ParseSocksaddrHostPort("b.com", 443)},
+			ruleIndex:    -1,
+			outbound:     "direct",
+			dnsRuleIndex: -1,
+			dnsServer:    "local",
+		},
+		{
+			name:         "ip",
+			metadata:     adapter.InboundContext{Destination: M.ParseSocksaddrHostPort("10.0.0.1", 443)},
+			ruleIndex:    1,
+			outbound:     "block",
+			dnsRuleIndex: -1,
+		},
+		{
+			name: "resolved domain",
+			metadata: adapter.InboundContext{
+				Destination:          M.ParseSocksaddrHostPort("b.com", 443),
+				DestinationAddresses: []netip.Addr{netip.MustParseAddr("10.0.0.1")},
+			},
+			ruleIndex:    1,
+			outbound:     "block",
+			dnsRuleIndex: -1,
+			dnsServer:    "local",
+		},
+		{
+			name: "process",
+			metadata: adapter.InboundContext{
+				Destination: M.ParseSocksaddrHostPort("b.com", 443),
+				ProcessInfo: &process.Info{ProcessPath: "/usr/bin/curl"},
+			},
+			ruleIndex:    2,
+			outbound:     "block",
+			dnsRuleIndex: -1,
+			dnsServer:    "local",
+		},
+		{
+			name: "inbound",
+			metadata: adapter.InboundContext{
+				Inbound:     "mixed-in",
+				Network:     N.NetworkUDP,
+				Destination: M.ParseSocksaddrHostPort("b.com", 443),
+			},
+			ruleIndex:    3,
+			outbound:     "block",
+			dnsRuleIndex: -1,
+			dnsServer:    "local",
+		},
+	} {
+		result, err := router.TestRoute(context.Background(), testCase.metadata)
+		require.NoError(t, err, testCase.name)
+		require.Equal(t, testCase.ruleIndex, result.RuleIndex, testCase.name)
+		require.Equal(t, testCase.outbound, result.Outbound, testCase.name)
+		require.Equal(t, []string{testCase.outbound}, result.Chain, testCase.name)
+		require.Equal(t, testCase.dnsRuleIndex, result.DNSRuleIndex, testCase.name)
+		require.Equal(t, testCase.dnsServer, result.DNSServer, testCase.name)
+	}
+	_, err := router.TestRoute(context.Background(), adapter.InboundContext{
+		Inbound:     "unknown",
+		Destination: M.ParseSocksaddrHostPort("a.com", 443),
+	})
+	require.Error(t, err)
+	_, err = router.TestRoute(context.Background(), adapter.InboundContext{
+		Network:     "icmp",
+		Destination: M.ParseSocksaddrHostPort("a.com", 443),
+	})
+	require.Error(t, err)
+}
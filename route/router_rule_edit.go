@@ -0,0 +1,429 @@
+package route
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"sync"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/service/filemanager"
+)
+
+// ruleList holds the rules used for matching, edits publish a new list
+// instead of modifying it, so matching can use the list it has acquired
+// without holding ruleAccess.
+type ruleList struct {
+	rules              []adapter.Rule
+	dnsRules           []adapter.DNSRule
+	sniffOverrideRules map[string][]adapter.Rule
+	readers            sync.WaitGroup
+}
+
+func (l *ruleList) clone() *ruleList {
+	return &ruleList{
+		rules:              l.rules,
+		dnsRules:           l.dnsRules,
+		sniffOverrideRules: l.sniffOverrideRules,
+	}
+}
+
+func (l *ruleList) release() {
+	l.readers.Done()
+}
+
+// acquireRules returns the current rule list, release must be called after
+// matching.
+func (r *Router) acquireRules() *ruleList {
+	r.ruleAccess.RLock()
+	defer r.ruleAccess.RUnlock()
+	rules := r.ruleList
+	rules.readers.Add(1)
+	return rules
+}
+
+// replaceRuleList publishes rules and closes replaced rules once readers of
+// the previous list have finished, it must be called with ruleAccess held.
+func (r *Router) replaceRuleList(rules *ruleList, replaced ...adapter.Service) {
+	oldRules := r.ruleList
+	r.ruleList = rules
+	if len(replaced) == 0 {
+		return
+	}
+	go func() {
+		oldRules.readers.Wait()
+		for _, rule := range replaced {
+			r.closeRule(rule)
+		}
+	}()
+}
+
+func readRulesOverlay(path string) (*option.RulesOverlay, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	overlay, err := json.UnmarshalExtended[option.RulesOverlay](content)
+	if err != nil {
+		return nil, err
+	}
+	return &overlay, nil
+}
+
+// loadRulesOverlay returns the rules to use on start and the hash of the
+// configuration rules to record in the overlay. Rules of the overlay replace
+// the configuration rules only if they were edited on the same configuration
+// rules, so later changes of the configuration are not hidden by the overlay.
+func loadRulesOverlay(logger log.Logger, path string, rules []option.Rule, dnsRules []option.DNSRule) ([]option.Rule, []option.DNSRule, string, error) {
+	baseHash, err := rulesHash(rules, dnsRules)
+	if err != nil {
+		return nil, nil, "", E.Cause(err, "hash rules")
+	}
+	overlay, err := readRulesOverlay(path)
+	if err != nil {
+		return nil, nil, "", E.Cause(err, "read rules overlay")
+	}
+	if overlay == nil {
+		return rules, dnsRules, baseHash, nil
+	}
+	if overlay.BaseHash != "" && overlay.BaseHash != baseHash {
+		logger.Warn("route and DNS rules of the configuration changed since rules overlay ", path, " was saved, the overlay is ignored and will be replaced by the next rule edit")
+		return rules, dnsRules, baseHash, nil
+	}
+	logger.Warn("route and DNS rules are loaded from rules overlay ", path, " instead of the configuration, remove the file to reset them")
+	return overlay.Rules, overlay.DNSRules, baseHash, nil
+}
+
+func rulesHash(rules []option.Rule, dnsRules []option.DNSRule) (string, error) {
+	content, err := json.Marshal(option.RulesOverlay{Rules: rules, DNSRules: dnsRules})
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+func (r *Router) InsertRule(index int, options option.Rule) (adapter.Rule, error) {
+	r.ruleAccess.Lock()
+	defer r.ruleAccess.Unlock()
+	rule, err := r.newRule(options)
+	if err != nil {
+		return nil, err
+	}
+	rules := r.ruleList.clone()
+	rules.rules = insertRule(rules.rules, index, rule)
+	r.routeRuleOptions[rule.UUID()] = options
+	err = r.saveRulesOverlay(rules)
+	if err != nil {
+		delete(r.routeRuleOptions, rule.UUID())
+		r.closeRule(rule)
+		return nil, err
+	}
+	r.routeRuleByUUID[rule.UUID()] = rule
+	r.replaceRuleList(rules)
+	return rule, nil
+}
+
+func (r *Router) UpdateRule(uuid string, options option.Rule) (adapter.Rule, error) {
+	r.ruleAccess.Lock()
+	defer r.ruleAccess.Unlock()
+	index := ruleIndex(r.ruleList.rules, uuid)
+	if index == -1 {
+		return nil, E.New("rule not found: ", uuid)
+	}
+	rule, err := r.newRule(options)
+	if err != nil {
+		return nil, err
+	}
+	rules := r.ruleList.clone()
+	oldRule := rules.rules[index]
+	rules.rules = insertRule(removeRule(rules.rules, index), index, rule)
+	r.routeRuleOptions[rule.UUID()] = options
+	err = r.saveRulesOverlay(rules)
+	if err != nil {
+		delete(r.routeRuleOptions, rule.UUID())
+		r.closeRule(rule)
+		return nil, err
+	}
+	delete(r.routeRuleByUUID, uuid)
+	delete(r.routeRuleOptions, uuid)
+	r.routeRuleByUUID[rule.UUID()] = rule
+	r.replaceRuleList(rules, oldRule)
+	return rule, nil
+}
+
+func (r *Router) MoveRule(uuid string, index int) error {
+	r.ruleAccess.Lock()
+	defer r.ruleAccess.Unlock()
+	oldIndex := ruleIndex(r.ruleList.rules, uuid)
+	if oldIndex == -1 {
+		return E.New("rule not found: ", uuid)
+	}
+	rules := r.ruleList.clone()
+	rule := rules.rules[oldIndex]
+	rules.rules = insertRule(removeRule(rules.rules, oldIndex), index, rule)
+	err := r.saveRulesOverlay(rules)
+	if err != nil {
+		return err
+	}
+	r.replaceRuleList(rules)
+	return nil
+}
+
+func (r *Router) RemoveRule(uuid string) error {
+	r.ruleAccess.Lock()
+	defer r.ruleAccess.Unlock()
+	index := ruleIndex(r.ruleList.rules, uuid)
+	if index == -1 {
+		return E.New("rule not found: ", uuid)
+	}
+	rules := r.ruleList.clone()
+	rule := rules.rules[index]
+	rules.rules = removeRule(rules.rules, index)
+	err := r.saveRulesOverlay(rules)
+	if err != nil {
+		return err
+	}
+	delete(r.routeRuleByUUID, uuid)
+	delete(r.routeRuleOptions, uuid)
+	r.replaceRuleList(rules, rule)
+	return nil
+}
+
+func (r *Router) InsertDNSRule(index int, options option.DNSRule) (adapter.DNSRule, error) {
+	r.ruleAccess.Lock()
+	defer r.ruleAccess.Unlock()
+	rule, err := r.newDNSRule(options)
+	if err != nil {
+		return nil, err
+	}
+	rules := r.ruleList.clone()
+	rules.dnsRules = insertRule(rules.dnsRules, index, rule)
+	r.dnsRuleOptions[rule.UUID()] = options
+	err = r.saveRulesOverlay(rules)
+	if err != nil {
+		delete(r.dnsRuleOptions, rule.UUID())
+		r.closeRule(rule)
+		return nil, err
+	}
+	r.dnsRuleByUUID[rule.UUID()] = rule
+	r.replaceRuleList(rules)
+	return rule, nil
+}
+
+func (r *Router) UpdateDNSRule(uuid string, options option.DNSRule) (adapter.DNSRule, error) {
+	r.ruleAccess.Lock()
+	defer r.ruleAccess.Unlock()
+	index := ruleIndex(r.ruleList.dnsRules, uuid)
+	if index == -1 {
+		return nil, E.New("DNS rule not found: ", uuid)
+	}
+	rule, err := r.newDNSRule(options)
+	if err != nil {
+		return nil, err
+	}
+	rules := r.ruleList.clone()
+	oldRule := rules.dnsRules[index]
+	rules.dnsRules = insertRule(removeRule(rules.dnsRules, index), index, rule)
+	r.dnsRuleOptions[rule.UUID()] = options
+	err = r.saveRulesOverlay(rules)
+	if err != nil {
+		delete(r.dnsRuleOptions, rule.UUID())
+		r.closeRule(rule)
+		return nil, err
+	}
+	delete(r.dnsRuleByUUID, uuid)
+	delete(r.dnsRuleOptions, uuid)
+	r.dnsRuleByUUID[rule.UUID()] = rule
+	r.replaceRuleList(rules, oldRule)
+	return rule, nil
+}
+
+func (r *Router) MoveDNSRule(uuid string, index int) error {
+	r.ruleAccess.Lock()
+	defer r.ruleAccess.Unlock()
+	oldIndex := ruleIndex(r.ruleList.dnsRules, uuid)
+	if oldIndex == -1 {
+		return E.New("DNS rule not found: ", uuid)
+	}
+	rules := r.ruleList.clone()
+	rule := rules.dnsRules[oldIndex]
+	rules.dnsRules = insertRule(removeRule(rules.dnsRules, oldIndex), index, rule)
+	err := r.saveRulesOverlay(rules)
+	if err != nil {
+		return err
+	}
+	r.replaceRuleList(rules)
+	return nil
+}
+
+func (r *Router) RemoveDNSRule(uuid string) error {
+	r.ruleAccess.Lock()
+	defer r.ruleAccess.Unlock()
+	index := ruleIndex(r.ruleList.dnsRules, uuid)
+	if index == -1 {
+		return E.New("DNS rule not found: ", uuid)
+	}
+	rules := r.ruleList.clone()
+	rule := rules.dnsRules[index]
+	rules.dnsRules = removeRule(rules.dnsRules, index)
+	err := r.saveRulesOverlay(rules)
+	if err != nil {
+		return err
+	}
+	delete(r.dnsRuleByUUID, uuid)
+	delete(r.dnsRuleOptions, uuid)
+	r.replaceRuleList(rules, rule)
+	return nil
+}
+
+// newRule creates and starts a route rule, rejecting conditions that need
+// resources only prepared on start.
+func (r *Router) newRule(options option.Rule) (adapter.Rule, error) {
+	if !r.started {
+		return nil, E.New("router not started")
+	}
+	rules := []option.Rule{options}
+	if hasRule(rules, isGeositeRule) {
+		return nil, E.New("geosite rules can not be added at runtime")
+	}
+	if hasRule(rules, isGeoIPRule) && r.geoIPReader == nil {
+		return nil, E.New("geoip database not loaded")
+	}
+	if hasRule(rules, isProcessRule) && r.processSearcher == nil {
+		return nil, E.New("process searcher not initialized")
+	}
+	if hasRule(rules, isWIFIRule) && !r.needWIFIState {
+		return nil, E.New("WIFI state not initialized")
+	}
+	rule, err := NewRule(r, r.logger, options, true)
+	if err != nil {
+		return nil, err
+	}
+	if _, loaded := r.Outbound(rule.Outbound()); !loaded {
+		return nil, E.New("outbound not found: ", rule.Outbound())
+	}
+	err = rule.Start()
+	if err != nil {
+		return nil, E.Cause(err, "initialize rule")
+	}
+	return rule, nil
+}
+
+func (r *Router) newDNSRule(options option.DNSRule) (adapter.DNSRule, error) {
+	if !r.started {
+		return nil, E.New("router not started")
+	}
+	rules := []option.DNSRule{options}
+	if hasDNSRule(rules, isGeositeDNSRule) {
+		return nil, E.New("geosite rules can not be added at runtime")
+	}
+	if (hasDNSRule(rules, isGeoIPDNSRule) || hasDNSFallbackRuleUseGeoIP(rules)) && r.geoIPReader == nil {
+		return nil, E.New("geoip database not loaded")
+	}
+	if hasDNSRule(rules, isProcessDNSRule) && r.processSearcher == nil {
+		return nil, E.New("process searcher not initialized")
+	}
+	if hasDNSRule(rules, isWIFIDNSRule) && !r.needWIFIState {
+		return nil, E.New("WIFI state not initialized")
+	}
+	rule, err := NewDNSRule(r, r.logger, options, true)
+	if err != nil {
+		return nil, err
+	}
+	if _, loaded := r.transportMap[rule.Outbound()]; !loaded {
+		return nil, E.New("DNS server not found: ", rule.Outbound())
+	}
+	err = rule.Start()
+	if err != nil {
+		return nil, E.Cause(err, "initialize DNS rule")
+	}
+	return rule, nil
+}
+
+func (r *Router) closeRule(rule adapter.Service) {
+	err := rule.Close()
+	if err != nil {
+		r.logger.Error(E.Cause(err, "close rule"))
+	}
+}
+
+// saveRulesOverlay writes rules to the overlay file if configured, the file
+// is replaced by rename so an interrupted write keeps the previous one. It
+// must be called with ruleAccess held.
+func (r *Router) saveRulesOverlay(rules *ruleList) error {
+	if r.rulesOverlayPath == "" {
+		return nil
+	}
+	overlay := option.RulesOverlay{
+		Rules: common.Map(rules.rules, func(it adapter.Rule) option.Rule {
+			return r.routeRuleOptions[it.UUID()]
+		}),
+		DNSRules: common.Map(rules.dnsRules, func(it adapter.DNSRule) option.DNSRule {
+			return r.dnsRuleOptions[it.UUID()]
+		}),
+		BaseHash: r.rulesOverlayBase,
+	}
+	buffer := new(bytes.Buffer)
+	encoder := json.NewEncoder(buffer)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(overlay)
+	if err != nil {
+		return E.Cause(err, "encode rules overlay")
+	}
+	tempPath := r.rulesOverlayPath + ".tmp"
+	file, err := filemanager.Create(r.ctx, tempPath)
+	if err != nil {
+		return E.Cause(err, "save rules overlay")
+	}
+	_, err = file.Write(buffer.Bytes())
+	if err == nil {
+		err = file.Sync()
+	}
+	err = E.Errors(err, file.Close())
+	if err == nil {
+		err = os.Rename(tempPath, r.rulesOverlayPath)
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return E.Cause(err, "save rules overlay")
+	}
+	return nil
+}
+
+func ruleIndex[T interface{ UUID() string }](rules []T, uuid string) int {
+	for i, rule := range rules {
+		if rule.UUID() == uuid {
+			return i
+		}
+	}
+	return -1
+}
+
+// insertRule returns a copy of rules with rule inserted at index, or appended
+// if index is out of range.
+func insertRule[T any](rules []T, index int, rule T) []T {
+	if index < 0 || index > len(rules) {
+		index = len(rules)
+	}
+	newRules := make([]T, 0, len(rules)+1)
+	newRules = append(newRules, rules[:index]...)
+	newRules = append(newRules, rule)
+	return append(newRules, rules[index:]...)
+}
+
+// removeRule returns a copy of rules without the rule at index.
+func removeRule[T any](rules []T, index int) []T {
+	newRules := make([]T, 0, len(rules)-1)
+	newRules = append(newRules, rules[:index]...)
+	return append(newRules, rules[index+1:]...)
+}
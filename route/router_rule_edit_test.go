@@ -0,0 +1,262 @@
+package route
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+func ruleOutbounds(router *Router) []string {
+	var outbounds []string
+	for _, rule := range router.Rules() {
+		outbounds = append(outbounds, rule.Outbound())
+	}
+	return outbounds
+}
+
+func TestRuleEdit(t *testing.T) {
+	t.Parallel()
+	router := newTestRouter(t, []option.Rule{
+		parseTestRule(t, `{"domain": "a.com", "outbound": "direct"}`),
+	}, nil, "")
+	first := router.Rules()[0]
+
+	second, err := router.InsertRule(0, parseTestRule(t, `{"domain": "b.com", "outbound": "block"}`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"block", "direct"}, ruleOutbounds(router))
+	_, err = router.InsertRule(-1, parseTestRule(t, `{"domain": "c.com", "outbound": "block"}`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"block", "direct", "block"}, ruleOutbounds(router))
+	_, err = router.InsertRule(0, parseTestRule(t, `{"domain": "d.com", "outbound": "proxy"}`))
+	require.Error(t, err)
+	require.Len(t, router.Rules(), 3)
+
+	updated, err := router.UpdateRule(first.UUID(), parseTestRule(t, `{"domain": "a.com", "outbound": "block"}`))
+	require.NoError(t, err)
+	require.NotEqual(t, first.UUID(), updated.UUID())
+	require.Equal(t, updated, router.Rules()[1])
+	_, loaded := router.Rule(first.UUID())
+	require.False(t, loaded)
+	_, loaded = router.Rule(updated.UUID())
+	require.True(t, loaded)
+	require.Equal(t, "block", router.routeRuleOptions[updated.UUID()].DefaultOptions.Outbound)
+
+	require.NoError(t, router.MoveRule(second.UUID(), 100))
+	require.Equal(t, second, router.Rules()[2])
+	require.NoError(t, router.MoveRule(second.UUID(), 0))
+	require.Equal(t, second, router.Rules()[0])
+
+	require.NoError(t, router.RemoveRule(second.UUID()))
+	require.Len(t, router.Rules(), 2)
+	_, loaded = router.Rule(second.UUID())
+	require.False(t, loaded)
+	require.Error(t, router.RemoveRule(second.UUID()))
+	require.Error(t, router.MoveRule(second.UUID(), 0))
+	_, err = router.UpdateRule(second.UUID(), parseTestRule(t, `{"domain": "b.com", "outbound": "block"}`))
+	require.Error(t, err)
+	require.Len(t, router.routeRuleOptions, 2)
+}
+
+func TestDNSRuleEdit(t *testing.T) {
+	t.Parallel()
+	router := newTestRouter(t, nil, []option.DNSRule{
+		parseTestDNSRule(t, `{"domain": "a.com", "server": "local"}`),
+	}, "")
+	first := router.DNSRules()[0]
+
+	second, err := router.InsertDNSRule(0, parseTestDNSRule(t, `{"domain": "b.com", "server": "remote"}`))
+	require.NoError(t, err)
+	require.Equal(t, second, router.DNSRules()[0])
+	_, err = router.InsertDNSRule(0, parseTestDNSRule(t, `{"domain": "c.com", "server": "google"}`))
+	require.Error(t, err)
+
+	updated, err := router.UpdateDNSRule(first.UUID(), parseTestDNSRule(t, `{"domain": "a.com", "server": "remote"}`))
+	require.NoError(t, err)
+	require.Equal(t, updated, router.DNSRules()[1])
+	_, loaded := router.DNSRule(first.UUID())
+	require.False(t, loaded)
+
+	require.NoError(t, router.MoveDNSRule(second.UUID(), 1))
+	require.Equal(t, []adapter.DNSRule{updated, second}, router.DNSRules())
+	require.NoError(t, router.RemoveDNSRule(updated.UUID()))
+	require.Equal(t, []adapter.DNSRule{second}, router.DNSRules())
+	require.Error(t, router.RemoveDNSRule(updated.UUID()))
+}
+
+func TestRuleEditNotStarted(t *testing.T) {
+	t.Parallel()
+	router := newTestRouter(t, nil, nil, "")
+	router.started = false
+	_, err := router.InsertRule(0, parseTestRule(t, `{"domain": "a.com", "outbound": "direct"}`))
+	require.Error(t, err)
+	_, err = router.InsertRule(0, parseTestRule(t, `{"geosite": "cn", "outbound": "direct"}`))
+	require.Error(t, err)
+}
+
+func TestRulesOverlay(t *testing.T) {
+	t.Parallel()
+	overlayPath := filepath.Join(t.TempDir(), "rules.json")
+	router := newTestRouter(t, []option.Rule{
+		parseTestRule(t, `{"domain": "a.com", "outbound": "direct"}`),
+	}, nil, overlayPath)
+	overlay, err := readRulesOverlay(overlayPath)
+	require.NoError(t, err)
+	require.Nil(t, overlay)
+
+	rule, err := router.InsertRule(0, parseTestRule(t, `{"domain": "b.com", "outbound": "block"}`))
+	require.NoError(t, err)
+	_, err = router.InsertDNSRule(0, parseTestDNSRule(t, `{"domain": "b.com", "server": "remote"}`))
+	require.NoError(t, err)
+	overlay, err = readRulesOverlay(overlayPath)
+	require.NoError(t, err)
+	require.Len(t, overlay.Rules, 2)
+	require.Equal(t, "block", overlay.Rules[0].DefaultOptions.Outbound)
+	require.Equal(t, "direct", overlay.Rules[1].DefaultOptions.Outbound)
+	require.Len(t, overlay.DNSRules, 1)
+	require.Equal(t, "remote", overlay.DNSRules[0].DefaultOptions.Server)
+	_, err = os.Stat(overlayPath + ".tmp")
+	require.True(t, os.IsNotExist(err))
+
+	require.NoError(t, router.MoveRule(rule.UUID(), 1))
+	overlay, err = readRulesOverlay(overlayPath)
+	require.NoError(t, err)
+	require.Equal(t, "block", overlay.Rules[1].DefaultOptions.Outbound)
+
+	require.NoError(t, router.RemoveRule(rule.UUID()))
+	overlay, err = readRulesOverlay(overlayPath)
+	require.NoError(t, err)
+	require.Len(t, overlay.Rules, 1)
+
+	require.NoError(t, os.WriteFile(overlayPath, []byte(`{"rules": [`), 0o644))
+	_, err = readRulesOverlay(overlayPath)
+	require.Error(t, err)
+}
+
+func TestLoadRulesOverlay(t *testing.T) {
+	t.Parallel()
+	logger := log.NewNOPFactory().NewLogger("router")
+	overlayPath := filepath.Join(t.TempDir(), "rules.json")
+	configRules := []option.Rule{parseTestRule(t, `{"domain": "a.com", "outbound": "direct"}`)}
+	rules, _, baseHash, err := loadRulesOverlay(logger, overlayPath, configRules, nil)
+	require.NoError(t, err)
+	require.Equal(t, configRules, rules)
+	require.NotEmpty(t, baseHash)
+
+	router := newTestRouter(t, configRules, nil, overlayPath)
+	router.rulesOverlayBase = baseHash
+	_, err = router.InsertRule(0, parseTestRule(t, `{"domain": "b.com", "outbound": "block"}`))
+	require.NoError(t, err)
+	rules, _, _, err = loadRulesOverlay(logger, overlayPath, configRules, nil)
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+
+	changedRules := []option.Rule{parseTestRule(t, `{"domain": "c.com", "outbound": "direct"}`)}
+	rules, _, changedHash, err := loadRulesOverlay(logger, overlayPath, changedRules, nil)
+	require.NoError(t, err)
+	require.Equal(t, changedRules, rules)
+	require.NotEqual(t, baseHash, changedHash)
+
+	require.NoError(t, os.WriteFile(overlayPath, []byte(`{"rules": [], "dns_rules": []}`), 0o644))
+	rules, _, _, err = loadRulesOverlay(logger, overlayPath, changedRules, nil)
+	require.NoError(t, err)
+	require.Empty(t, rules)
+}
+
+func TestRulesOverlayWriteFailure(t *testing.T) {
+	t.Parallel()
+	overlayPath := filepath.Join(t.TempDir(), "missing", "rules.json")
+	router := newTestRouter(t, []option.Rule{
+		parseTestRule(t, `{"domain": "a.com", "outbound": "direct"}`),
+	}, nil, overlayPath)
+	rule := router.Rules()[0]
+	_, err := router.InsertRule(0, parseTestRule(t, `{"domain": "b.com", "outbound": "block"}`))
+	require.Error(t, err)
+	_, err = router.UpdateRule(rule.UUID(), parseTestRule(t, `{"domain": "b.com", "outbound": "block"}`))
+	require.Error(t, err)
+	require.Error(t, router.RemoveRule(rule.UUID()))
+	require.Equal(t, []adapter.Rule{rule}, router.Rules())
+	require.Len(t, router.routeRuleByUUID, 1)
+	require.Len(t, router.routeRuleOptions, 1)
+}
+
+func TestRuleEditConcurrent(t *testing.T) {
+	t.Parallel()
+	router := newTestRouter(t, []option.Rule{
+		parseTestRule(t, `{"domain": "a.com", "outbound": "block"}`),
+	}, []option.DNSRule{
+		parseTestDNSRule(t, `{"domain": "a.com", "server": "remote"}`),
+	}, "")
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				metadata := adapter.InboundContext{
+					Network:     N.NetworkTCP,
+					Destination: M.ParseSocksaddrHostPort("a.com", 443),
+				}
+				_, outbound := router.match0(ctx, &metadata, router.defaultOutboundForConnection)
+				require.NotNil(t, outbound)
+				dnsMetadata := adapter.InboundContext{Domain: "a.com"}
+				router.matchDNS(adapter.WithContext(ctx, &dnsMetadata), true, -1, true)
+				router.matchSniffOverride(ctx, &metadata)
+			}
+		}()
+	}
+	for i := 0; i < 50; i++ {
+		rule, err := router.InsertRule(0, parseTestRule(t, `{"domain": "a.com", "outbound": "direct"}`))
+		require.NoError(t, err)
+		rule, err = router.UpdateRule(rule.UUID(), parseTestRule(t, `{"domain": "a.com", "outbound": "block"}`))
+		require.NoError(t, err)
+		require.NoError(t, router.MoveRule(rule.UUID(), -1))
+		require.NoError(t, router.RemoveRule(rule.UUID()))
+		dnsRule, err := router.InsertDNSRule(0, parseTestDNSRule(t, `{"domain": "a.com", "server": "local"}`))
+		require.NoError(t, err)
+		require.NoError(t, router.RemoveDNSRule(dnsRule.UUID()))
+	}
+	cancel()
+	wg.Wait()
+	require.Len(t, router.Rules(), 1)
+	require.Len(t, router.DNSRules(), 1)
+}
+
+type testClosedService struct {
+	closed atomic.Bool
+}
+
+func (s *testClosedService) Start() error {
+	return nil
+}
+
+func (s *testClosedService) Close() error {
+	s.closed.Store(true)
+	return nil
+}
+
+func TestReplaceRuleListClosesAfterRelease(t *testing.T) {
+	t.Parallel()
+	router := newTestRouter(t, nil, nil, "")
+	rules := router.acquireRules()
+	service := &testClosedService{}
+	router.ruleAccess.Lock()
+	router.replaceRuleList(router.ruleList.clone(), service)
+	router.ruleAccess.Unlock()
+	time.Sleep(50 * time.Millisecond)
+	require.False(t, service.closed.Load())
+	rules.release()
+	require.Eventually(t, service.closed.Load, time.Second, 10*time.Millisecond)
+}
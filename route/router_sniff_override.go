@@ -7,7 +7,9 @@ import (
 )
 
 func (r *Router) matchSniffOverride(ctx context.Context, metadata *adapter.InboundContext) bool {
-	rules := r.sniffOverrideRules[metadata.Inbound]
+	ruleList := r.acquireRules()
+	defer ruleList.release()
+	rules := ruleList.sniffOverrideRules[metadata.Inbound]
 	if len(rules) == 0 {
 		return true
 	}
@@ -0,0 +1,117 @@
+package route
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/tracing"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	O "github.com/sagernet/sing-box/outbound"
+	"github.com/sagernet/sing-dns"
+	"github.com/sagernet/sing/common/json"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/service"
+	"github.com/sagernet/sing/service/pause"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testInbound struct {
+	inboundType string
+	tag         string
+}
+
+func (i *testInbound) Type() string {
+	return i.inboundType
+}
+
+func (i *testInbound) Tag() string {
+	return i.tag
+}
+
+func (i *testInbound) Start() error {
+	return nil
+}
+
+func (i *testInbound) Close() error {
+	return nil
+}
+
+func parseTestRule(t *testing.T, content string) option.Rule {
+	options, err := json.UnmarshalExtended[option.Rule]([]byte(content))
+	require.NoError(t, err)
+	return options
+}
+
+func parseTestDNSRule(t *testing.T, content string) option.DNSRule {
+	options, err := json.UnmarshalExtended[option.DNSRule]([]byte(content))
+	require.NoError(t, err)
+	return options
+}
+
+// newTestRouter creates a started router with direct and block outbounds,
+// local and remote DNS servers, a mixed-in inbound and a tracer, without
+// starting any service.
+func newTestRouter(t *testing.T, rules []option.Rule, dnsRules []option.DNSRule, rulesOverlayPath string) *Router {
+	logger := log.NewNOPFactory().NewLogger("router")
+	direct := O.NewBlock(logger, "direct")
+	block := O.NewBlock(logger, "block")
+	transportMap := make(map[string]dns.Transport)
+	for _, name := range []string{"local", "remote"} {
+		transport, err := dns.NewLocalTransport(dns.TransportOptions{
+			Context: context.Background(),
+			Name:    name,
+			Dialer:  N.SystemDialer,
+		})
+		require.NoError(t, err)
+		transportMap[name] = transport
+	}
+	router := &Router{
+		ctx:       context.Background(),
+		logger:    logger,
+		dnsLogger: logger,
+		inboundByTag: map[string]adapter.Inbound{
+			"mixed-in": &testInbound{inboundType: C.TypeMixed, tag: "mixed-in"},
+		},
+		outboundByTag: map[string]adapter.Outbound{
+			"direct": direct,
+			"block":  block,
+		},
+		defaultOutboundForConnection:       direct,
+		defaultOutboundForPacketConnection: direct,
+		transportMap:                       transportMap,
+		defaultTransport:                   transportMap["local"],
+		dnsClient:                          dns.NewClient(dns.ClientOptions{}),
+		pauseManager:                       service.FromContext[pause.Manager](pause.WithDefaultManager(context.Background())),
+		tracer:                             tracing.NewTracer(),
+		ruleList: &ruleList{
+			sniffOverrideRules: make(map[string][]adapter.Rule),
+		},
+		routeRuleByUUID:  make(map[string]adapter.Rule),
+		routeRuleOptions: make(map[string]option.Rule),
+		dnsRuleByUUID:    make(map[string]adapter.DNSRule),
+		dnsRuleOptions:   make(map[string]option.DNSRule),
+		rulesOverlayPath: rulesOverlayPath,
+	}
+	for _, options := range rules {
+		rule, err := NewRule(router, logger, options, true)
+		require.NoError(t, err)
+		require.NoError(t, rule.Start())
+		router.ruleList.rules = append(router.ruleList.rules, rule)
+		router.routeRuleByUUID[rule.UUID()] = rule
+		router.routeRuleOptions[rule.UUID()] = options
+	}
+	for _, options := range dnsRules {
+		rule, err := NewDNSRule(router, logger, options, true)
+		require.NoError(t, err)
+		require.NoError(t, rule.Start())
+		router.ruleList.dnsRules = append(router.ruleList.dnsRules, rule)
+		router.dnsRuleByUUID[rule.UUID()] = rule
+		router.dnsRuleOptions[rule.UUID()] = options
+	}
+	router.started = true
+	return router
+}
@@ -0,0 +1,127 @@
+package route
+
+import (
+	"context"
+	"net"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/tracing"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing/common"
+	N "github.com/sagernet/sing/common/network"
+)
+
+// finishTrace publishes the trace of a routed connection, it is a no-op if
+// tracing is disabled or the trace is already published.
+func (r *Router) finishTrace(ctx context.Context, trace *tracing.Trace, metadata *adapter.InboundContext, detour adapter.Outbound, err error) {
+	if trace == nil {
+		return
+	}
+	r.tracer.Finish(trace, func(trace *tracing.Trace) {
+		if id, loaded := log.IDFromContext(ctx); loaded {
+			trace.ID = id.ID
+		}
+		trace.Inbound = metadata.Inbound
+		trace.InboundType = metadata.InboundType
+		trace.Network = metadata.Network
+		trace.Source = metadata.Source.String()
+		trace.Destination = metadata.Destination.String()
+		if metadata.Protocol != "" {
+			trace.Sniff = &tracing.SniffResult{
+				Protocol: metadata.Protocol,
+				Domain:   metadata.SniffHost,
+				Client:   metadata.Client,
+			}
+		}
+		trace.DNSMode = metadata.DNSMode
+		if detour != nil {
+			trace.Outbound = detour.Tag()
+			trace.Chain = outboundChain(detour, metadata.Network)
+		}
+		if err != nil {
+			trace.Error = err.Error()
+		}
+	})
+}
+
+// outboundChain resolves the outbounds selected by groups starting from
+// detour.
+func outboundChain(detour adapter.Outbound, network string) []string {
+	chain := []string{detour.Tag()}
+	for {
+		group, isGroup := detour.(adapter.OutboundGroup)
+		if !isGroup {
+			return chain
+		}
+		detour = group.SelectedOutbound(network)
+		if detour == nil || common.Contains(chain, detour.Tag()) {
+			return chain
+		}
+		chain = append(chain, detour.Tag())
+	}
+}
+
+// tracedConn finishes the trace of a connection when the outbound reports the
+// result of its dial.
+type tracedConn struct {
+	net.Conn
+	finish func(err error)
+}
+
+func (c *tracedConn) HandshakeSuccess() error {
+	c.finish(nil)
+	return N.ReportHandshakeSuccess(c.Conn)
+}
+
+func (c *tracedConn) HandshakeFailure(err error) error {
+	c.finish(err)
+	return reportUpstreamHandshakeFailure(c.Conn, err)
+}
+
+func (c *tracedConn) ReaderReplaceable() bool {
+	return true
+}
+
+func (c *tracedConn) WriterReplaceable() bool {
+	return true
+}
+
+func (c *tracedConn) Upstream() any {
+	return c.Conn
+}
+
+type tracedPacketConn struct {
+	N.PacketConn
+	finish func(err error)
+}
+
+func (c *tracedPacketConn) HandshakeSuccess() error {
+	c.finish(nil)
+	return N.ReportHandshakeSuccess(c.PacketConn)
+}
+
+func (c *tracedPacketConn) HandshakeFailure(err error) error {
+	c.finish(err)
+	return reportUpstreamHandshakeFailure(c.PacketConn, err)
+}
+
+func (c *tracedPacketConn) ReaderReplaceable() bool {
+	return true
+}
+
+func (c *tracedPacketConn) WriterReplaceable() bool {
+	return true
+}
+
+func (c *tracedPacketConn) Upstream() any {
+	return c.PacketConn
+}
+
+// reportUpstreamHandshakeFailure returns only the error of writing the failure
+// to conn, since the caller appends it to err.
+func reportUpstreamHandshakeFailure(conn any, err error) error {
+	if handshakeConn, isHandshakeConn := common.Cast[N.HandshakeFailure](conn); isHandshakeConn {
+		return handshakeConn.HandshakeFailure(err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package route
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/tracing"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testHandshakeOutbound reports the result of a dial and then waits for done,
+// as outbounds copying a connection do.
+type testHandshakeOutbound struct {
+	adapter.Outbound
+	dialErr error
+	done    chan struct{}
+}
+
+func (o *testHandshakeOutbound) Type() string {
+	return C.TypeDirect
+}
+
+func (o *testHandshakeOutbound) Tag() string {
+	return "proxy"
+}
+
+func (o *testHandshakeOutbound) Network() []string {
+	return []string{N.NetworkTCP, N.NetworkUDP}
+}
+
+func (o *testHandshakeOutbound) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	if o.dialErr != nil {
+		return N.ReportHandshakeFailure(conn, o.dialErr)
+	}
+	err := N.ReportHandshakeSuccess(conn)
+	if err != nil {
+		return err
+	}
+	<-o.done
+	return nil
+}
+
+func receiveTrace(t *testing.T, subscription <-chan *tracing.Trace) *tracing.Trace {
+	select {
+	case trace := <-subscription:
+		return trace
+	case <-time.After(time.Second):
+		t.Fatal("trace not published")
+		return nil
+	}
+}
+
+func TestRouteConnectionTrace(t *testing.T) {
+	t.Parallel()
+	router := newTestRouter(t, []option.Rule{
+		parseTestRule(t, `{"ip_cidr": "10.0.0.0/8", "outbound": "proxy"}`),
+	}, nil, "")
+	detour := &testHandshakeOutbound{done: make(chan struct{})}
+	router.outboundByTag["proxy"] = detour
+	subscription, _, err := router.tracer.Subscribe()
+	require.NoError(t, err)
+	defer router.tracer.UnSubscribe(subscription)
+
+	conn, _ := net.Pipe()
+	defer conn.Close()
+	routeDone := make(chan error, 1)
+	go func() {
+		routeDone <- router.RouteConnection(context.Background(), conn, adapter.InboundContext{
+			Destination: M.ParseSocksaddrHostPort("10.0.0.1", 443),
+		})
+	}()
+	trace := receiveTrace(t, subscription)
+	require.Equal(t, "proxy", trace.Outbound)
+	require.Empty(t, trace.Error)
+	select {
+	case <-routeDone:
+		t.Fatal("trace published after the connection is closed")
+	default:
+	}
+	close(detour.done)
+	require.NoError(t, <-routeDone)
+
+	detour.dialErr = E.New("dial failed")
+	err = router.RouteConnection(context.Background(), conn, adapter.InboundContext{
+		Destination: M.ParseSocksaddrHostPort("10.0.0.1", 443),
+	})
+	require.Equal(t, "dial failed", err.Error())
+	trace = receiveTrace(t, subscription)
+	require.Equal(t, "proxy", trace.Outbound)
+	require.Equal(t, "dial failed", trace.Error)
+}
@@ -28,7 +28,7 @@ func NewWIFISSIDItem(router adapter.Router, ssidList []string) *WIFISSIDItem {
 }
 
 func (r *WIFISSIDItem) Match(metadata *adapter.InboundContext) bool {
-	return r.ssidMap[r.router.WIFIState().SSID]
+	return r.ssidMap[wifiState(r.router, metadata).SSID]
 }
 
 func (r *WIFISSIDItem) String() string {
@@ -37,3 +37,12 @@ func (r *WIFISSIDItem) String() string {
 	}
 	return F.ToString("wifi_ssid=[", strings.Join(r.ssidList, " "), "]")
 }
+
+// wifiState returns the WIFI state of metadata if set, e.g. for routing tests,
+// or the current one.
+func wifiState(router adapter.Router, metadata *adapter.InboundContext) adapter.WIFIState {
+	if metadata.WIFIState != nil {
+		return *metadata.WIFIState
+	}
+	return router.WIFIState()
+}
@@ -36,3 +36,8 @@ func CreatePlugin(ctx context.Context, name string, pluginArgs string, router ad
 	}
 	return constructor(ctx, pluginOptions, router, dialer, serverAddr)
 }
+
+func PluginExists(name string) bool {
+	_, loaded := plugins[name]
+	return loaded
+}